@@ -0,0 +1,884 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+)
+
+// brotli解码实现（RFC 7932），纯Go实现，无任何外部依赖
+// 内嵌数据：
+// brotli_dict.bin：RFC 7932附录A的静态字典（122784字节）
+// brotli_context.bin：字面量上下文查找表，4种上下文模式×(p1表256项+p2表256项)
+var (
+	//go:embed data/brotli_dict.bin
+	brotliDictionary []byte
+	//go:embed data/brotli_context.bin
+	brotliContextLookup []byte
+)
+
+// errBrotliCorrupt brotli数据格式错误
+var errBrotliCorrupt = errors.New("brotli: 数据格式错误")
+
+// brotli静态字典：各单词长度对应的索引位数及在字典中的起始偏移（长度4~24有效）
+var brotliDictSizeBits = [25]uint{0, 0, 0, 0, 10, 10, 11, 11, 10, 10, 10, 10, 10, 9, 9, 8, 7, 7, 8, 7, 7, 6, 6, 5, 5}
+var brotliDictOffsets = [25]int{0, 0, 0, 0, 0, 4096, 9216, 21504, 35840, 44032, 53248, 63488, 74752, 87040, 93696, 100864, 104704, 106752, 108928, 113536, 115968, 118528, 119872, 121280, 122016}
+
+// 插入长度/复制长度/块长度编码表：{基数, 额外位数}
+var brotliInsertLengthCodes = [24][2]uint32{
+	{0, 0}, {1, 0}, {2, 0}, {3, 0}, {4, 0}, {5, 0}, {6, 1}, {8, 1}, {10, 2}, {14, 2}, {18, 3}, {26, 3},
+	{34, 4}, {50, 4}, {66, 5}, {98, 5}, {130, 6}, {194, 7}, {322, 8}, {578, 9}, {1090, 10}, {2114, 12}, {6210, 14}, {22594, 24},
+}
+var brotliCopyLengthCodes = [24][2]uint32{
+	{2, 0}, {3, 0}, {4, 0}, {5, 0}, {6, 0}, {7, 0}, {8, 0}, {9, 0}, {10, 1}, {12, 1}, {14, 2}, {18, 2},
+	{22, 3}, {30, 3}, {38, 4}, {54, 4}, {70, 5}, {102, 5}, {134, 6}, {198, 7}, {326, 8}, {582, 9}, {1094, 10}, {2118, 24},
+}
+var brotliBlockLengthCodes = [26][2]uint32{
+	{1, 2}, {5, 2}, {9, 2}, {13, 2}, {17, 3}, {25, 3}, {33, 3}, {41, 3}, {49, 4}, {65, 4}, {81, 4}, {97, 4}, {113, 5},
+	{145, 5}, {177, 5}, {209, 5}, {241, 6}, {305, 6}, {369, 7}, {497, 8}, {753, 9}, {1265, 10}, {2289, 11}, {4337, 12}, {8433, 13}, {16625, 24},
+}
+
+// 插入复制命令的11个单元：{插入码起点, 复制码起点}，前两个单元隐含距离码0
+var brotliCommandCells = [11][2]uint32{
+	{0, 0}, {0, 8}, {0, 0}, {0, 8}, {8, 0}, {8, 8}, {0, 16}, {16, 0}, {8, 16}, {16, 8}, {16, 16},
+}
+
+// 复杂前缀码中“码长的码长”的读取顺序
+var brotliCodeLengthOrder = [18]int{1, 2, 3, 4, 0, 5, 17, 6, 16, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// 读取码长码长所用的静态前缀码：按预读4位索引，得到{码长, 值}
+var brotliCodeLengthPrefix = [16][2]uint{
+	{2, 0}, {2, 4}, {2, 3}, {3, 2}, {2, 0}, {2, 4}, {2, 3}, {4, 1},
+	{2, 0}, {2, 4}, {2, 3}, {3, 2}, {2, 0}, {2, 4}, {2, 3}, {4, 5},
+}
+
+// 字典单词变换类型
+const (
+	brotliTransformIdentity       = 0
+	brotliTransformOmitLast9      = 9
+	brotliTransformUppercaseFirst = 10
+	brotliTransformUppercaseAll   = 11
+	brotliTransformOmitFirst1     = 12
+	brotliTransformOmitFirst9     = 20
+)
+
+// brotliTransforms RFC 7932附录B的121种字典单词变换：{前缀, 变换类型, 后缀}
+var brotliTransforms = [121]struct {
+	prefix string
+	kind   int
+	suffix string
+}{
+	{"", 0, ""},
+	{"", 0, " "},
+	{" ", 0, " "},
+	{"", 12, ""},
+	{"", 10, " "},
+	{"", 0, " the "},
+	{" ", 0, ""},
+	{"s ", 0, " "},
+	{"", 0, " of "},
+	{"", 10, ""},
+	{"", 0, " and "},
+	{"", 13, ""},
+	{"", 1, ""},
+	{", ", 0, " "},
+	{"", 0, ", "},
+	{" ", 10, " "},
+	{"", 0, " in "},
+	{"", 0, " to "},
+	{"e ", 0, " "},
+	{"", 0, "\""},
+	{"", 0, "."},
+	{"", 0, "\">"},
+	{"", 0, "\n"},
+	{"", 3, ""},
+	{"", 0, "]"},
+	{"", 0, " for "},
+	{"", 14, ""},
+	{"", 2, ""},
+	{"", 0, " a "},
+	{"", 0, " that "},
+	{" ", 10, ""},
+	{"", 0, ". "},
+	{".", 0, ""},
+	{" ", 0, ", "},
+	{"", 15, ""},
+	{"", 0, " with "},
+	{"", 0, "'"},
+	{"", 0, " from "},
+	{"", 0, " by "},
+	{"", 16, ""},
+	{"", 17, ""},
+	{" the ", 0, ""},
+	{"", 4, ""},
+	{"", 0, ". The "},
+	{"", 11, ""},
+	{"", 0, " on "},
+	{"", 0, " as "},
+	{"", 0, " is "},
+	{"", 7, ""},
+	{"", 1, "ing "},
+	{"", 0, "\n\t"},
+	{"", 0, ":"},
+	{" ", 0, ". "},
+	{"", 0, "ed "},
+	{"", 20, ""},
+	{"", 18, ""},
+	{"", 6, ""},
+	{"", 0, "("},
+	{"", 10, ", "},
+	{"", 8, ""},
+	{"", 0, " at "},
+	{"", 0, "ly "},
+	{" the ", 0, " of "},
+	{"", 5, ""},
+	{"", 9, ""},
+	{" ", 10, ", "},
+	{"", 10, "\""},
+	{".", 0, "("},
+	{"", 11, " "},
+	{"", 10, "\">"},
+	{"", 0, "=\""},
+	{" ", 0, "."},
+	{".com/", 0, ""},
+	{" the ", 0, " of the "},
+	{"", 10, "'"},
+	{"", 0, ". This "},
+	{"", 0, ","},
+	{".", 0, " "},
+	{"", 10, "("},
+	{"", 10, "."},
+	{"", 0, " not "},
+	{" ", 0, "=\""},
+	{"", 0, "er "},
+	{" ", 11, " "},
+	{"", 0, "al "},
+	{" ", 11, ""},
+	{"", 0, "='"},
+	{"", 11, "\""},
+	{"", 10, ". "},
+	{" ", 0, "("},
+	{"", 0, "ful "},
+	{" ", 10, ". "},
+	{"", 0, "ive "},
+	{"", 0, "less "},
+	{"", 11, "'"},
+	{"", 0, "est "},
+	{" ", 10, "."},
+	{"", 11, "\">"},
+	{" ", 0, "='"},
+	{"", 10, ","},
+	{"", 0, "ize "},
+	{"", 11, "."},
+	{"\xc2\xa0", 0, ""},
+	{" ", 0, ","},
+	{"", 10, "=\""},
+	{"", 11, "=\""},
+	{"", 0, "ous "},
+	{"", 11, ", "},
+	{"", 10, "='"},
+	{" ", 10, ","},
+	{" ", 11, "=\""},
+	{" ", 11, ", "},
+	{"", 11, ","},
+	{"", 11, "("},
+	{"", 11, ". "},
+	{" ", 11, "."},
+	{"", 11, "='"},
+	{" ", 11, ". "},
+	{" ", 10, "=\""},
+	{" ", 11, "='"},
+	{" ", 10, "='"},
+}
+
+// BrotliDecode 解码完整的brotli数据
+func BrotliDecode(data []byte) ([]byte, error) {
+	return brotliDecode(data, -1)
+}
+
+// brotliDecode 解码brotli数据，limit>=0时在解码结果即将超过limit字节时立即返回ErrBodyTooLarge，
+// 不会先解出全部数据（元块头部声明了输出长度，可在输出前检查）
+func brotliDecode(data []byte, limit int64) (out []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok && (errors.Is(e, errBrotliCorrupt) || errors.Is(e, ErrBodyTooLarge)) {
+				out, err = nil, e
+				return
+			}
+			panic(r)
+		}
+	}()
+	d := &brotliDecoder{br: &lsbBitReader{data: data, corrupt: errBrotliCorrupt}, limit: limit}
+	return d.decode(), nil
+}
+
+// ---------------------- 位读取器（低位优先） ----------------------
+// lsbBitReader 按低位优先顺序读取比特流，数据不足时以corrupt错误panic（由调用方recover）
+type lsbBitReader struct {
+	data    []byte
+	pos     int
+	val     uint64
+	nbits   uint
+	corrupt error
+}
+
+// fill 尽量填充位缓冲
+func (br *lsbBitReader) fill() {
+	for br.nbits <= 56 && br.pos < len(br.data) {
+		br.val |= uint64(br.data[br.pos]) << br.nbits
+		br.pos++
+		br.nbits += 8
+	}
+}
+
+// peek 预读n位（n≤32），数据不足时高位补0
+func (br *lsbBitReader) peek(n uint) uint32 {
+	if br.nbits < n {
+		br.fill()
+	}
+	return uint32(br.val & (1<<n - 1))
+}
+
+// skip 丢弃n位
+func (br *lsbBitReader) skip(n uint) {
+	if br.nbits < n {
+		br.fill()
+		if br.nbits < n {
+			panic(fmt.Errorf("%w: 数据意外结束", br.corrupt))
+		}
+	}
+	br.val >>= n
+	br.nbits -= n
+}
+
+// bits 读取n位（n≤32）
+func (br *lsbBitReader) bits(n uint) uint32 {
+	if n == 0 {
+		return 0
+	}
+	v := br.peek(n)
+	br.skip(n)
+	return v
+}
+
+// alignToByte 跳到下一个字节边界，填充位必须为0
+func (br *lsbBitReader) alignToByte() {
+	if pad := br.nbits % 8; pad != 0 {
+		if br.bits(pad) != 0 {
+			panic(fmt.Errorf("%w: 字节对齐填充位非0", br.corrupt))
+		}
+	}
+}
+
+// readBytes 在字节对齐后读取n个原始字节
+func (br *lsbBitReader) readBytes(n int) []byte {
+	// 先检查剩余数据是否足够，避免按伪造的长度预分配内存
+	if n > int(br.nbits/8)+len(br.data)-br.pos {
+		panic(fmt.Errorf("%w: 数据意外结束", br.corrupt))
+	}
+	out := make([]byte, 0, n)
+	for len(out) < n && br.nbits >= 8 {
+		out = append(out, byte(br.val))
+		br.val >>= 8
+		br.nbits -= 8
+	}
+	remain := n - len(out)
+	out = append(out, br.data[br.pos:br.pos+remain]...)
+	br.pos += remain
+	return out
+}
+
+// ---------------------- 前缀码（规范哈夫曼码） ----------------------
+// prefixCode 规范哈夫曼码解码表：8位快速查表+逐位慢速回退
+type prefixCode struct {
+	count  [16]uint16 // 各码长的码字数量
+	symbol []uint16   // 按码字顺序排列的符号
+	single int        // 只有一个符号时该符号值（码长为0），否则为-1
+	fast   [256]uint16
+	fastN  [256]uint8
+}
+
+// newPrefixCode 根据各符号码长构建规范前缀码
+func newPrefixCode(lengths []uint8, corrupt error) *prefixCode {
+	h := &prefixCode{single: -1}
+	nonZero := 0
+	last := 0
+	for sym, l := range lengths {
+		h.count[l]++
+		if l != 0 {
+			nonZero++
+			last = sym
+		}
+	}
+	if nonZero == 0 {
+		panic(fmt.Errorf("%w: 前缀码为空", corrupt))
+	}
+	if nonZero == 1 {
+		h.single = last
+		return h
+	}
+	// 计算每个码长在symbol中的起始下标
+	var start [16]uint16
+	for l := 2; l < 16; l++ {
+		start[l] = start[l-1] + h.count[l-1]
+	}
+	h.symbol = make([]uint16, nonZero)
+	for sym, l := range lengths {
+		if l != 0 {
+			h.symbol[start[l]] = uint16(sym)
+			start[l]++
+		}
+	}
+
+	// 构建8位快速查表：码字按高位优先构造后反转为低位优先的读取顺序
+	code := 0
+	idx := 0
+	for l := 1; l <= 8; l++ {
+		for i := 0; i < int(h.count[l]); i++ {
+			rev := reverseBits(uint32(code), uint(l))
+			for k := int(rev); k < 256; k += 1 << l {
+				h.fast[k] = h.symbol[idx]
+				h.fastN[k] = uint8(l)
+			}
+			code++
+			idx++
+		}
+		code <<= 1
+	}
+	return h
+}
+
+// reverseBits 反转低n位
+func reverseBits(v uint32, n uint) uint32 {
+	var r uint32
+	for i := uint(0); i < n; i++ {
+		r = r<<1 | v&1
+		v >>= 1
+	}
+	return r
+}
+
+// decode 从比特流中解码一个符号
+func (h *prefixCode) decode(br *lsbBitReader) int {
+	if h.single >= 0 {
+		return h.single
+	}
+	if peek := br.peek(8); h.fastN[peek] != 0 {
+		br.skip(uint(h.fastN[peek]))
+		return int(h.fast[peek])
+	}
+	code, first, index := 0, 0, 0
+	for l := 1; l < 16; l++ {
+		code |= int(br.bits(1))
+		count := int(h.count[l])
+		if code-count < first {
+			return int(h.symbol[index+code-first])
+		}
+		index += count
+		first += count
+		first <<= 1
+		code <<= 1
+	}
+	panic(fmt.Errorf("%w: 无效的前缀码", br.corrupt))
+}
+
+// ---------------------- brotli解码器 ----------------------
+type brotliDecoder struct {
+	br         *lsbBitReader
+	out        []byte
+	limit      int64 // 解码结果最大字节数，<0表示不限制
+	windowSize int
+	dist       [4]int // 最近4个距离，dist[0]为最近一次
+}
+
+// decode 解码整个brotli流
+func (d *brotliDecoder) decode() []byte {
+	d.readWindowBits()
+	d.dist = [4]int{4, 11, 15, 16}
+	for {
+		if last := d.decodeMetaBlock(); last {
+			break
+		}
+	}
+	return d.out
+}
+
+// readWindowBits 读取流头部的WBITS
+func (d *brotliDecoder) readWindowBits() {
+	br := d.br
+	wbits := uint(16)
+	if br.bits(1) == 1 {
+		if n := br.bits(3); n != 0 {
+			wbits = 17 + uint(n)
+		} else if m := br.bits(3); m == 1 {
+			panic(fmt.Errorf("%w: 不支持large window", errBrotliCorrupt))
+		} else if m != 0 {
+			wbits = 8 + uint(m)
+		} else {
+			wbits = 17
+		}
+	}
+	d.windowSize = 1<<wbits - 16
+}
+
+// readVarLenUint8 读取1~256范围的变长整数（返回值0~255）
+func (d *brotliDecoder) readVarLenUint8() int {
+	br := d.br
+	if br.bits(1) == 0 {
+		return 0
+	}
+	n := uint(br.bits(3))
+	if n == 0 {
+		return 1
+	}
+	return 1<<n + int(br.bits(n))
+}
+
+// decodeMetaBlock 解码一个元块，返回是否为最后一个元块
+func (d *brotliDecoder) decodeMetaBlock() bool {
+	br := d.br
+	isLast := br.bits(1) == 1
+	if isLast && br.bits(1) == 1 { // ISLASTEMPTY
+		br.alignToByte()
+		return true
+	}
+
+	nibbles := br.bits(2)
+	if nibbles == 3 {
+		// 元数据块：跳过指定字节数
+		if br.bits(1) != 0 {
+			panic(fmt.Errorf("%w: 保留位非0", errBrotliCorrupt))
+		}
+		skipBytes := uint(br.bits(2))
+		skipLen := 0
+		if skipBytes > 0 {
+			skipLen = int(br.bits(8*skipBytes)) + 1
+		}
+		br.alignToByte()
+		br.readBytes(skipLen)
+		return isLast
+	}
+	mlen := int(br.bits(uint(nibbles+4)*4)) + 1
+	if d.limit >= 0 && int64(len(d.out)+mlen) > d.limit {
+		panic(fmt.Errorf("%w: 解码后超过 %d 字节", ErrBodyTooLarge, d.limit))
+	}
+
+	if !isLast && br.bits(1) == 1 {
+		// 未压缩元块
+		br.alignToByte()
+		d.out = append(d.out, br.readBytes(mlen)...)
+		return false
+	}
+
+	// 三类块（字面量L、插入复制I、距离D）的块类型与块长度
+	var nTypes [3]int
+	var typeCodes, lenCodes [3]*prefixCode
+	var blockLen, blockType [3]int
+	var lastTypes [3][2]int
+	for i := 0; i < 3; i++ {
+		nTypes[i] = d.readVarLenUint8() + 1
+		blockLen[i] = 1 << 28
+		lastTypes[i] = [2]int{0, 1}
+		if nTypes[i] >= 2 {
+			typeCodes[i] = d.readPrefixCode(nTypes[i] + 2)
+			lenCodes[i] = d.readPrefixCode(26)
+			blockLen[i] = d.readBlockLength(lenCodes[i])
+		}
+	}
+
+	npostfix := uint(br.bits(2))
+	ndirect := int(br.bits(4)) << npostfix
+
+	contextModes := make([]int, nTypes[0])
+	for i := range contextModes {
+		contextModes[i] = int(br.bits(2))
+	}
+	nTreesL := d.readVarLenUint8() + 1
+	ctxMapL := d.readContextMap(64*nTypes[0], nTreesL)
+	nTreesD := d.readVarLenUint8() + 1
+	ctxMapD := d.readContextMap(4*nTypes[2], nTreesD)
+
+	literalCodes := make([]*prefixCode, nTreesL)
+	for i := range literalCodes {
+		literalCodes[i] = d.readPrefixCode(256)
+	}
+	commandCodes := make([]*prefixCode, nTypes[1])
+	for i := range commandCodes {
+		commandCodes[i] = d.readPrefixCode(704)
+	}
+	distanceCodes := make([]*prefixCode, nTreesD)
+	for i := range distanceCodes {
+		distanceCodes[i] = d.readPrefixCode(16 + ndirect + 48<<npostfix)
+	}
+
+	// switchBlock 当前块用尽时切换块类型
+	switchBlock := func(i int) {
+		sym := typeCodes[i].decode(br)
+		t := 0
+		switch sym {
+		case 0:
+			t = lastTypes[i][1]
+		case 1:
+			t = lastTypes[i][0] + 1
+		default:
+			t = sym - 2
+		}
+		if t >= nTypes[i] {
+			t -= nTypes[i]
+		}
+		lastTypes[i] = [2]int{t, lastTypes[i][0]}
+		blockType[i] = t
+		blockLen[i] = d.readBlockLength(lenCodes[i])
+	}
+
+	end := len(d.out) + mlen
+	for len(d.out) < end {
+		// 1. 插入复制命令
+		if blockLen[1] == 0 {
+			switchBlock(1)
+		}
+		blockLen[1]--
+		cmd := commandCodes[blockType[1]].decode(br)
+		cell := brotliCommandCells[cmd>>6]
+		insCode := cell[0] + uint32(cmd>>3)&7
+		copyCode := cell[1] + uint32(cmd)&7
+		insertLen := int(brotliInsertLengthCodes[insCode][0] + br.bits(uint(brotliInsertLengthCodes[insCode][1])))
+		copyLen := int(brotliCopyLengthCodes[copyCode][0] + br.bits(uint(brotliCopyLengthCodes[copyCode][1])))
+
+		// 2. 字面量
+		for i := 0; i < insertLen; i++ {
+			if len(d.out) >= end {
+				panic(fmt.Errorf("%w: 字面量超出元块长度", errBrotliCorrupt))
+			}
+			if blockLen[0] == 0 {
+				switchBlock(0)
+			}
+			blockLen[0]--
+			var p1, p2 byte
+			if n := len(d.out); n > 0 {
+				p1 = d.out[n-1]
+				if n > 1 {
+					p2 = d.out[n-2]
+				}
+			}
+			mode := contextModes[blockType[0]]
+			ctx := brotliContextLookup[mode*512+int(p1)] | brotliContextLookup[mode*512+256+int(p2)]
+			tree := ctxMapL[64*blockType[0]+int(ctx)]
+			d.out = append(d.out, byte(literalCodes[tree].decode(br)))
+		}
+		if len(d.out) >= end {
+			break
+		}
+
+		// 3. 距离
+		distCode := 0
+		if cmd >= 128 {
+			if blockLen[2] == 0 {
+				switchBlock(2)
+			}
+			blockLen[2]--
+			ctx := 3
+			if copyLen <= 4 {
+				ctx = copyLen - 2
+			}
+			tree := ctxMapD[4*blockType[2]+ctx]
+			distCode = distanceCodes[tree].decode(br)
+		}
+		distance := d.decodeDistance(distCode, ndirect, npostfix)
+
+		// 4. 复制：距离超出可回溯范围时为静态字典引用
+		maxDistance := len(d.out)
+		if maxDistance > d.windowSize {
+			maxDistance = d.windowSize
+		}
+		if distance > maxDistance {
+			d.copyFromDictionary(distance-maxDistance-1, copyLen)
+		} else {
+			if distCode != 0 {
+				d.dist = [4]int{distance, d.dist[0], d.dist[1], d.dist[2]}
+			}
+			if len(d.out)+copyLen > end {
+				panic(fmt.Errorf("%w: 复制超出元块长度", errBrotliCorrupt))
+			}
+			start := len(d.out) - distance
+			for i := 0; i < copyLen; i++ {
+				d.out = append(d.out, d.out[start+i])
+			}
+		}
+		if len(d.out) > end {
+			panic(fmt.Errorf("%w: 复制超出元块长度", errBrotliCorrupt))
+		}
+	}
+	return isLast
+}
+
+// decodeDistance 把距离码转换为实际距离
+func (d *brotliDecoder) decodeDistance(code, ndirect int, npostfix uint) int {
+	if code < 16 {
+		var base, delta int
+		switch {
+		case code < 4:
+			return d.dist[code]
+		case code < 10:
+			base = d.dist[0]
+			delta = []int{-1, 1, -2, 2, -3, 3}[code-4]
+		default:
+			base = d.dist[1]
+			delta = []int{-1, 1, -2, 2, -3, 3}[code-10]
+		}
+		if base+delta <= 0 {
+			panic(fmt.Errorf("%w: 无效距离", errBrotliCorrupt))
+		}
+		return base + delta
+	}
+	if code < 16+ndirect {
+		return code - 15
+	}
+	code -= ndirect + 16
+	postfixMask := 1<<npostfix - 1
+	ndistbits := 1 + uint(code>>(npostfix+1))
+	hcode := code >> npostfix
+	lcode := code & postfixMask
+	offset := (2+hcode&1)<<ndistbits - 4
+	return (offset+int(d.br.bits(ndistbits)))<<npostfix + lcode + ndirect + 1
+}
+
+// copyFromDictionary 按静态字典引用输出变换后的单词
+func (d *brotliDecoder) copyFromDictionary(wordID, length int) {
+	if length < 4 || length > 24 {
+		panic(fmt.Errorf("%w: 无效的字典引用", errBrotliCorrupt))
+	}
+	nbits := brotliDictSizeBits[length]
+	index := wordID & (1<<nbits - 1)
+	transformID := wordID >> nbits
+	if transformID >= len(brotliTransforms) {
+		panic(fmt.Errorf("%w: 无效的字典变换", errBrotliCorrupt))
+	}
+	offset := brotliDictOffsets[length] + index*length
+	word := append([]byte(nil), brotliDictionary[offset:offset+length]...)
+
+	t := brotliTransforms[transformID]
+	switch {
+	case t.kind >= 1 && t.kind <= brotliTransformOmitLast9:
+		if t.kind >= len(word) {
+			word = word[:0]
+		} else {
+			word = word[:len(word)-t.kind]
+		}
+	case t.kind >= brotliTransformOmitFirst1 && t.kind <= brotliTransformOmitFirst9:
+		skip := t.kind - brotliTransformOmitFirst1 + 1
+		if skip >= len(word) {
+			word = word[:0]
+		} else {
+			word = word[skip:]
+		}
+	case t.kind == brotliTransformUppercaseFirst:
+		brotliToUpper(word)
+	case t.kind == brotliTransformUppercaseAll:
+		for i := 0; i < len(word); {
+			i += brotliToUpper(word[i:])
+		}
+	}
+	d.out = append(d.out, t.prefix...)
+	d.out = append(d.out, word...)
+	d.out = append(d.out, t.suffix...)
+}
+
+// brotliToUpper 按RFC 7932的简化规则把UTF-8字符转大写，返回处理的字节数
+func brotliToUpper(p []byte) int {
+	if len(p) == 0 {
+		return 1
+	}
+	if p[0] < 0xC0 {
+		if p[0] >= 'a' && p[0] <= 'z' {
+			p[0] ^= 32
+		}
+		return 1
+	}
+	if p[0] < 0xE0 {
+		if len(p) > 1 {
+			p[1] ^= 32
+		}
+		return 2
+	}
+	if len(p) > 2 {
+		p[2] ^= 5
+	}
+	return 3
+}
+
+// readBlockLength 读取块长度
+func (d *brotliDecoder) readBlockLength(code *prefixCode) int {
+	sym := code.decode(d.br)
+	return int(brotliBlockLengthCodes[sym][0] + d.br.bits(uint(brotliBlockLengthCodes[sym][1])))
+}
+
+// readContextMap 读取上下文映射表（支持游程编码与逆向MTF变换）
+func (d *brotliDecoder) readContextMap(size, nTrees int) []int {
+	br := d.br
+	ctxMap := make([]int, size)
+	if nTrees < 2 {
+		return ctxMap
+	}
+	rleMax := 0
+	if br.bits(1) == 1 {
+		rleMax = int(br.bits(4)) + 1
+	}
+	code := d.readPrefixCode(nTrees + rleMax)
+	for i := 0; i < size; {
+		sym := code.decode(br)
+		switch {
+		case sym == 0:
+			ctxMap[i] = 0
+			i++
+		case sym <= rleMax:
+			reps := 1<<uint(sym) + int(br.bits(uint(sym)))
+			if i+reps > size {
+				panic(fmt.Errorf("%w: 上下文映射游程越界", errBrotliCorrupt))
+			}
+			i += reps // 默认即为0
+		default:
+			ctxMap[i] = sym - rleMax
+			i++
+		}
+	}
+	if br.bits(1) == 1 {
+		// 逆向move-to-front变换
+		var mtf [256]int
+		for i := range mtf {
+			mtf[i] = i
+		}
+		for i, v := range ctxMap {
+			value := mtf[v]
+			ctxMap[i] = value
+			copy(mtf[1:v+1], mtf[:v])
+			mtf[0] = value
+		}
+	}
+	for _, v := range ctxMap {
+		if v >= nTrees {
+			panic(fmt.Errorf("%w: 上下文映射越界", errBrotliCorrupt))
+		}
+	}
+	return ctxMap
+}
+
+// readPrefixCode 读取一个前缀码定义（简单前缀码或复杂前缀码）
+func (d *brotliDecoder) readPrefixCode(alphabetSize int) *prefixCode {
+	br := d.br
+	lengths := make([]uint8, alphabetSize)
+	hskip := br.bits(2)
+	if hskip == 1 {
+		// 简单前缀码：直接列出1~4个符号
+		alphabetBits := uint(0)
+		for (1 << alphabetBits) < alphabetSize {
+			alphabetBits++
+		}
+		nsym := int(br.bits(2)) + 1
+		syms := make([]int, nsym)
+		for i := range syms {
+			syms[i] = int(br.bits(alphabetBits))
+			if syms[i] >= alphabetSize {
+				panic(fmt.Errorf("%w: 简单前缀码符号越界", errBrotliCorrupt))
+			}
+			for j := 0; j < i; j++ {
+				if syms[j] == syms[i] {
+					panic(fmt.Errorf("%w: 简单前缀码符号重复", errBrotliCorrupt))
+				}
+			}
+		}
+		switch nsym {
+		case 1:
+			lengths[syms[0]] = 1 // 单符号：解码时不读取任何位
+		case 2:
+			lengths[syms[0]], lengths[syms[1]] = 1, 1
+		case 3:
+			lengths[syms[0]], lengths[syms[1]], lengths[syms[2]] = 1, 2, 2
+		case 4:
+			if br.bits(1) == 0 {
+				lengths[syms[0]], lengths[syms[1]], lengths[syms[2]], lengths[syms[3]] = 2, 2, 2, 2
+			} else {
+				lengths[syms[0]], lengths[syms[1]], lengths[syms[2]], lengths[syms[3]] = 1, 2, 3, 3
+			}
+		}
+		return newPrefixCode(lengths, errBrotliCorrupt)
+	}
+
+	// 复杂前缀码：先读取“码长的码长”
+	var clLengths [18]uint8
+	space := 32
+	numCodes := 0
+	for i := int(hskip); i < 18; i++ {
+		v := br.peek(4)
+		br.skip(brotliCodeLengthPrefix[v][0])
+		l := brotliCodeLengthPrefix[v][1]
+		clLengths[brotliCodeLengthOrder[i]] = uint8(l)
+		if l != 0 {
+			space -= 32 >> l
+			numCodes++
+			if space <= 0 {
+				break
+			}
+		}
+	}
+	if numCodes != 1 && space != 0 {
+		panic(fmt.Errorf("%w: 码长码不完整", errBrotliCorrupt))
+	}
+	clCode := newPrefixCode(clLengths[:], errBrotliCorrupt)
+
+	// 再按码长码读取每个符号的码长（16=重复上一个非0码长，17=重复0）
+	symbol := 0
+	prevLen := uint8(8)
+	repeat := 0
+	repeatLen := uint8(0)
+	space = 32768
+	for symbol < alphabetSize && space > 0 {
+		p := clCode.decode(br)
+		if p < 16 {
+			repeat = 0
+			lengths[symbol] = uint8(p)
+			symbol++
+			if p != 0 {
+				prevLen = uint8(p)
+				space -= 32768 >> uint(p)
+			}
+			continue
+		}
+		extraBits := uint(2)
+		newLen := prevLen
+		if p == 17 {
+			extraBits = 3
+			newLen = 0
+		}
+		if repeatLen != newLen {
+			repeat = 0
+			repeatLen = newLen
+		}
+		oldRepeat := repeat
+		if repeat > 0 {
+			repeat = (repeat - 2) << extraBits
+		}
+		repeat += int(br.bits(extraBits)) + 3
+		delta := repeat - oldRepeat
+		if symbol+delta > alphabetSize {
+			panic(fmt.Errorf("%w: 码长重复越界", errBrotliCorrupt))
+		}
+		for i := 0; i < delta; i++ {
+			lengths[symbol] = repeatLen
+			symbol++
+		}
+		if repeatLen != 0 {
+			space -= delta << (15 - repeatLen)
+		}
+	}
+	if space != 0 {
+		panic(fmt.Errorf("%w: 前缀码不完整", errBrotliCorrupt))
+	}
+	return newPrefixCode(lengths, errBrotliCorrupt)
+}
@@ -0,0 +1,480 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// heuristicMaxLifetime 仅有Last-Modified时按启发式规则（距上次修改时间的10%）计算的新鲜期上限
+const heuristicMaxLifetime = 24 * time.Hour
+
+// CacheEntry 缓存的一条响应（已按Content-Encoding解码，不含Set-Cookie）
+type CacheEntry struct {
+	Key          string            // 缓存键（见cacheKey）
+	URL          string            // 请求URL
+	StatusCode   int               // 状态码
+	Status       string            // 状态行文本
+	Header       http.Header       // 响应头
+	Body         []byte            // 响应体
+	Vary         map[string]string // Vary中列出的请求头在缓存时的取值
+	RequestTime  time.Time         // 发出请求的时间
+	ResponseTime time.Time         // 收到响应的时间
+}
+
+// CacheStore 响应缓存的存储后端，实现需并发安全
+// 内置内存（NewMemoryCache）和磁盘（NewDiskCache）两种实现，也可自行实现（如Redis）
+type CacheStore interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+	Delete(key string)
+}
+
+// SetCache 为实例开启HTTP缓存（nil表示关闭），遵循RFC 7234：
+//  1. 仅缓存GET请求的200/203响应，且响应需带有Cache-Control/Expires或ETag/Last-Modified
+//  2. 新鲜期内直接返回缓存，不发出请求
+//  3. 过期后自动携带If-None-Match/If-Modified-Since重新验证，服务器返回304时透明地返回缓存内容
+//  4. 遵守响应的no-store/no-cache/must-revalidate，以及请求头Cache-Control中的
+//     no-store、no-cache、max-age、min-fresh、max-stale（无Cache-Control时Pragma: no-cache视同no-cache）
+//  5. 请求携带Authorization、Cookie头或Cookie管理器中有该URL的Cookie时，缓存键包含这些凭据的摘要，
+//     不同登录态/会话之间不会共享缓存响应
+//
+// 示例：
+//
+//	ga.SetCache(gather.NewMemoryCache(1000))
+//	html, _, err := ga.Get(URL, "") // 第二次请求命中缓存或以304重新验证
+func (g *GatherStruct) SetCache(store CacheStore) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	g.cache = store
+}
+
+// SetCache 为池内所有实例设置同一个缓存
+func (p *Pool) SetCache(store CacheStore) {
+	for _, ga := range p.pool {
+		ga.SetCache(store)
+	}
+}
+
+// doCached 经过缓存执行请求，调用方需持有g.locker
+func (g *GatherStruct) doCached(req *http.Request) (*Response, error) {
+	rawURL := req.URL.String()
+	key := g.cacheKey(req)
+	if req.Method != "" && req.Method != http.MethodGet {
+		resp, err := g.doWithRetry(req)
+		// 非安全方法成功后，同一URL的缓存失效（其他会话以各自凭据为键的缓存项无法枚举，不在此列）
+		if err == nil && req.Method != http.MethodHead && req.Method != http.MethodOptions {
+			g.cache.Delete(rawURL)
+			g.cache.Delete(key)
+		}
+		return resp, err
+	}
+	reqDirectives := parseCacheControl(req.Header.Get("Cache-Control"))
+	if req.Header.Get("Cache-Control") == "" && strings.Contains(strings.ToLower(req.Header.Get("Pragma")), "no-cache") {
+		reqDirectives["no-cache"] = ""
+	}
+	if _, ok := reqDirectives["no-store"]; ok {
+		return g.doWithRetry(req)
+	}
+
+	entry, ok := g.cache.Get(key)
+	if ok && !entry.varyMatches(req.Header) {
+		ok = false
+	}
+	if ok {
+		if entry.usable(reqDirectives, time.Now()) {
+			return g.cachedResponse(entry, req), nil
+		}
+		// 已过期：携带验证器发起条件请求（调用方已自行设置时不覆盖）
+		etag, lastModified := entry.Header.Get("ETag"), entry.Header.Get("Last-Modified")
+		if etag != "" || lastModified != "" {
+			req = req.Clone(req.Context())
+			if etag != "" && req.Header.Get("If-None-Match") == "" {
+				req.Header.Set("If-None-Match", etag)
+			}
+			if lastModified != "" && req.Header.Get("If-Modified-Since") == "" {
+				req.Header.Set("If-Modified-Since", lastModified)
+			}
+		}
+	}
+
+	requestTime := time.Now()
+	resp, err := g.doWithRetry(req)
+	if ok && resp != nil && resp.StatusCode == http.StatusNotModified {
+		entry = entry.revalidated(resp.Header, requestTime, time.Now())
+		g.cache.Set(key, entry)
+		return g.cachedResponse(entry, req), nil
+	}
+	if err == nil && resp != nil {
+		if newEntry := newCacheEntry(key, rawURL, resp, requestTime, time.Now()); newEntry != nil {
+			g.cache.Set(key, newEntry)
+		} else if ok {
+			g.cache.Delete(key)
+		}
+	}
+	return resp, err
+}
+
+// cacheKey 请求的缓存键：默认为URL；请求携带凭据（Authorization头、Cookie头即单次请求传入的cookies、
+// Cookie管理器中该URL的Cookie）时，在URL后追加凭据的SHA-256摘要，避免把一个会话的响应返回给另一个会话
+func (g *GatherStruct) cacheKey(req *http.Request) string {
+	var credentials []string
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		credentials = append(credentials, "authorization:"+auth)
+	}
+	for _, cookie := range req.Header.Values("Cookie") {
+		credentials = append(credentials, "cookie:"+cookie)
+	}
+	if g.Client != nil && g.Client.Jar != nil {
+		for _, cookie := range g.Client.Jar.Cookies(req.URL) {
+			credentials = append(credentials, "jar:"+cookie.Name+"="+cookie.Value)
+		}
+	}
+	if len(credentials) == 0 {
+		return req.URL.String()
+	}
+	sum := sha256.Sum256([]byte(strings.Join(credentials, "\n")))
+	return req.URL.String() + "#credentials=" + hex.EncodeToString(sum[:16])
+}
+
+// cachedResponse 用缓存项组装Response
+func (g *GatherStruct) cachedResponse(entry *CacheEntry, req *http.Request) *Response {
+	response := &Response{
+		StatusCode: entry.StatusCode,
+		Status:     entry.Status,
+		Header:     entry.Header.Clone(),
+		Body:       bytes.Clone(entry.Body),
+		FinalURL:   entry.URL,
+		Request:    req,
+		FromCache:  true,
+		transcode:  !g.charsetDisabled,
+	}
+	if isTextContent(entry.Header.Get("Content-Type"), entry.Body) {
+		response.Charset = DetectCharset(entry.Header.Get("Content-Type"), entry.Body)
+	}
+	return response
+}
+
+// newCacheEntry 判断响应是否可缓存，可缓存时返回缓存项，否则返回nil
+func newCacheEntry(key, rawURL string, resp *Response, requestTime, responseTime time.Time) *CacheEntry {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNonAuthoritativeInfo {
+		return nil
+	}
+	// 发生跳转时请求URL与最终URL不一致，不缓存
+	if len(resp.Redirects) > 0 || resp.FinalURL != rawURL {
+		return nil
+	}
+	directives := parseCacheControl(resp.Header.Get("Cache-Control"))
+	if _, ok := directives["no-store"]; ok {
+		return nil
+	}
+	varyHeader := resp.Header.Values("Vary")
+	vary := make(map[string]string)
+	for _, field := range varyHeader {
+		for _, name := range strings.Split(field, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "*" {
+				return nil
+			}
+			if name != "" && resp.Request != nil {
+				vary[name] = resp.Request.Header.Get(name)
+			}
+		}
+	}
+	_, hasMaxAge := directives["max-age"]
+	if !hasMaxAge && resp.Header.Get("Expires") == "" && resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "" {
+		return nil
+	}
+
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	return &CacheEntry{
+		Key:          key,
+		URL:          rawURL,
+		StatusCode:   resp.StatusCode,
+		Status:       resp.Status,
+		Header:       header,
+		Body:         bytes.Clone(resp.Body),
+		Vary:         vary,
+		RequestTime:  requestTime,
+		ResponseTime: responseTime,
+	}
+}
+
+// varyMatches 判断请求头是否与缓存时Vary列出的请求头一致
+func (e *CacheEntry) varyMatches(header http.Header) bool {
+	for name, value := range e.Vary {
+		if header.Get(name) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// revalidated 服务器返回304后，用304的响应头更新缓存项
+func (e *CacheEntry) revalidated(header http.Header, requestTime, responseTime time.Time) *CacheEntry {
+	updated := *e
+	updated.Header = e.Header.Clone()
+	for name, values := range header {
+		switch name {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding", "Set-Cookie":
+			continue
+		}
+		updated.Header[name] = append([]string(nil), values...)
+	}
+	updated.RequestTime = requestTime
+	updated.ResponseTime = responseTime
+	return &updated
+}
+
+// fresh 判断缓存项在now时刻是否仍在新鲜期内（RFC 7234 4.2）
+func (e *CacheEntry) fresh(now time.Time) bool {
+	return e.freshnessLifetime() > e.currentAge(now)
+}
+
+// usable 判断缓存项在now时刻能否不经重新验证直接使用：在新鲜期内，
+// 且满足请求Cache-Control中的no-cache、max-age、min-fresh、max-stale（RFC 7234 5.2.1）
+func (e *CacheEntry) usable(reqDirectives map[string]string, now time.Time) bool {
+	if _, ok := reqDirectives["no-cache"]; ok {
+		return false
+	}
+	lifetime, age := e.freshnessLifetime(), e.currentAge(now)
+	if v, ok := reqDirectives["max-age"]; ok {
+		maxAge, valid := deltaSeconds(v)
+		if !valid || age > maxAge {
+			return false
+		}
+	}
+	if v, ok := reqDirectives["min-fresh"]; ok {
+		// 要求至少还有min-fresh秒的新鲜期，不满足时不再考虑max-stale
+		minFresh, valid := deltaSeconds(v)
+		return valid && lifetime-age > minFresh
+	}
+	if lifetime > age {
+		return true
+	}
+	// 已过期：请求带max-stale（无值表示不限）且响应未要求必须重新验证时，仍可使用
+	v, ok := reqDirectives["max-stale"]
+	if !ok {
+		return false
+	}
+	directives := parseCacheControl(e.Header.Get("Cache-Control"))
+	if _, mustRevalidate := directives["must-revalidate"]; mustRevalidate {
+		return false
+	}
+	if _, noCache := directives["no-cache"]; noCache {
+		return false
+	}
+	if v == "" {
+		return true
+	}
+	maxStale, valid := deltaSeconds(v)
+	return valid && age-lifetime <= maxStale
+}
+
+// freshnessLifetime 新鲜期：max-age优先，其次Expires-Date，最后按Last-Modified启发式计算
+func (e *CacheEntry) freshnessLifetime() time.Duration {
+	directives := parseCacheControl(e.Header.Get("Cache-Control"))
+	if _, ok := directives["no-cache"]; ok {
+		return 0
+	}
+	if v, ok := directives["max-age"]; ok {
+		lifetime, _ := deltaSeconds(v)
+		return lifetime
+	}
+	date := e.date()
+	if expires := e.Header.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return 0 // 无效的Expires（如"0"）视为已过期
+		}
+		return t.Sub(date)
+	}
+	if lastModified, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil && date.After(lastModified) {
+		return min(date.Sub(lastModified)/10, heuristicMaxLifetime)
+	}
+	return 0
+}
+
+// currentAge 缓存项在now时刻的年龄（RFC 7234 4.2.3）
+func (e *CacheEntry) currentAge(now time.Time) time.Duration {
+	apparentAge := max(e.ResponseTime.Sub(e.date()), 0)
+	var ageValue time.Duration
+	if seconds, err := strconv.ParseInt(e.Header.Get("Age"), 10, 64); err == nil && seconds > 0 {
+		ageValue = time.Duration(seconds) * time.Second
+	}
+	correctedAge := ageValue + e.ResponseTime.Sub(e.RequestTime)
+	return max(apparentAge, correctedAge) + now.Sub(e.ResponseTime)
+}
+
+// date 响应的Date头，缺失或无效时使用收到响应的时间
+func (e *CacheEntry) date() time.Time {
+	if t, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		return t
+	}
+	return e.ResponseTime
+}
+
+// deltaSeconds 解析Cache-Control中以秒为单位的非负整数，过大的值按最大时长处理
+func deltaSeconds(v string) (time.Duration, bool) {
+	seconds, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange && !strings.HasPrefix(v, "-") {
+			return math.MaxInt64, true
+		}
+		return 0, false
+	}
+	if seconds < 0 {
+		return 0, false
+	}
+	if seconds > math.MaxInt64/int64(time.Second) {
+		return math.MaxInt64, true
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// parseCacheControl 解析Cache-Control指令（指令名小写，值去掉引号）
+func parseCacheControl(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		directives[name] = strings.Trim(strings.TrimSpace(arg), `"`)
+	}
+	return directives
+}
+
+// MemoryCache 内存缓存，超过容量时淘汰最久未使用的缓存项
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+// memoryItem MemoryCache链表元素
+type memoryItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// NewMemoryCache 创建内存缓存，maxEntries<=0表示不限制数量
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{maxEntries: maxEntries, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// Get 获取缓存项
+func (c *MemoryCache) Get(key string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		return el.Value.(*memoryItem).entry, true
+	}
+	return nil, false
+}
+
+// Set 写入缓存项
+func (c *MemoryCache) Set(key string, entry *CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		el.Value.(*memoryItem).entry = entry
+		return
+	}
+	c.items[key] = c.ll.PushFront(&memoryItem{key: key, entry: entry})
+	if c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*memoryItem).key)
+	}
+}
+
+// Delete 删除缓存项
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.ll.Remove(el)
+		delete(c.items, key)
+	}
+}
+
+// Len 返回缓存项数量
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// DiskCache 磁盘缓存，每个缓存项保存为目录下的一个JSON文件（文件名为缓存键的SHA-256），
+// 程序重启后依然有效，写入时先写临时文件再重命名，避免进程中断留下损坏的文件
+type DiskCache struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewDiskCache 创建磁盘缓存，目录不存在时自动创建
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+// path 缓存键对应的文件路径
+func (c *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get 读取缓存项，文件不存在或已损坏时视为未命中
+func (c *DiskCache) Get(key string) (*CacheEntry, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// Set 写入缓存项（写入失败时静默忽略，下次请求按未命中处理）
+func (c *DiskCache) Set(key string, entry *CacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writeFileAtomic(c.path(key), data)
+}
+
+// Delete 删除缓存项
+func (c *DiskCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	os.Remove(c.path(key))
+}
@@ -0,0 +1,309 @@
+package gather
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newCacheTestServer 缓存测试Server，返回Server及“完整响应/304响应”计数
+// /fresh：max-age=60；/etag：no-cache + ETag；/modified：仅Last-Modified；/nostore：no-store
+func newCacheTestServer() (*httptest.Server, *atomic.Int32, *atomic.Int32) {
+	full, notModified := new(atomic.Int32), new(atomic.Int32)
+	lastModified := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/fresh", func(w http.ResponseWriter, r *http.Request) {
+		full.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Set-Cookie", "sid=1")
+		w.Write([]byte("fresh"))
+	})
+	mux.HandleFunc("/etag", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.Header().Set("X-Revalidated", "1")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		w.Write([]byte("etag-body"))
+	})
+	mux.HandleFunc("/modified", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=0")
+		w.Header().Set("Last-Modified", lastModified)
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		w.Write([]byte("modified-body"))
+	})
+	mux.HandleFunc("/nostore", func(w http.ResponseWriter, r *http.Request) {
+		full.Add(1)
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write([]byte("nostore"))
+	})
+	return httptest.NewServer(mux), full, notModified
+}
+
+// TestCache_Memory 测试新鲜期命中、ETag/Last-Modified条件请求及no-store
+func TestCache_Memory(t *testing.T) {
+	server, full, notModified := newCacheTestServer()
+	defer server.Close()
+
+	ga := NewGather("chrome", false)
+	cache := NewMemoryCache(10)
+	ga.SetCache(cache)
+
+	// 新鲜期内不发出请求；首次响应下发了Cookie，之后携带Cookie的请求使用独立的缓存项
+	for i := 0; i < 3; i++ {
+		html, _, err := ga.Get(server.URL+"/fresh", "")
+		if err != nil || html != "fresh" {
+			t.Fatalf("请求失败：%q, %v", html, err)
+		}
+	}
+	if full.Load() != 2 {
+		t.Errorf("新鲜期内应只按会话各请求1次，实际%d次", full.Load())
+	}
+	resp, err := ga.GetResponse(server.URL+"/fresh", "", "")
+	if err != nil || !resp.FromCache || resp.Header.Get("Set-Cookie") != "" {
+		t.Errorf("缓存响应应标记FromCache且不含Set-Cookie：%v, %v", resp.Header, err)
+	}
+
+	// ETag：每次重新验证，304时返回缓存内容
+	full.Store(0)
+	for i := 0; i < 3; i++ {
+		html, _, err := ga.GetUtil(server.URL+"/etag", "", "")
+		if err != nil || html != "etag-body" {
+			t.Fatalf("第%d次请求结果错误：%q, %v", i+1, html, err)
+		}
+	}
+	if full.Load() != 1 || notModified.Load() != 2 {
+		t.Errorf("应完整下载1次、304两次，实际%d次、%d次", full.Load(), notModified.Load())
+	}
+	resp, _ = ga.GetResponse(server.URL+"/etag", "", "")
+	if !resp.FromCache || resp.StatusCode != http.StatusOK || resp.Header.Get("X-Revalidated") != "1" {
+		t.Errorf("304后应返回200缓存内容并更新响应头：%d, %v", resp.StatusCode, resp.Header)
+	}
+
+	// Last-Modified
+	full.Store(0)
+	notModified.Store(0)
+	ga.Get(server.URL+"/modified", "")
+	html, _, err := ga.Get(server.URL+"/modified", "")
+	if err != nil || html != "modified-body" || full.Load() != 1 || notModified.Load() != 1 {
+		t.Errorf("If-Modified-Since重新验证失败：%q, %v, %d, %d", html, err, full.Load(), notModified.Load())
+	}
+
+	// no-store不缓存
+	full.Store(0)
+	ga.Get(server.URL+"/nostore", "")
+	ga.Get(server.URL+"/nostore", "")
+	if full.Load() != 2 {
+		t.Errorf("no-store响应不应缓存，实际请求%d次", full.Load())
+	}
+
+	// 关闭缓存后直接请求
+	ga.SetCache(nil)
+	full.Store(0)
+	ga.Get(server.URL+"/fresh", "")
+	if full.Load() != 1 {
+		t.Errorf("关闭缓存后应重新请求")
+	}
+}
+
+// TestCache_Disk 测试磁盘缓存跨实例复用
+func TestCache_Disk(t *testing.T) {
+	server, full, _ := newCacheTestServer()
+	defer server.Close()
+
+	dir := t.TempDir()
+	cache, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatalf("创建磁盘缓存失败：%v", err)
+	}
+	ga := NewGather("chrome", false)
+	ga.SetCache(cache)
+	ga.Get(server.URL+"/fresh", "")
+
+	// 模拟程序重启：新建实例和缓存对象
+	cache2, _ := NewDiskCache(dir)
+	pool := NewGatherUtilPool(map[string]string{"User-Agent": "test"}, "", 30, false, 2)
+	pool.SetCache(cache2)
+	html, _, err := pool.Get(server.URL+"/fresh", "")
+	if err != nil || html != "fresh" || full.Load() != 1 {
+		t.Errorf("磁盘缓存应跨实例命中：%q, %v, 请求%d次", html, err, full.Load())
+	}
+	cache2.Delete(server.URL + "/fresh")
+	if _, ok := cache2.Get(server.URL + "/fresh"); ok {
+		t.Error("删除后不应命中")
+	}
+}
+
+// TestCache_Credentials 测试携带不同凭据的请求不共享缓存
+func TestCache_Credentials(t *testing.T) {
+	var full atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		full.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("user:" + r.Header.Get("Authorization") + r.Header.Get("Cookie")))
+	}))
+	defer server.Close()
+
+	cache := NewMemoryCache(10)
+	ga := NewGather("chrome", false)
+	ga.SetCache(cache)
+	get := func(headers map[string]string, cookies string) string {
+		t.Helper()
+		for k, v := range headers {
+			ga.safeHeaders.Store(k, v)
+			defer ga.safeHeaders.Delete(k)
+		}
+		html, _, err := ga.GetUtil(server.URL, "", cookies)
+		if err != nil {
+			t.Fatalf("请求失败：%v", err)
+		}
+		return html
+	}
+
+	if html := get(nil, ""); html != "user:" {
+		t.Errorf("匿名请求结果错误：%q", html)
+	}
+	if html := get(nil, "sid=alice"); html != "user:sid=alice" {
+		t.Errorf("携带Cookie的请求不应命中匿名缓存：%q", html)
+	}
+	if html := get(nil, "sid=bob"); html != "user:sid=bob" {
+		t.Errorf("不同Cookie的请求不应共享缓存：%q", html)
+	}
+	if html := get(map[string]string{"Authorization": "Bearer a"}, ""); html != "user:Bearer a" {
+		t.Errorf("携带Authorization的请求不应命中匿名缓存：%q", html)
+	}
+	if full.Load() != 4 {
+		t.Errorf("应请求4次，实际%d次", full.Load())
+	}
+	// 凭据相同时仍然命中
+	if html := get(nil, "sid=alice"); html != "user:sid=alice" || full.Load() != 4 {
+		t.Errorf("相同凭据应命中缓存：%q, 请求%d次", html, full.Load())
+	}
+
+	// Cookie管理器中的会话Cookie同样区分缓存
+	u, _ := url.Parse(server.URL)
+	ga.J.SetCookies(u, []*http.Cookie{{Name: "session", Value: "carol"}})
+	if html := get(nil, ""); html != "user:session=carol" {
+		t.Errorf("Cookie管理器中的Cookie应参与缓存键：%q", html)
+	}
+}
+
+// TestCache_RequestDirectives 测试请求头Cache-Control对缓存的影响
+func TestCache_RequestDirectives(t *testing.T) {
+	var full atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		full.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	ga := NewGather("chrome", false)
+	ga.SetCache(NewMemoryCache(10))
+	testCases := []struct {
+		header map[string]string
+		want   int32 // 累计请求次数
+	}{
+		{nil, 1},
+		{nil, 1},
+		{map[string]string{"Cache-Control": "no-cache"}, 2},
+		{map[string]string{"Cache-Control": "max-age=0"}, 3},
+		{map[string]string{"Cache-Control": "max-age=30"}, 3},
+		{map[string]string{"Cache-Control": "min-fresh=120"}, 4},
+		{map[string]string{"Pragma": "no-cache"}, 5},
+		{map[string]string{"Cache-Control": "max-stale"}, 5},
+	}
+	for i, tc := range testCases {
+		for k, v := range tc.header {
+			ga.safeHeaders.Store(k, v)
+		}
+		_, _, err := ga.Get(server.URL, "")
+		for k := range tc.header {
+			ga.safeHeaders.Delete(k)
+		}
+		if err != nil {
+			t.Fatalf("第%d次请求失败：%v", i+1, err)
+		}
+		if full.Load() != tc.want {
+			t.Errorf("第%d次请求%v后应累计请求%d次，实际%d次", i+1, tc.header, tc.want, full.Load())
+		}
+	}
+}
+
+// TestCacheEntry_Usable 测试请求指令max-age/min-fresh/max-stale的判断
+func TestCacheEntry_Usable(t *testing.T) {
+	now := time.Now()
+	newEntry := func(cacheControl string) *CacheEntry {
+		h := http.Header{}
+		h.Set("Cache-Control", cacheControl)
+		return &CacheEntry{Header: h, RequestTime: now, ResponseTime: now}
+	}
+	testCases := []struct {
+		response string
+		request  string
+		after    time.Duration
+		want     bool
+	}{
+		{"max-age=60", "", 30 * time.Second, true},
+		{"max-age=60", "max-age=10", 30 * time.Second, false},
+		{"max-age=60", "max-age=40", 30 * time.Second, true},
+		{"max-age=60", "min-fresh=20", 30 * time.Second, true},
+		{"max-age=60", "min-fresh=40", 30 * time.Second, false},
+		{"max-age=60", "max-stale", 10 * time.Minute, true},
+		{"max-age=60", "max-stale=30", 80 * time.Second, true},
+		{"max-age=60", "max-stale=30", 100 * time.Second, false},
+		{"max-age=60, must-revalidate", "max-stale", 90 * time.Second, false},
+		{"no-cache", "max-stale", 0, false},
+		{"max-age=60", "max-age=abc", 0, false},
+		{"max-age=99999999999999999999", "", 1000 * time.Hour, true},
+	}
+	for _, tc := range testCases {
+		got := newEntry(tc.response).usable(parseCacheControl(tc.request), now.Add(tc.after))
+		if got != tc.want {
+			t.Errorf("响应%q 请求%q 经过%v后usable=%v，期望%v", tc.response, tc.request, tc.after, got, tc.want)
+		}
+	}
+}
+
+// TestCacheEntry_Fresh 测试新鲜期计算
+func TestCacheEntry_Fresh(t *testing.T) {
+	now := time.Now()
+	newEntry := func(header map[string]string) *CacheEntry {
+		h := http.Header{}
+		for k, v := range header {
+			h.Set(k, v)
+		}
+		return &CacheEntry{Header: h, RequestTime: now, ResponseTime: now}
+	}
+	testCases := []struct {
+		header map[string]string
+		after  time.Duration
+		want   bool
+	}{
+		{map[string]string{"Cache-Control": "max-age=60"}, 30 * time.Second, true},
+		{map[string]string{"Cache-Control": "max-age=60"}, 90 * time.Second, false},
+		{map[string]string{"Cache-Control": "max-age=60", "Age": "50"}, 20 * time.Second, false},
+		{map[string]string{"Cache-Control": "no-cache, max-age=60"}, 0, false},
+		{map[string]string{"Expires": now.Add(time.Minute).UTC().Format(http.TimeFormat)}, 30 * time.Second, true},
+		{map[string]string{"Expires": "0"}, 0, false},
+		{map[string]string{"Last-Modified": now.Add(-100 * time.Minute).UTC().Format(http.TimeFormat)}, 5 * time.Minute, true},
+		{map[string]string{"Last-Modified": now.Add(-100 * time.Minute).UTC().Format(http.TimeFormat)}, 15 * time.Minute, false},
+	}
+	for _, tc := range testCases {
+		if got := newEntry(tc.header).fresh(now.Add(tc.after)); got != tc.want {
+			t.Errorf("%v 经过%v后fresh=%v，期望%v", tc.header, tc.after, got, tc.want)
+		}
+	}
+}
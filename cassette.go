@@ -0,0 +1,416 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrCassetteMiss 回放模式下cassette中没有与请求匹配的记录
+var ErrCassetteMiss = errors.New("cassette中没有匹配的记录")
+
+// cassetteRedacted 录制文件中替换Set-Cookie取值的占位符
+const cassetteRedacted = "REDACTED"
+
+// CassetteMode cassette工作模式
+type CassetteMode int
+
+const (
+	// CassetteRecord 录制：所有请求真实发出，并覆盖写入cassette文件
+	CassetteRecord CassetteMode = iota
+	// CassetteReplay 回放：有匹配记录时直接回放，没有时真实请求并追加录制
+	CassetteReplay
+	// CassetteReplayOrFail 仅回放：没有匹配记录时返回ErrCassetteMiss，不发出任何真实请求（适合离线测试）
+	CassetteReplayOrFail
+)
+
+// Cassette 请求录制/回放器，把真实的请求与响应保存到磁盘，之后可离线、确定性地回放
+// 请求按“方法 + URL（查询参数排序）+ 规范化后的请求体”匹配：
+// 表单按参数排序、JSON按键排序、multipart忽略随机boundary，其余按原始字节比较
+// 同一请求录制了多次时按顺序依次回放（如同一URL的翻页），用完后重复回放最后一条
+// 录制文件不保存请求头；响应的Set-Cookie默认原样保存，保证回放登录流程时Cookie可用，
+// 需要脱敏的Cookie用RedactCookies指定，其取值替换为REDACTED
+// 示例：
+//
+//	cassette, err := gather.NewCassette("testdata/list.json", gather.CassetteReplayOrFail)
+//	ga.UseCassette(cassette) // Pool同样使用pool.UseCassette(cassette)
+//	html, _, err := ga.Get(URL, "") // 从testdata/list.json回放
+type Cassette struct {
+	mu           sync.Mutex
+	path         string
+	mode         CassetteMode
+	interactions []*cassetteInteraction
+	played       map[*cassetteInteraction]bool
+	redacted     map[string]bool // 录制时取值需要脱敏的Cookie名称
+}
+
+// cassetteFile cassette文件格式
+type cassetteFile struct {
+	Interactions []*cassetteInteraction `json:"interactions"`
+}
+
+// cassetteInteraction 一次录制的请求与响应
+type cassetteInteraction struct {
+	Request    cassetteRequest  `json:"request"`
+	Response   cassetteResponse `json:"response"`
+	RecordedAt time.Time        `json:"recordedAt"`
+}
+
+// cassetteRequest 录制的请求（不保存请求头，避免泄露Cookie、Authorization等敏感信息）
+type cassetteRequest struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        string `json:"body,omitempty"`
+}
+
+// cassetteResponse 录制的响应（响应体保持传输时的原始字节，回放时同样经过解压、转码流程）
+type cassetteResponse struct {
+	StatusCode   int         `json:"statusCode"`
+	Status       string      `json:"status"`
+	Proto        string      `json:"proto"`
+	Header       http.Header `json:"header"`
+	Body         string      `json:"body"`
+	BodyEncoding string      `json:"bodyEncoding,omitempty"` // 非UTF-8内容为"base64"
+}
+
+// NewCassette 创建cassette
+// 录制模式下从空记录开始（第一次录制时覆盖已有文件）；回放模式下加载已有文件，文件不存在时视为空记录
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	c := &Cassette{path: path, mode: mode, played: make(map[*cassetteInteraction]bool)}
+	if mode == CassetteRecord {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file cassetteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析cassette文件%s失败: %w", path, err)
+	}
+	c.interactions = file.Interactions
+	return c, nil
+}
+
+// Len 返回已录制的请求数
+func (c *Cassette) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.interactions)
+}
+
+// RedactCookies 指定录制时需要脱敏的Cookie名称，这些Cookie在Set-Cookie中的取值替换为REDACTED，
+// 名称和属性保留；默认不脱敏。脱敏后回放时下发的是占位值，依赖该Cookie取值的回放流程会受影响
+func (c *Cassette) RedactCookies(names ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.redacted = make(map[string]bool, len(names))
+	for _, name := range names {
+		c.redacted[name] = true
+	}
+}
+
+// Transport 返回使用本cassette的RoundTripper，base为真实发出请求的Transport（nil时使用http.DefaultTransport）
+// 可用于自行创建的http.Client
+func (c *Cassette) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &cassetteTransport{cassette: c, base: base, maxBodySize: func() int64 { return 0 }}
+}
+
+// UseCassette 为实例挂载cassette（nil表示卸载，恢复真实请求）
+// cassette作用于Transport层，跳转的每一跳、Cookie下发均按录制内容回放
+// 录制时同样遵守实例的SetMaxBodySize，超过上限的响应不录制并返回ErrBodyTooLarge
+func (g *GatherStruct) UseCassette(c *Cassette) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	base := g.Client.Transport
+	if ct, ok := base.(*cassetteTransport); ok {
+		base = ct.base
+	}
+	if c == nil {
+		g.Client.Transport = base
+		return
+	}
+	// 请求发出期间调用方持有g.locker，此时直接读取maxBodySize（与roundTrip相同）
+	g.Client.Transport = &cassetteTransport{cassette: c, base: base, maxBodySize: func() int64 { return g.maxBodySize }}
+}
+
+// UseCassette 为池内所有实例挂载同一个cassette
+func (p *Pool) UseCassette(c *Cassette) {
+	for _, ga := range p.pool {
+		ga.UseCassette(c)
+	}
+}
+
+// cassetteTransport 录制/回放Transport
+type cassetteTransport struct {
+	cassette    *Cassette
+	base        http.RoundTripper
+	maxBodySize func() int64 // 录制时读取响应体的上限，<=0表示不限制
+}
+
+// RoundTrip 按模式回放或真实请求并录制
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := cassetteRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	record := cassetteRequest{Method: req.Method, URL: req.URL.String(), ContentType: req.Header.Get("Content-Type"), Body: string(body)}
+	if record.Method == "" {
+		record.Method = http.MethodGet
+	}
+
+	c := t.cassette
+	if c.mode != CassetteRecord {
+		interaction := c.find(record)
+		if interaction != nil || c.mode == CassetteReplayOrFail {
+			// 不发出真实请求，按RoundTripper约定关闭请求体
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			if interaction == nil {
+				return nil, fmt.Errorf("%w: %s %s", ErrCassetteMiss, record.Method, record.URL)
+			}
+			return interaction.Response.toHTTP(req)
+		}
+	}
+
+	// 真实请求：请求体已被读取时用副本重新发出
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := t.readBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := &cassetteInteraction{
+		Request:    record,
+		Response:   c.newResponse(resp, respBody),
+		RecordedAt: time.Now(),
+	}
+	if err := c.add(interaction); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// readBody 读取并关闭响应体，设置了大小上限时与roundTrip一样多读1字节用于判断是否超限
+func (t *cassetteTransport) readBody(resp *http.Response) ([]byte, error) {
+	defer resp.Body.Close()
+	limit := t.maxBodySize()
+	if limit <= 0 {
+		return io.ReadAll(resp.Body)
+	}
+	if resp.ContentLength > limit {
+		return nil, fmt.Errorf("%w: Content-Length %d 超过 %d 字节", ErrBodyTooLarge, resp.ContentLength, limit)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%w: 超过 %d 字节", ErrBodyTooLarge, limit)
+	}
+	return body, nil
+}
+
+// find 查找与请求匹配且未回放过的记录，全部回放过时返回最后一条匹配的记录
+func (c *Cassette) find(record cassetteRequest) *cassetteInteraction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := cassetteKey(record)
+	var last *cassetteInteraction
+	for _, interaction := range c.interactions {
+		if cassetteKey(interaction.Request) != key {
+			continue
+		}
+		if !c.played[interaction] {
+			c.played[interaction] = true
+			return interaction
+		}
+		last = interaction
+	}
+	return last
+}
+
+// add 追加一条记录并立即写入文件（先写临时文件再重命名，中断不会损坏已有文件）
+func (c *Cassette) add(interaction *cassetteInteraction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, interaction)
+	c.played[interaction] = true
+
+	data, err := json.MarshalIndent(cassetteFile{Interactions: c.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.path, data)
+}
+
+// cassetteRequestBody 读取请求体副本
+func cassetteRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+	return io.ReadAll(req.Body)
+}
+
+// cassetteKey 请求的匹配键：方法 + 规范化URL + 规范化请求体
+func cassetteKey(record cassetteRequest) string {
+	return strings.ToUpper(record.Method) + " " + normalizeCassetteURL(record.URL) + "\n" + normalizeCassetteBody(record.ContentType, record.Body)
+}
+
+// normalizeCassetteURL 查询参数按名称排序，去掉片段
+func normalizeCassetteURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.Fragment, u.RawFragment = "", ""
+	if u.RawQuery != "" {
+		if query, err := url.ParseQuery(u.RawQuery); err == nil {
+			u.RawQuery = query.Encode()
+		}
+	}
+	return u.String()
+}
+
+// normalizeCassetteBody 规范化请求体，使参数顺序、JSON键顺序、multipart随机boundary不影响匹配
+func normalizeCassetteBody(contentType, body string) string {
+	if body == "" {
+		return ""
+	}
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		if values, err := url.ParseQuery(body); err == nil {
+			return values.Encode()
+		}
+	case strings.Contains(mediaType, "json"):
+		var v any
+		if err := json.Unmarshal([]byte(body), &v); err == nil {
+			if data, err := json.Marshal(v); err == nil {
+				return string(data)
+			}
+		}
+	case strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "":
+		return strings.ReplaceAll(body, params["boundary"], "BOUNDARY")
+	}
+	return body
+}
+
+// newResponse 录制响应，非UTF-8内容（如gzip压缩数据、图片）以base64保存，RedactCookies指定的Cookie取值脱敏
+func (c *Cassette) newResponse(resp *http.Response, body []byte) cassetteResponse {
+	record := cassetteResponse{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Proto:      resp.Proto,
+		Header:     resp.Header.Clone(),
+	}
+	c.mu.Lock()
+	if cookies := record.Header.Values("Set-Cookie"); len(cookies) > 0 && len(c.redacted) > 0 {
+		redacted := make([]string, len(cookies))
+		for i, cookie := range cookies {
+			redacted[i] = redactSetCookie(cookie, c.redacted)
+		}
+		record.Header["Set-Cookie"] = redacted
+	}
+	c.mu.Unlock()
+	if utf8.Valid(body) {
+		record.Body = string(body)
+	} else {
+		record.Body = base64.StdEncoding.EncodeToString(body)
+		record.BodyEncoding = "base64"
+	}
+	return record
+}
+
+// redactSetCookie 名称在names中时把Set-Cookie的取值替换为占位符，保留名称和属性，回放时Cookie的下发、
+// 作用域和过期行为不变（请求匹配不依赖Cookie取值）
+func redactSetCookie(value string, names map[string]bool) string {
+	pair, attrs, hasAttrs := strings.Cut(value, ";")
+	name, _, _ := strings.Cut(pair, "=")
+	name = strings.TrimSpace(name)
+	if !names[name] {
+		return value
+	}
+	redacted := name + "=" + cassetteRedacted
+	if hasAttrs {
+		redacted += ";" + attrs
+	}
+	return redacted
+}
+
+// toHTTP 把录制的响应还原为标准库响应
+func (r cassetteResponse) toHTTP(req *http.Request) (*http.Response, error) {
+	body := []byte(r.Body)
+	if r.BodyEncoding == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("cassette响应体解码失败: %w", err)
+		}
+		body = decoded
+	}
+	proto := r.Proto
+	major, minor, ok := http.ParseHTTPVersion(proto)
+	if !ok {
+		proto, major, minor = "HTTP/1.1", 1, 1
+	}
+	status := r.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode))
+	}
+	header := r.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		StatusCode:    r.StatusCode,
+		Status:        status,
+		Proto:         proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,188 @@
+package gather
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestCassette 测试录制、回放及仅回放模式
+func TestCassette(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "abc", Path: "/"})
+			http.Redirect(w, r, "/home", http.StatusFound)
+			return
+		}
+		if r.URL.Path == "/home" {
+			cookie, _ := r.Cookie("sid")
+			if cookie == nil {
+				w.Write([]byte("no-cookie"))
+				return
+			}
+			w.Write([]byte("home:" + cookie.Value))
+			return
+		}
+		if r.URL.Path == "/gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipBytes([]byte("压缩内容")))
+			return
+		}
+		r.ParseForm()
+		w.Write([]byte(r.Method + ":" + r.Form.Encode() + ":" + string(rune('0'+n))))
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "cassettes", "test.json")
+
+	// 录制
+	recorder, _ := NewCassette(path, CassetteRecord)
+	ga := NewGatherUtil(map[string]string{"User-Agent": "chrome"}, "", 30, false)
+	ga.UseCassette(recorder)
+	loginHTML, _, err := ga.PostUtil(server.URL+"/login", "", "", map[string]string{"user": "ydg", "pwd": "1"})
+	if err != nil || loginHTML != "home:abc" {
+		t.Fatalf("录制时请求失败：%q, %v", loginHTML, err)
+	}
+	gzipHTML, _, _ := ga.Get(server.URL+"/gzip", "")
+	page1, _, _ := ga.Get(server.URL+"/list?b=2&a=1", "")
+	page2, _, _ := ga.Get(server.URL+"/list?b=2&a=1", "")
+	if recorder.Len() != 5 || page1 == page2 {
+		t.Fatalf("应录制5条记录（含跳转），实际%d条", recorder.Len())
+	}
+	server.Close()
+
+	// 默认不脱敏，录制文件保存登录Cookie的原始取值
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "sid=abc; Path=/") {
+		t.Errorf("录制文件应保存Set-Cookie的原始取值：%s", data)
+	}
+
+	// 仅回放：服务器已关闭，按录制内容回放（查询参数、表单顺序不影响匹配）
+	replay, err := NewCassette(path, CassetteReplayOrFail)
+	if err != nil || replay.Len() != 5 {
+		t.Fatalf("加载cassette失败：%v", err)
+	}
+	pool := NewGatherUtilPool(map[string]string{"User-Agent": "test"}, "", 30, false, 1)
+	pool.UseCassette(replay)
+	if html, _, err := pool.PostUtil(server.URL+"/login", "", "", map[string]string{"pwd": "1", "user": "ydg"}); err != nil || html != loginHTML {
+		t.Errorf("回放登录流程失败：%q, %v", html, err)
+	}
+	if cookie, ok := pool.pool[0].J.Get("127.0.0.1", "sid"); !ok || cookie.Value != "abc" {
+		t.Errorf("回放登录流程后jar中应有登录Cookie的原始取值：%v", cookie)
+	}
+	if html, _, err := pool.Get(server.URL+"/gzip", ""); err != nil || html != gzipHTML || html != "压缩内容" {
+		t.Errorf("回放压缩响应失败：%q, %v", html, err)
+	}
+	first, _, _ := pool.Get(server.URL+"/list?a=1&b=2", "")
+	second, _, _ := pool.Get(server.URL+"/list?a=1&b=2", "")
+	third, _, _ := pool.Get(server.URL+"/list?a=1&b=2", "")
+	if first != page1 || second != page2 || third != page2 {
+		t.Errorf("同一请求应按录制顺序回放：%q %q %q", first, second, third)
+	}
+	if _, _, err := pool.Get(server.URL+"/missing", ""); !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("没有匹配记录时应返回ErrCassetteMiss，实际：%v", err)
+	}
+	if _, _, err := pool.PostUtil(server.URL+"/login", "", "", map[string]string{"user": "other"}); !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("请求体不同时不应匹配，实际：%v", err)
+	}
+
+	// 卸载后恢复真实请求
+	ga.UseCassette(nil)
+	if _, _, err := ga.Get(server.URL+"/gzip", ""); err == nil || errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("卸载cassette后应真实请求（服务器已关闭），实际：%v", err)
+	}
+}
+
+// TestCassette_RedactCookies 测试只脱敏指定名称的Cookie
+func TestCassette_RedactCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "token", Value: "secret", Path: "/", HttpOnly: true})
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "abc", Path: "/"})
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "redact.json")
+
+	recorder, _ := NewCassette(path, CassetteRecord)
+	recorder.RedactCookies("token")
+	ga := NewGather("chrome", false)
+	ga.UseCassette(recorder)
+	ga.Get(server.URL+"/login", "")
+	if cookie, ok := ga.J.Get("127.0.0.1", "token"); !ok || cookie.Value != "secret" {
+		t.Errorf("脱敏只影响录制文件，录制时的请求应拿到原始取值：%v", cookie)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "secret") || !strings.Contains(string(data), "token=REDACTED; Path=/; HttpOnly") || !strings.Contains(string(data), "sid=abc") {
+		t.Errorf("应只脱敏指定的Cookie并保留属性：%s", data)
+	}
+
+	replay, _ := NewCassette(path, CassetteReplayOrFail)
+	ga2 := NewGather("chrome", false)
+	ga2.UseCassette(replay)
+	ga2.Get(server.URL+"/login", "")
+	if got := jarCookieString(t, ga2.J, server.URL+"/"); got != "token=REDACTED; sid=abc" {
+		t.Errorf("回放时应下发录制的取值：%q", got)
+	}
+}
+
+// TestCassette_Replay 测试回放模式缺失记录时真实请求并追加录制
+func TestCassette_Replay(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "replay.json")
+
+	cassette, _ := NewCassette(path, CassetteReplay)
+	ga := NewGather("chrome", false)
+	ga.UseCassette(cassette)
+	ga.PostJson(server.URL+"/api", "", `{"b":1,"a":2}`)
+
+	cassette2, _ := NewCassette(path, CassetteReplay)
+	ga.UseCassette(cassette2)
+	if html, _, err := ga.PostJson(server.URL+"/api", "", `{"a":2, "b":1}`); err != nil || html != "ok" || hits.Load() != 1 {
+		t.Errorf("JSON键顺序不同也应命中录制：%q, %v, 请求%d次", html, err, hits.Load())
+	}
+	ga.Get(server.URL+"/new", "")
+	if hits.Load() != 2 || cassette2.Len() != 2 {
+		t.Errorf("缺失的记录应真实请求并追加录制，实际请求%d次、记录%d条", hits.Load(), cassette2.Len())
+	}
+}
+
+// TestCassette_MaxBodySize 测试录制时遵守SetMaxBodySize，超限的响应不录制
+func TestCassette_MaxBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			w.Write([]byte(strings.Repeat("a", 50)))
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(strings.Repeat("b", 50)))
+	}))
+	defer server.Close()
+	cassette, _ := NewCassette(filepath.Join(t.TempDir(), "limit.json"), CassetteRecord)
+	ga := NewGather("chrome", false)
+	ga.SetMaxBodySize(80)
+	ga.UseCassette(cassette)
+
+	if _, _, err := ga.Get(server.URL+"/chunked", ""); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("录制超限响应应返回ErrBodyTooLarge，实际：%v", err)
+	}
+	if _, _, err := ga.Get(server.URL+"/small", ""); err != nil {
+		t.Errorf("未超限的响应应正常录制：%v", err)
+	}
+	if cassette.Len() != 1 {
+		t.Errorf("超限的响应不应录制，实际记录%d条", cassette.Len())
+	}
+	ga.SetMaxBodySize(0)
+	if _, _, err := ga.Get(server.URL+"/chunked", ""); err != nil || cassette.Len() != 2 {
+		t.Errorf("取消上限后应正常录制：%v, %d条", err, cassette.Len())
+	}
+}
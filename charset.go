@@ -0,0 +1,423 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/binary"
+	"fmt"
+	"mime"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// 字符集解码表（内嵌，无需任何网络依赖）
+// gb18030.bin：GB18030双字节区映射表，按指针(lead-0x81)*190+(trail偏移)顺序存放的uint16(小端)，覆盖GB2312/GBK
+// gb18030_ranges.bin：GB18030四字节区(BMP部分)的分段线性映射表，每段为(指针uint16, 码点uint16)
+// big5.bin：Big5(CP950)双字节映射表，按指针(lead-0x81)*157+(trail偏移)顺序存放的uint16(小端)，0表示无映射
+var (
+	//go:embed data/gb18030.bin
+	gb18030TableData []byte
+	//go:embed data/gb18030_ranges.bin
+	gb18030RangesData []byte
+	//go:embed data/big5.bin
+	big5TableData []byte
+)
+
+// 统一的规范化字符集名称
+const (
+	CharsetUTF8        = "utf-8"
+	CharsetGBK         = "gbk" // GB2312/GBK均归为gbk，解码时使用其超集GB18030
+	CharsetGB18030     = "gb18030"
+	CharsetBig5        = "big5"
+	CharsetUTF16LE     = "utf-16le"
+	CharsetUTF16BE     = "utf-16be"
+	CharsetWindows1252 = "windows-1252" // ISO-8859-1/Latin1按浏览器惯例按windows-1252处理
+)
+
+// charsetLabels 常见字符集标签到规范名称的映射（参考WHATWG Encoding标准的标签表）
+var charsetLabels = func() map[string]string {
+	groups := map[string][]string{
+		CharsetUTF8:        {"utf-8", "utf8", "unicode-1-1-utf-8"},
+		CharsetGBK:         {"gb2312", "gbk", "x-gbk", "cp936", "ms936", "windows-936", "csgb2312", "chinese", "euc-cn", "gb_2312", "gb_2312-80", "iso-ir-58", "csiso58gb231280"},
+		CharsetGB18030:     {"gb18030"},
+		CharsetBig5:        {"big5", "big5-hkscs", "cn-big5", "csbig5", "x-x-big5", "cp950"},
+		CharsetUTF16LE:     {"utf-16", "utf-16le", "unicode", "ucs-2"},
+		CharsetUTF16BE:     {"utf-16be", "unicodefffe"},
+		CharsetWindows1252: {"iso-8859-1", "iso8859-1", "iso_8859-1", "latin1", "l1", "us-ascii", "ascii", "windows-1252", "cp1252"},
+	}
+	labels := make(map[string]string)
+	for charset, names := range groups {
+		for _, name := range names {
+			labels[name] = charset
+		}
+	}
+	return labels
+}()
+
+// NormalizeCharset 把字符集标签规范化为本包支持的名称，不支持的标签返回空字符串
+// 例：NormalizeCharset("GB2312") == "gbk"，NormalizeCharset(" UTF8 ") == "utf-8"
+func NormalizeCharset(label string) string {
+	label = strings.ToLower(strings.Trim(strings.TrimSpace(label), `"'`))
+	return charsetLabels[label]
+}
+
+// DetectCharset 检测响应体的字符集，返回规范化名称
+// 检测优先级（与浏览器一致）：
+// 1. BOM（UTF-8/UTF-16LE/UTF-16BE）
+// 2. Content-Type响应头中的charset参数
+// 3. 页面前4KB中的<meta charset>、<meta http-equiv="Content-Type">或<?xml encoding?>声明
+// 4. 均未声明时：内容是合法UTF-8则为utf-8，否则按中文站点惯例回退为gb18030
+func DetectCharset(contentType string, body []byte) string {
+	if cs := charsetFromBOM(body); cs != "" {
+		return cs
+	}
+	if contentType != "" {
+		if _, params, err := mime.ParseMediaType(contentType); err == nil {
+			if cs := NormalizeCharset(params["charset"]); cs != "" {
+				return cs
+			}
+		}
+	}
+	if cs := charsetFromMeta(body); cs != "" {
+		return cs
+	}
+	if utf8.Valid(body) {
+		return CharsetUTF8
+	}
+	return CharsetGB18030
+}
+
+// ToUTF8 把指定字符集的数据转码为UTF-8
+// charset支持NormalizeCharset可识别的所有标签；无法解码的字节替换为U+FFFD
+// 不支持的字符集返回原数据及错误
+func ToUTF8(data []byte, charset string) ([]byte, error) {
+	cs := NormalizeCharset(charset)
+	switch cs {
+	case CharsetUTF8:
+		return bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF")), nil
+	case CharsetGBK, CharsetGB18030:
+		return decodeGB18030(data), nil
+	case CharsetBig5:
+		return decodeBig5(data), nil
+	case CharsetUTF16LE:
+		return decodeUTF16(bytes.TrimPrefix(data, []byte{0xFF, 0xFE}), binary.LittleEndian), nil
+	case CharsetUTF16BE:
+		return decodeUTF16(bytes.TrimPrefix(data, []byte{0xFE, 0xFF}), binary.BigEndian), nil
+	case CharsetWindows1252:
+		return decodeWindows1252(data), nil
+	}
+	return data, fmt.Errorf("不支持的字符集：%s", charset)
+}
+
+// charsetFromBOM 根据BOM判断字符集
+func charsetFromBOM(body []byte) string {
+	switch {
+	case bytes.HasPrefix(body, []byte("\xEF\xBB\xBF")):
+		return CharsetUTF8
+	case bytes.HasPrefix(body, []byte{0xFF, 0xFE}):
+		return CharsetUTF16LE
+	case bytes.HasPrefix(body, []byte{0xFE, 0xFF}):
+		return CharsetUTF16BE
+	}
+	return ""
+}
+
+// charsetFromMeta 预扫描页面头部的meta/xml声明，提取charset
+func charsetFromMeta(body []byte) string {
+	if len(body) > 4096 {
+		body = body[:4096]
+	}
+	head := strings.ToLower(string(body))
+
+	// <?xml version="1.0" encoding="gbk"?>
+	if strings.HasPrefix(strings.TrimSpace(head), "<?xml") {
+		if end := strings.Index(head, "?>"); end > 0 {
+			if cs := NormalizeCharset(attrValue(head[:end], "encoding")); cs != "" {
+				return cs
+			}
+		}
+	}
+
+	// <meta charset="gbk"> 或 <meta http-equiv="Content-Type" content="text/html; charset=gb2312">
+	for rest := head; ; {
+		start := strings.Index(rest, "<meta")
+		if start < 0 {
+			return ""
+		}
+		rest = rest[start+len("<meta"):]
+		end := strings.IndexByte(rest, '>')
+		if end < 0 {
+			return ""
+		}
+		tag := rest[:end]
+		cs := NormalizeCharset(attrValue(tag, "charset"))
+		if cs == "" {
+			cs = NormalizeCharset(charsetFromContent(attrValue(tag, "content")))
+		}
+		if cs != "" {
+			// UTF-16声明出现在ASCII兼容的页面中时无意义，浏览器按UTF-8处理
+			if cs == CharsetUTF16LE || cs == CharsetUTF16BE {
+				return CharsetUTF8
+			}
+			return cs
+		}
+		rest = rest[end:]
+	}
+}
+
+// attrValue 在标签文本中逐个扫描属性，返回名称等于name的属性值（支持单双引号和无引号）
+// 按属性边界匹配，x-charset-info="..."之类包含name的属性名或属性值不会误匹配
+func attrValue(tag, name string) string {
+	for rest := tag; rest != ""; {
+		var key, value string
+		key, value, rest = nextAttr(rest)
+		if key == name {
+			return value
+		}
+	}
+	return ""
+}
+
+// nextAttr 读取标签文本中的下一个属性，返回属性名、属性值（没有值时为空）和剩余文本
+// 属性名到空白、'='、'/'、'>'为止；无引号的值到空白或'>'为止，末尾的'/'视为自闭合标记
+func nextAttr(tag string) (name, value, rest string) {
+	rest = strings.TrimLeft(tag, " \t\r\n/>")
+	end := strings.IndexAny(rest, " \t\r\n=/>")
+	if end < 0 {
+		return rest, "", ""
+	}
+	name, rest = rest[:end], strings.TrimLeft(rest[end:], " \t\r\n")
+	if !strings.HasPrefix(rest, "=") {
+		return name, "", rest
+	}
+	rest = strings.TrimLeft(rest[1:], " \t\r\n")
+	if rest == "" {
+		return name, "", ""
+	}
+	if q := rest[0]; q == '"' || q == '\'' {
+		end := strings.IndexByte(rest[1:], q)
+		if end < 0 {
+			return name, "", ""
+		}
+		return name, rest[1 : end+1], rest[end+2:]
+	}
+	end = strings.IndexAny(rest, " \t\r\n>")
+	if end < 0 {
+		end = len(rest)
+	}
+	return name, strings.TrimSuffix(rest[:end], "/"), rest[end:]
+}
+
+// charsetFromContent 从meta的content值（如"text/html; charset=gb2312"）中提取charset参数
+func charsetFromContent(content string) string {
+	for _, param := range strings.Split(content, ";") {
+		if key, value, ok := strings.Cut(param, "="); ok && strings.TrimSpace(key) == "charset" {
+			return value
+		}
+	}
+	return ""
+}
+
+// ---------------------- 解码表加载（首次使用时解析） ----------------------
+var (
+	charsetTableOnce  sync.Once
+	gb18030Table      []uint16
+	gb18030Ranges     [][2]uint16
+	big5Table         []uint16
+	windows1252Extras = [32]rune{
+		0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021, 0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+		0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014, 0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+	}
+)
+
+// loadCharsetTables 解析内嵌的二进制解码表
+func loadCharsetTables() {
+	charsetTableOnce.Do(func() {
+		gb18030Table = readUint16s(gb18030TableData)
+		big5Table = readUint16s(big5TableData)
+		pairs := readUint16s(gb18030RangesData)
+		for i := 0; i+1 < len(pairs); i += 2 {
+			gb18030Ranges = append(gb18030Ranges, [2]uint16{pairs[i], pairs[i+1]})
+		}
+	})
+}
+
+// readUint16s 按小端序读取uint16数组
+func readUint16s(data []byte) []uint16 {
+	out := make([]uint16, len(data)/2)
+	for i := range out {
+		out[i] = binary.LittleEndian.Uint16(data[i*2:])
+	}
+	return out
+}
+
+// ---------------------- 各字符集解码实现 ----------------------
+// decodeGB18030 解码GB18030（兼容GB2312/GBK），单字节/双字节/四字节均支持
+func decodeGB18030(data []byte) []byte {
+	loadCharsetTables()
+	out := make([]byte, 0, len(data)*3/2)
+	for i := 0; i < len(data); {
+		b1 := data[i]
+		switch {
+		case b1 < 0x80:
+			out = append(out, b1)
+			i++
+			continue
+		case b1 == 0x80:
+			out = utf8.AppendRune(out, 0x20AC) // GBK中0x80为欧元符号
+			i++
+			continue
+		case b1 == 0xFF || i+1 >= len(data):
+			out = utf8.AppendRune(out, utf8.RuneError)
+			i++
+			continue
+		}
+
+		b2 := data[i+1]
+		// 四字节序列：81-FE 30-39 81-FE 30-39
+		if b2 >= 0x30 && b2 <= 0x39 {
+			if i+3 >= len(data) || data[i+2] < 0x81 || data[i+2] > 0xFE || data[i+3] < 0x30 || data[i+3] > 0x39 {
+				out = utf8.AppendRune(out, utf8.RuneError)
+				i++
+				continue
+			}
+			pointer := ((int(b1)-0x81)*10+int(b2)-0x30)*1260 + (int(data[i+2])-0x81)*10 + int(data[i+3]) - 0x30
+			out = utf8.AppendRune(out, gb18030FourByte(pointer))
+			i += 4
+			continue
+		}
+
+		// 双字节序列：81-FE 40-7E/80-FE
+		if b2 < 0x40 || b2 == 0x7F || b2 == 0xFF {
+			out = utf8.AppendRune(out, utf8.RuneError)
+			i++
+			continue
+		}
+		offset := 0x40
+		if b2 >= 0x80 {
+			offset = 0x41
+		}
+		pointer := (int(b1)-0x81)*190 + int(b2) - offset
+		if r := gb18030Table[pointer]; r != 0 {
+			out = utf8.AppendRune(out, rune(r))
+		} else {
+			out = utf8.AppendRune(out, utf8.RuneError)
+		}
+		i += 2
+	}
+	return out
+}
+
+// gb18030FourByte 把GB18030四字节指针转换为码点
+func gb18030FourByte(pointer int) rune {
+	// 辅助平面：线性映射
+	if pointer >= 189000 && pointer <= 1237575 {
+		return rune(0x10000 + pointer - 189000)
+	}
+	if pointer > 39419 {
+		return utf8.RuneError
+	}
+	// BMP：分段线性映射，查找指针所在的最后一个分段
+	idx := sort.Search(len(gb18030Ranges), func(i int) bool {
+		return int(gb18030Ranges[i][0]) > pointer
+	}) - 1
+	if idx < 0 {
+		return utf8.RuneError
+	}
+	return rune(int(gb18030Ranges[idx][1]) + pointer - int(gb18030Ranges[idx][0]))
+}
+
+// decodeBig5 解码Big5(CP950)
+func decodeBig5(data []byte) []byte {
+	loadCharsetTables()
+	out := make([]byte, 0, len(data)*3/2)
+	for i := 0; i < len(data); {
+		b1 := data[i]
+		if b1 < 0x80 {
+			out = append(out, b1)
+			i++
+			continue
+		}
+		if b1 < 0x81 || b1 == 0xFF || i+1 >= len(data) {
+			out = utf8.AppendRune(out, utf8.RuneError)
+			i++
+			continue
+		}
+		b2 := data[i+1]
+		var pointer int
+		switch {
+		case b2 >= 0x40 && b2 <= 0x7E:
+			pointer = (int(b1)-0x81)*157 + int(b2) - 0x40
+		case b2 >= 0xA1 && b2 <= 0xFE:
+			pointer = (int(b1)-0x81)*157 + int(b2) - 0x62
+		default:
+			out = utf8.AppendRune(out, utf8.RuneError)
+			i++
+			continue
+		}
+		if r := big5Table[pointer]; r != 0 {
+			out = utf8.AppendRune(out, rune(r))
+		} else {
+			out = utf8.AppendRune(out, utf8.RuneError)
+		}
+		i += 2
+	}
+	return out
+}
+
+// decodeUTF16 解码UTF-16（按指定字节序）
+func decodeUTF16(data []byte, order binary.ByteOrder) []byte {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[i*2:])
+	}
+	out := make([]byte, 0, len(data))
+	for _, r := range utf16.Decode(units) {
+		out = utf8.AppendRune(out, r)
+	}
+	if len(data)%2 == 1 {
+		out = utf8.AppendRune(out, utf8.RuneError)
+	}
+	return out
+}
+
+// decodeWindows1252 解码windows-1252/ISO-8859-1
+func decodeWindows1252(data []byte) []byte {
+	out := make([]byte, 0, len(data)+len(data)/2)
+	for _, b := range data {
+		switch {
+		case b < 0x80:
+			out = append(out, b)
+		case b < 0xA0:
+			out = utf8.AppendRune(out, windows1252Extras[b-0x80])
+		default:
+			out = utf8.AppendRune(out, rune(b))
+		}
+	}
+	return out
+}
+
+// SetAutoCharset 开启/关闭自动字符集转码（默认开启）
+// 开启时，Get/Post等方法返回的html以及Response.Text()会自动转码为UTF-8；
+// 关闭时原样返回服务器数据（Response.Charset仍会给出检测结果，便于调用方自行处理）
+func (g *GatherStruct) SetAutoCharset(enable bool) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	g.charsetDisabled = !enable
+}
+
+// SetAutoCharset 为池内所有实例开启/关闭自动字符集转码
+func (p *Pool) SetAutoCharset(enable bool) {
+	for _, ga := range p.pool {
+		ga.SetAutoCharset(enable)
+	}
+}
@@ -0,0 +1,113 @@
+package gather
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestToUTF8 测试各字符集转码
+func TestToUTF8(t *testing.T) {
+	testCases := []struct {
+		name    string
+		charset string
+		data    []byte
+		want    string
+	}{
+		{"GBK双字节", "gbk", []byte{0xD6, 0xD0, 0xCE, 0xC4, 0xCD, 0xF8, 0xD2, 0xB3}, "中文网页"},
+		{"GB2312标签", "GB2312", []byte("<p>\xD6\xD0\xCE\xC4</p>"), "<p>中文</p>"},
+		{"GB18030双字节", "gb18030", []byte{0xA8, 0xA6, 0xA2, 0xE3}, "é€"},
+		{"GB18030四字节BMP", "gb18030", []byte{0x81, 0x30, 0x84, 0x36, 0xA8, 0xB9}, "¥ü"},
+		{"GB18030四字节辅助平面", "gb18030", []byte{0x94, 0x39, 0xFC, 0x36}, "😀"},
+		{"Big5", "big5", []byte{0xA4, 0xA4, 0xA4, 0xE5, 0xC1, 0x63, 0xC5, 0xE9}, "中文繁體"},
+		{"UTF-16LE", "utf-16le", []byte{0xFF, 0xFE, 0x2D, 0x4E, 0x87, 0x65}, "中文"},
+		{"UTF-16BE", "utf-16be", []byte{0x4E, 0x2D, 0x65, 0x87}, "中文"},
+		{"Latin1", "iso-8859-1", []byte{0x63, 0x61, 0x66, 0xE9, 0x80}, "café€"},
+		{"非法字节替换", "gbk", []byte{0x41, 0xFF, 0x42}, "A�B"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ToUTF8(tc.data, tc.charset)
+			if err != nil {
+				t.Fatalf("转码失败：%v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("转码结果不符：期望%q，实际%q", tc.want, string(got))
+			}
+		})
+	}
+
+	if _, err := ToUTF8([]byte("abc"), "x-unknown"); err == nil {
+		t.Error("不支持的字符集应返回错误")
+	}
+}
+
+// TestDetectCharset 测试字符集检测优先级
+func TestDetectCharset(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"BOM优先", "text/html; charset=gbk", "\xEF\xBB\xBFabc", CharsetUTF8},
+		{"Content-Type", "text/html; charset=GB2312", "<html></html>", CharsetGBK},
+		{"meta charset", "text/html", `<html><head><meta charset="big5"></head>`, CharsetBig5},
+		{"meta http-equiv", "text/html", `<meta http-equiv="Content-Type" content="text/html; charset=gb18030">`, CharsetGB18030},
+		{"meta属性名边界", "text/html", `<meta name="x-charset-info" charset="gbk">`, CharsetGBK},
+		{"meta属性值中的charset", "text/html", `<meta name="description" content="charset=big5 示例" charset=gbk>`, CharsetGBK},
+		{"meta无引号自闭合", "text/html", `<meta charset=big5/>`, CharsetBig5},
+		{"meta无引号content", "text/html", `<meta http-equiv=Content-Type content=text/html;charset=big5>`, CharsetBig5},
+		{"xml声明", "application/xml", `<?xml version="1.0" encoding="GBK"?><a/>`, CharsetGBK},
+		{"无声明UTF-8", "text/html", "<p>中文</p>", CharsetUTF8},
+		{"无声明非UTF-8回退", "text/html", "<p>\xD6\xD0\xCE\xC4</p>", CharsetGB18030},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := DetectCharset(tc.contentType, []byte(tc.body)); got != tc.want {
+				t.Errorf("检测结果不符：期望%s，实际%s", tc.want, got)
+			}
+		})
+	}
+}
+
+// TestGather_AutoCharset 测试GBK页面自动转码及关闭开关
+func TestGather_AutoCharset(t *testing.T) {
+	gbkPage := "<html><head><meta http-equiv=\"Content-Type\" content=\"text/html; charset=gb2312\"></head><body>\xD6\xD0\xCE\xC4</body></html>"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(gbkPage))
+	}))
+	defer server.Close()
+
+	ga := NewGather("chrome", false)
+	html, _, err := ga.Get(server.URL, "")
+	if err != nil {
+		t.Fatalf("请求失败：%v", err)
+	}
+	if !strings.Contains(html, "<body>中文</body>") {
+		t.Errorf("GBK页面未转码为UTF-8：%q", html)
+	}
+
+	resp, err := ga.GetResponse(server.URL, "", "")
+	if err != nil {
+		t.Fatalf("请求失败：%v", err)
+	}
+	if resp.Charset != CharsetGBK {
+		t.Errorf("检测字符集不符：期望gbk，实际%s", resp.Charset)
+	}
+	if string(resp.Body) != gbkPage {
+		t.Error("Response.Body应保持服务器原始编码")
+	}
+
+	// 关闭自动转码后原样返回
+	ga.SetAutoCharset(false)
+	html, _, err = ga.Get(server.URL, "")
+	if err != nil {
+		t.Fatalf("请求失败：%v", err)
+	}
+	if html != gbkPage {
+		t.Errorf("关闭自动转码后内容应原样返回：%q", html)
+	}
+}
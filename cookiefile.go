@@ -0,0 +1,368 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cookieFileVersion Cookie文件格式版本
+const cookieFileVersion = 1
+
+// cookieFile SaveCookies写出的JSON文件结构
+type cookieFile struct {
+	Version int           `json:"version"`
+	Cookies []savedCookie `json:"cookies"`
+}
+
+// savedCookie 文件中的单个Cookie，保留jar中的全部属性
+type savedCookie struct {
+	Name       string    `json:"name"`
+	Value      string    `json:"value"`
+	Quoted     bool      `json:"quoted,omitempty"`
+	Domain     string    `json:"domain"`
+	Path       string    `json:"path"`
+	HostOnly   bool      `json:"hostOnly"`
+	Secure     bool      `json:"secure"`
+	HttpOnly   bool      `json:"httpOnly"`
+	SameSite   string    `json:"sameSite,omitempty"` // Strict、Lax、None，未声明时为空
+	Persistent bool      `json:"persistent"`
+	Expires    time.Time `json:"expires,omitzero"` // 会话Cookie不写出
+	Creation   time.Time `json:"creation"`
+	LastAccess time.Time `json:"lastAccess"`
+}
+
+// SaveCookies 把jar中所有未过期的Cookie（含会话Cookie）以JSON格式保存到path
+// 先写临时文件再重命名，写入中断不会损坏已有文件；文件权限为0600
+// 示例：
+//
+//	ga.Get("https://example.com/login", "") // 登录
+//	if err := ga.J.SaveCookies("cookies/example.json"); err != nil {
+//	    log.Println(err)
+//	}
+func (j *WebCookieJar) SaveCookies(path string) error {
+	// 串行写入，且在写锁内取快照，保证最后写入的是最新状态
+	j.saveMu.Lock()
+	defer j.saveMu.Unlock()
+	j.lk.Lock()
+	file := cookieFile{Version: cookieFileVersion, Cookies: j.snapshot(j.now())}
+	j.lk.Unlock()
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// LoadCookies 从SaveCookies保存的文件中加载Cookie，与jar中已有的Cookie合并（名称、域名、路径相同的被替换）
+// 域名按jar的存储形式规范化（小写、去掉前导点、端口和末尾的点），名称为空、路径不以"/"开头、
+// 已过期的Cookie及作用于公共后缀的域Cookie被忽略，文件不存在时返回的错误满足errors.Is(err, os.ErrNotExist)
+func (j *WebCookieJar) LoadCookies(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var file cookieFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("解析Cookie文件%s失败：%w", path, err)
+	}
+	if file.Version != cookieFileVersion {
+		return fmt.Errorf("不支持的Cookie文件版本：%d", file.Version)
+	}
+
+	j.lk.Lock()
+	now := j.now()
+	for _, c := range file.Cookies {
+		// 文件可能经过手工编辑或由其他工具生成，不能假定与jar中的存储形式一致
+		domain := normalizeCookieDomain(c.Domain)
+		if domain == "" || c.Name == "" || !strings.HasPrefix(c.Path, "/") {
+			continue
+		}
+		e := &jarEntry{
+			Name: c.Name, Value: c.Value, Quoted: c.Quoted, Domain: domain, Path: c.Path, HostOnly: c.HostOnly,
+			Secure: c.Secure, HttpOnly: c.HttpOnly, SameSite: parseSameSite(c.SameSite),
+			Persistent: c.Persistent, Expires: c.Expires, Creation: c.Creation, LastAccess: c.LastAccess,
+		}
+		if e.expired(now) || (!e.HostOnly && j.publicSuffixes().IsPublicSuffix(e.Domain)) {
+			continue
+		}
+		j.store(e)
+	}
+	j.lk.Unlock()
+	j.changed()
+	return nil
+}
+
+// SetAutoSave 开启自动保存：jar发生变化（新增、替换、删除、清理过期Cookie）时立即写入path
+// 开启时若path已存在，先从中加载Cookie（进程重启后恢复登录状态），path为空表示关闭
+// 自动保存失败时通过日志（见GatherStruct.SetLogger，未设置时为slog默认日志）以Warn级别报告
+func (j *WebCookieJar) SetAutoSave(path string) error {
+	j.lk.Lock()
+	j.autoSavePath = ""
+	j.lk.Unlock()
+	if path == "" {
+		return nil
+	}
+	if err := j.LoadCookies(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	j.lk.Lock()
+	j.autoSavePath = path
+	j.lk.Unlock()
+	return j.SaveCookies(path)
+}
+
+// changed jar发生变化后调用，开启了自动保存时写入文件，调用方不能持有j.lk
+func (j *WebCookieJar) changed() {
+	j.lk.Lock()
+	path, logger := j.autoSavePath, j.logger
+	j.lk.Unlock()
+	if path == "" {
+		return
+	}
+	if err := j.SaveCookies(path); err != nil {
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.LogAttrs(context.Background(), slog.LevelWarn, "自动保存cookie失败",
+			slog.String("path", path), slog.String("error", err.Error()))
+	}
+}
+
+// snapshot 返回所有未过期Cookie的副本，按域名、路径、名称排序，调用方需持有j.lk
+func (j *WebCookieJar) snapshot(now time.Time) []savedCookie {
+	cookies := []savedCookie{}
+	for _, domainEntries := range j.entries {
+		for _, e := range domainEntries {
+			if e.expired(now) {
+				continue
+			}
+			cookies = append(cookies, savedCookie{
+				Name: e.Name, Value: e.Value, Quoted: e.Quoted, Domain: e.Domain, Path: e.Path, HostOnly: e.HostOnly,
+				Secure: e.Secure, HttpOnly: e.HttpOnly, SameSite: sameSiteString(e.SameSite),
+				Persistent: e.Persistent, Expires: e.Expires, Creation: e.Creation, LastAccess: e.LastAccess,
+			})
+		}
+	}
+	sort.Slice(cookies, func(a, b int) bool {
+		if cookies[a].Domain != cookies[b].Domain {
+			return cookies[a].Domain < cookies[b].Domain
+		}
+		if cookies[a].Path != cookies[b].Path {
+			return cookies[a].Path < cookies[b].Path
+		}
+		return cookies[a].Name < cookies[b].Name
+	})
+	return cookies
+}
+
+// sameSiteString SameSite属性的文本形式
+func sameSiteString(mode http.SameSite) string {
+	switch mode {
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteNoneMode:
+		return "None"
+	}
+	return ""
+}
+
+// parseSameSite 解析SameSite属性的文本形式，无法识别时为默认模式
+func parseSameSite(v string) http.SameSite {
+	switch v {
+	case "Strict", "strict":
+		return http.SameSiteStrictMode
+	case "Lax", "lax":
+		return http.SameSiteLaxMode
+	case "None", "none":
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteDefaultMode
+}
+
+// netscapeHeader Netscape cookies.txt文件头（curl、wget及浏览器导出插件通用）
+const netscapeHeader = "# Netscape HTTP Cookie File\n# This file was generated by gather. Edit at your own risk.\n\n"
+
+// netscapeHttpOnlyPrefix curl等工具在域名字段前加该前缀表示HttpOnly
+const netscapeHttpOnlyPrefix = "#HttpOnly_"
+
+// WriteNetscape 以Netscape cookies.txt格式写出所有未过期的Cookie，可直接用于curl -b、wget --load-cookies
+// 每行7个字段以Tab分隔：域名、是否包含子域名、路径、是否Secure、过期时间（Unix秒，会话Cookie为0）、名称、值
+// 域Cookie的域名带前导点且第二个字段为TRUE，host-only为FALSE；HttpOnly的Cookie域名前加#HttpOnly_
+// SameSite属性无法在该格式中表示，不写出
+func (j *WebCookieJar) WriteNetscape(w io.Writer) error {
+	j.lk.Lock()
+	cookies := j.snapshot(j.now())
+	j.lk.Unlock()
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(netscapeHeader)
+	for _, c := range cookies {
+		domain, includeSubdomains := c.Domain, "FALSE"
+		if !c.HostOnly {
+			domain, includeSubdomains = "."+c.Domain, "TRUE"
+		}
+		if c.HttpOnly {
+			domain = netscapeHttpOnlyPrefix + domain
+		}
+		var expires int64
+		if c.Persistent {
+			expires = c.Expires.Unix()
+		}
+		fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, includeSubdomains, c.Path, netscapeBool(c.Secure), expires, c.Name, c.Value)
+	}
+	return bw.Flush()
+}
+
+// SaveNetscape 以Netscape cookies.txt格式保存到path（先写临时文件再重命名，文件权限为0600）
+func (j *WebCookieJar) SaveNetscape(path string) error {
+	var b strings.Builder
+	if err := j.WriteNetscape(&b); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, []byte(b.String()))
+}
+
+// ReadNetscape 从Netscape cookies.txt格式导入Cookie，与jar中已有的Cookie合并，返回导入的数量
+// 空行和注释行被忽略，已过期的Cookie及作用于公共后缀的域Cookie被忽略；任何一行格式错误时返回错误且不导入任何Cookie
+func (j *WebCookieJar) ReadNetscape(r io.Reader) (int, error) {
+	j.lk.Lock()
+	now := j.now()
+	j.lk.Unlock()
+
+	var entries []*jarEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := strings.HasPrefix(line, netscapeHttpOnlyPrefix)
+		if httpOnly {
+			line = line[len(netscapeHttpOnlyPrefix):]
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		e, err := parseNetscapeLine(line, now)
+		if err != nil {
+			return 0, fmt.Errorf("cookies.txt第%d行：%w", lineNo, err)
+		}
+		e.HttpOnly = httpOnly
+		if !e.expired(now) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	j.lk.Lock()
+	count := 0
+	for _, e := range entries {
+		if !e.HostOnly && j.publicSuffixes().IsPublicSuffix(e.Domain) {
+			continue
+		}
+		j.store(e)
+		count++
+	}
+	j.lk.Unlock()
+	if count > 0 {
+		j.changed()
+	}
+	return count, nil
+}
+
+// LoadNetscape 从Netscape cookies.txt文件导入Cookie，返回导入的数量
+func (j *WebCookieJar) LoadNetscape(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return j.ReadNetscape(f)
+}
+
+// parseNetscapeLine 解析cookies.txt中的一行（值为空时部分工具只输出6个字段）
+func parseNetscapeLine(line string, now time.Time) (*jarEntry, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) == 6 {
+		fields = append(fields, "")
+	}
+	if len(fields) != 7 {
+		return nil, fmt.Errorf("应为7个以Tab分隔的字段，实际%d个", len(fields))
+	}
+	domain := strings.ToLower(strings.TrimSpace(fields[0]))
+	hostOnly := !strings.HasPrefix(domain, ".")
+	domain = normalizeCookieDomain(domain)
+	if domain == "" || fields[5] == "" {
+		return nil, errors.New("域名和名称不能为空")
+	}
+	// 第二个字段与域名的前导点应一致，以字段为准
+	switch strings.ToUpper(fields[1]) {
+	case "TRUE":
+		hostOnly = false
+	case "FALSE":
+		hostOnly = true
+	default:
+		return nil, fmt.Errorf("无法识别的子域名标记：%s", fields[1])
+	}
+	secure, err := parseNetscapeBool(fields[3])
+	if err != nil {
+		return nil, err
+	}
+	expires, err := strconv.ParseFloat(fields[4], 64)
+	if err != nil || expires < 0 || math.IsInf(expires, 0) {
+		return nil, fmt.Errorf("无法识别的过期时间：%s", fields[4])
+	}
+	path := fields[2]
+	if path == "" || path[0] != '/' {
+		path = "/"
+	}
+	e := &jarEntry{
+		Name: fields[5], Value: fields[6], Domain: domain, Path: path, HostOnly: hostOnly,
+		Secure: secure, Creation: now, LastAccess: now,
+	}
+	if expires > 0 {
+		e.Persistent, e.Expires = true, time.Unix(int64(expires), 0)
+	}
+	return e, nil
+}
+
+// netscapeBool cookies.txt中的布尔值
+func netscapeBool(v bool) string {
+	if v {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// parseNetscapeBool 解析cookies.txt中的布尔值（不区分大小写）
+func parseNetscapeBool(v string) (bool, error) {
+	switch strings.ToUpper(v) {
+	case "TRUE":
+		return true, nil
+	case "FALSE":
+		return false, nil
+	}
+	return false, fmt.Errorf("无法识别的布尔值：%s", v)
+}
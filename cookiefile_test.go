@@ -0,0 +1,178 @@
+package gather
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCookieFile_SaveLoad 测试保存、加载Cookie并保留全部属性
+func TestCookieFile_SaveLoad(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	path := filepath.Join(t.TempDir(), "cookies", "example.json")
+
+	jar := newWebCookieJar(false)
+	jar.SetClock(clock)
+	jar.SetCookies(mustParseURL(t, "https://passport.example.com/login"), []*http.Cookie{
+		{Name: "sso", Value: "1", Domain: ".example.com", Path: "/", Secure: true, HttpOnly: true, SameSite: http.SameSiteLaxMode, MaxAge: 3600},
+		{Name: "session", Value: "2", Path: "/"},
+		{Name: "short", Value: "3", Path: "/", MaxAge: 10},
+	})
+	if err := jar.SaveCookies(path); err != nil {
+		t.Fatalf("保存失败：%v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Cookie文件权限应为0600：%v, %v", info.Mode(), err)
+	}
+
+	loaded := newWebCookieJar(false)
+	now = now.Add(time.Minute) // short已过期，加载时忽略
+	loaded.SetClock(clock)
+	if err := loaded.LoadCookies(path); err != nil {
+		t.Fatalf("加载失败：%v", err)
+	}
+	sso := loaded.entries["example.com"]["sso;/"]
+	if sso == nil || sso.Value != "1" || sso.HostOnly || !sso.Secure || !sso.HttpOnly || sso.SameSite != http.SameSiteLaxMode ||
+		!sso.Persistent || !sso.Expires.Equal(now.Add(-time.Minute).Add(time.Hour)) {
+		t.Errorf("属性未完整保留：%+v", sso)
+	}
+	session := loaded.entries["passport.example.com"]["session;/"]
+	if session == nil || !session.HostOnly || session.Persistent || !session.Expires.IsZero() {
+		t.Errorf("会话Cookie未正确保留：%+v", session)
+	}
+	if loaded.entries["passport.example.com"]["short;/"] != nil {
+		t.Errorf("已过期的Cookie不应加载")
+	}
+	if got := jarCookieString(t, loaded, "https://www.example.com/"); got != "sso=1" {
+		t.Errorf("加载后应发送域Cookie：%q", got)
+	}
+
+	// 手工编辑的文件：域名规范化，名称为空或路径无效的Cookie忽略
+	edited := filepath.Join(t.TempDir(), "edited.json")
+	os.WriteFile(edited, []byte(`{"version":1,"cookies":[
+		{"name":"token","value":"4","domain":".WWW.Example.COM.","path":"/","hostOnly":false},
+		{"name":"","value":"5","domain":"example.com","path":"/","hostOnly":true},
+		{"name":"nopath","value":"6","domain":"example.com","path":"","hostOnly":true},
+		{"name":"psl","value":"7","domain":".COM","path":"/","hostOnly":false}
+	]}`), 0o600)
+	manual := newWebCookieJar(false)
+	if err := manual.LoadCookies(edited); err != nil {
+		t.Fatalf("加载失败：%v", err)
+	}
+	if manual.Len() != 1 || manual.entries["www.example.com"]["token;/"] == nil {
+		t.Errorf("应只加载规范化后的token：%v", manual.entries)
+	}
+	if got := jarCookieString(t, manual, "https://a.www.example.com/"); got != "token=4" {
+		t.Errorf("规范化后的域Cookie应发送给子域名：%q", got)
+	}
+
+	if err := loaded.LoadCookies(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("文件不存在时应返回os.ErrNotExist：%v", err)
+	}
+	os.WriteFile(path, []byte("not json"), 0o600)
+	if err := loaded.LoadCookies(path); err == nil {
+		t.Errorf("文件格式错误时应返回错误")
+	}
+}
+
+// TestCookieFile_AutoSave 测试自动保存及重启后恢复
+func TestCookieFile_AutoSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	u := mustParseURL(t, "http://www.example.com/")
+
+	jar := newWebCookieJar(false)
+	if err := jar.SetAutoSave(path); err != nil {
+		t.Fatalf("开启自动保存失败：%v", err)
+	}
+	jar.SetCookies(u, []*http.Cookie{{Name: "sid", Value: "abc", MaxAge: 3600}})
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), `"value": "abc"`) {
+		t.Errorf("新增Cookie后应自动保存：%s", data)
+	}
+
+	// 模拟进程重启
+	restarted := newWebCookieJar(false)
+	if err := restarted.SetAutoSave(path); err != nil {
+		t.Fatalf("开启自动保存失败：%v", err)
+	}
+	if got := jarCookieString(t, restarted, u.String()); got != "sid=abc" {
+		t.Errorf("重启后应恢复Cookie：%q", got)
+	}
+
+	// 服务器删除Cookie后文件同步更新
+	restarted.SetCookies(u, []*http.Cookie{{Name: "sid", MaxAge: -1}})
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "sid") {
+		t.Errorf("删除Cookie后应自动保存：%s", data)
+	}
+
+	// 关闭自动保存
+	restarted.SetAutoSave("")
+	restarted.SetCookies(u, []*http.Cookie{{Name: "other", Value: "1"}})
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "other") {
+		t.Errorf("关闭后不应再自动保存：%s", data)
+	}
+}
+
+// TestCookieFile_Netscape 测试Netscape cookies.txt的导入与导出
+func TestCookieFile_Netscape(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	jar := newWebCookieJar(false)
+	jar.SetClock(func() time.Time { return now })
+
+	// curl导出的文件：带HttpOnly前缀、Windows换行、6字段空值行
+	cookiesTxt := "# Netscape HTTP Cookie File\r\n" +
+		"# https://curl.se/docs/http-cookies.html\r\n" +
+		"\r\n" +
+		".example.com\tTRUE\t/\tTRUE\t1800000000\tsso\tabc\r\n" +
+		"#HttpOnly_www.example.com\tFALSE\t/app\tFALSE\t0\tsid\txyz\r\n" +
+		"www.example.com\tFALSE\t/\tFALSE\t1600000000\told\t1\r\n" + // 已过期
+		"www.example.com\tFALSE\t/\tFALSE\t0\tempty\r\n"
+	n, err := jar.ReadNetscape(strings.NewReader(cookiesTxt))
+	if err != nil || n != 3 {
+		t.Fatalf("导入失败：%d, %v", n, err)
+	}
+	sso := jar.entries["example.com"]["sso;/"]
+	if sso == nil || sso.HostOnly || !sso.Secure || !sso.Persistent || sso.Expires.Unix() != 1800000000 {
+		t.Errorf("域Cookie导入错误：%+v", sso)
+	}
+	sid := jar.entries["www.example.com"]["sid;/app"]
+	if sid == nil || !sid.HostOnly || !sid.HttpOnly || sid.Persistent {
+		t.Errorf("host-only会话Cookie导入错误：%+v", sid)
+	}
+	if got := jarCookieString(t, jar, "https://www.example.com/app/list"); got != "sid=xyz; sso=abc; empty=" {
+		t.Errorf("导入后发送的Cookie错误：%q", got)
+	}
+
+	// 导出后再导入结果一致
+	var out strings.Builder
+	if err := jar.WriteNetscape(&out); err != nil {
+		t.Fatalf("导出失败：%v", err)
+	}
+	for _, line := range []string{
+		".example.com\tTRUE\t/\tTRUE\t1800000000\tsso\tabc",
+		"#HttpOnly_www.example.com\tFALSE\t/app\tFALSE\t0\tsid\txyz",
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("导出缺少%q：\n%s", line, out.String())
+		}
+	}
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	if err := jar.SaveNetscape(path); err != nil {
+		t.Fatalf("保存失败：%v", err)
+	}
+	other := newWebCookieJar(false)
+	other.SetClock(func() time.Time { return now })
+	if n, err := other.LoadNetscape(path); err != nil || n != 3 {
+		t.Errorf("重新导入失败：%d, %v", n, err)
+	}
+
+	// 格式错误时不导入任何Cookie
+	bad := newWebCookieJar(false)
+	if _, err := bad.ReadNetscape(strings.NewReader(".a.com\tTRUE\t/\tFALSE\t0\tok\t1\nbroken line\n")); err == nil || len(bad.entries) != 0 {
+		t.Errorf("格式错误时应返回错误且不导入：%v, %v", err, bad.entries)
+	}
+}
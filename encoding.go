@@ -0,0 +1,251 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ContentDecoder 内容编码解码函数：输入按某种Content-Encoding压缩的数据，返回解码后的数据
+type ContentDecoder func(data []byte) ([]byte, error)
+
+// limitedDecoder 注册表内部使用的解码函数，limit>=0时解码结果超过limit字节返回ErrBodyTooLarge
+// 内置解码器在解码过程中检查，不会先解出全部数据；自定义解码器只能在解码完成后检查
+type limitedDecoder func(data []byte, limit int64) ([]byte, error)
+
+// 内置支持的内容编码名称
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+	EncodingBrotli  = "br"
+	EncodingZstd    = "zstd"
+)
+
+// decoderRegistry 全局解码器注册表，键为小写的Content-Encoding名称
+// names为在Accept-Encoding中声明的编码，按注册顺序保存（保证声明顺序稳定）
+// br/zstd为纯Go实现的解码器，默认只用于解码服务器主动返回的数据，不在Accept-Encoding中声明，
+// 需要时通过SetAcceptEncoding显式声明
+var decoderRegistry = struct {
+	sync.RWMutex
+	names    []string
+	decoders map[string]limitedDecoder
+}{
+	names: []string{EncodingGzip, EncodingDeflate},
+	decoders: map[string]limitedDecoder{
+		EncodingGzip:    decodeGzip,
+		EncodingDeflate: decodeDeflate,
+		EncodingBrotli:  brotliDecode,
+		EncodingZstd:    zstdDecode,
+	},
+}
+
+// encodingAliases 历史遗留的编码别名（RFC 9110要求按gzip处理x-gzip）
+var encodingAliases = map[string]string{
+	"x-gzip": EncodingGzip,
+}
+
+// RegisterDecoder 注册（或替换）指定Content-Encoding的解码器，decoder为nil时移除该编码
+// 注册后该编码会自动出现在默认请求头的Accept-Encoding中，响应也会按其自动解码
+// 示例：
+//
+//	gather.RegisterDecoder("lz4", func(data []byte) ([]byte, error) {
+//	    return myLz4Decode(data)
+//	})
+//	gather.RegisterDecoder("zstd", nil) // 不再解码zstd
+func RegisterDecoder(name string, decoder ContentDecoder) {
+	name = normalizeEncoding(name)
+	if name == "" || name == "identity" {
+		return
+	}
+	decoderRegistry.Lock()
+	defer decoderRegistry.Unlock()
+	if decoder == nil {
+		delete(decoderRegistry.decoders, name)
+		decoderRegistry.names = slices.DeleteFunc(decoderRegistry.names, func(v string) bool { return v == name })
+		return
+	}
+	if !slices.Contains(decoderRegistry.names, name) {
+		decoderRegistry.names = append(decoderRegistry.names, name)
+	}
+	decoderRegistry.decoders[name] = func(data []byte, limit int64) ([]byte, error) {
+		decoded, err := decoder(data)
+		if err != nil {
+			return nil, err
+		}
+		if limit >= 0 && int64(len(decoded)) > limit {
+			return nil, fmt.Errorf("%w: 解码后超过 %d 字节", ErrBodyTooLarge, limit)
+		}
+		return decoded, nil
+	}
+}
+
+// SetAcceptEncoding 设置默认请求头Accept-Encoding中声明的编码及顺序，未注册的编码会被忽略
+// 不传参数时恢复默认的"gzip, deflate"；只影响之后创建的实例
+// 示例：
+//
+//	gather.SetAcceptEncoding("gzip", "deflate", "br", "zstd") // 同时声明br、zstd
+func SetAcceptEncoding(names ...string) {
+	if len(names) == 0 {
+		names = []string{EncodingGzip, EncodingDeflate}
+	}
+	decoderRegistry.Lock()
+	defer decoderRegistry.Unlock()
+	var kept []string
+	for _, name := range names {
+		name = normalizeEncoding(name)
+		if _, ok := decoderRegistry.decoders[name]; ok && !slices.Contains(kept, name) {
+			kept = append(kept, name)
+		}
+	}
+	decoderRegistry.names = kept
+}
+
+// AcceptEncoding 返回默认请求头使用的Accept-Encoding值
+// 默认值为"gzip, deflate"，可通过SetAcceptEncoding修改，RegisterDecoder注册的编码会追加在末尾
+func AcceptEncoding() string {
+	decoderRegistry.RLock()
+	defer decoderRegistry.RUnlock()
+	return strings.Join(decoderRegistry.names, ", ")
+}
+
+// DecodeContent 按Content-Encoding响应头解码数据
+// 支持多重编码（如"gzip, br"表示先gzip再br压缩，解码时按相反顺序逐层解开）
+// 遇到未注册的编码返回错误，identity及空值直接跳过
+func DecodeContent(data []byte, contentEncoding string) ([]byte, error) {
+	return decodeContent(data, contentEncoding, -1)
+}
+
+// decodeContent 按Content-Encoding逐层解码，limit>=0时任一层解码结果超过limit字节即返回ErrBodyTooLarge
+func decodeContent(data []byte, contentEncoding string, limit int64) ([]byte, error) {
+	encodings := strings.Split(contentEncoding, ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		name := normalizeEncoding(encodings[i])
+		if name == "" || name == "identity" {
+			continue
+		}
+		decoderRegistry.RLock()
+		decoder := decoderRegistry.decoders[name]
+		decoderRegistry.RUnlock()
+		if decoder == nil {
+			return nil, fmt.Errorf("不支持的内容编码：%s", name)
+		}
+		decoded, err := decoder(data, limit)
+		if err != nil {
+			return nil, fmt.Errorf("%s解码失败：%w", name, err)
+		}
+		data = decoded
+	}
+	return data, nil
+}
+
+// normalizeEncoding 规范化编码名称（小写、去空格、处理别名）
+func normalizeEncoding(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := encodingAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// filterAcceptEncoding 过滤Accept-Encoding中无法解码的编码（如历史默认值中的sdch）
+// 保留identity、*及已注册编码，保留原有的q值参数
+func filterAcceptEncoding(value string) string {
+	decoderRegistry.RLock()
+	defer decoderRegistry.RUnlock()
+	var kept []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		token := item
+		if idx := strings.IndexByte(token, ';'); idx >= 0 {
+			token = token[:idx]
+		}
+		token = normalizeEncoding(token)
+		if _, ok := decoderRegistry.decoders[token]; ok || token == "identity" || token == "*" {
+			kept = append(kept, item)
+		}
+	}
+	return strings.Join(kept, ", ")
+}
+
+// decodeResponseBody 按响应头解码响应体，成功后移除Content-Encoding/Content-Length（与标准库透明解压行为一致）
+// 未声明Content-Encoding时保留GZIP魔数嗅探，兼容未正确声明编码的服务器
+// limit>=0时解码结果超过limit字节立即返回ErrBodyTooLarge，防止压缩炸弹耗尽内存
+func decodeResponseBody(header http.Header, body []byte, limit int64) ([]byte, error) {
+	contentEncoding := header.Get("Content-Encoding")
+	if contentEncoding == "" {
+		return ungzipBytes(body, limit)
+	}
+	// HEAD请求、204/304等无响应体的情况
+	if len(body) == 0 {
+		return body, nil
+	}
+	decoded, err := decodeContent(body, strings.Join(header.Values("Content-Encoding"), ","), limit)
+	if err != nil {
+		return nil, err
+	}
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+	return decoded, nil
+}
+
+// decodeGzip 解码gzip数据，支持多成员（multi-member）格式
+func decodeGzip(data []byte, limit int64) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0x1F || data[1] != 0x8B {
+		return nil, fmt.Errorf("gzip: 魔数不匹配")
+	}
+	// ungzipBytes使用的gzip.Reader默认开启multistream，会连续解出所有成员
+	return ungzipBytes(data, limit)
+}
+
+// decodeDeflate 解码deflate数据
+// RFC规定deflate应为zlib格式，但不少服务器直接发送raw deflate，这里按zlib头自动识别
+func decodeDeflate(data []byte, limit int64) ([]byte, error) {
+	var reader io.ReadCloser
+	if isZlibHeader(data) {
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		reader = zr
+	} else {
+		reader = flate.NewReader(bytes.NewReader(data))
+	}
+	defer reader.Close()
+	return readAllLimit(reader, limit)
+}
+
+// readAllLimit 读取解码流，limit>=0时最多读取limit+1字节，超过limit返回ErrBodyTooLarge
+func readAllLimit(r io.Reader, limit int64) ([]byte, error) {
+	if limit < 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: 解码后超过 %d 字节", ErrBodyTooLarge, limit)
+	}
+	return data, nil
+}
+
+// isZlibHeader 判断是否为zlib头：压缩方法为8、窗口不超过32K、头部校验能被31整除
+func isZlibHeader(data []byte) bool {
+	if len(data) < 2 {
+		return false
+	}
+	cmf, flg := data[0], data[1]
+	return cmf&0x0F == 8 && cmf>>4 <= 7 && (uint16(cmf)<<8|uint16(flg))%31 == 0
+}
@@ -0,0 +1,357 @@
+package gather
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// encodingTestText 编码测试用的原文（br/zstd测试数据由官方压缩工具对该文本压缩生成）
+func encodingTestText() []byte {
+	var sb strings.Builder
+	sb.WriteString("<html><head><title>The gather test page</title></head><body>")
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&sb, "<p>The quick brown fox jumps over the lazy dog %d.</p>", i)
+	}
+	sb.WriteString("<p>中文内容</p></body></html>")
+	return []byte(sb.String())
+}
+
+// brotli -q 11（文本模式，会引用静态字典）
+var encodingTestBrotli = mustHex("1b8a04201c056eec0695f984029fadf2fb1d927ea0eaae2d194d087a03064ce458120cb4edf80a7b43901d588c53e80f" +
+	"38c8222a90420f00d21dfabbd36612eb49ed134d1b8bc43d5c684e2f6b6baa4ccb15c14a3f12b33c14c75235eb8b2ef4" +
+	"0d8d1f1dfe43dea004f2384e9cb9e6865beeb8e781c70b3415e7293c7bed8db7de79ef43896972698b91dd2d02")
+
+// zstd -19（含内容校验）
+var encodingTestZstd = mustHex("28b52ffd648b038d050052481f2090cf01fcff099372f65094c7371ac8029b208b4c70b88bc41c37cc621963d73998aa" +
+	"fab61635d5505447722dcf544990c3288841109404398c821884cb5e2078883bff81997abdfb679ae3af5dd526bdc79f" +
+	"b73a9e313f35d1865d177f0e67ecb55743f9faaee9fe99de3d43aff78235d5a37eb193f5be98aafabe0119a810d8caa8" +
+	"513f077023391d10222490f8ff7fe4034ba4c0385d52b428e5fd2c8814192933bf6c7849cf6ead6cf9a253e6109579")
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// 使用标准库生成gzip/zlib/raw deflate测试数据
+func gzipBytes(data []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+func zlibBytes(data []byte) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+func flateBytes(data []byte) []byte {
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.BestCompression)
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+// TestDecodeContent 测试各内容编码及多成员、多重编码的解码
+func TestDecodeContent(t *testing.T) {
+	text := encodingTestText()
+	half := len(text) / 2
+	testCases := []struct {
+		name     string
+		encoding string
+		data     []byte
+		want     []byte
+	}{
+		{"gzip", "gzip", gzipBytes(text), text},
+		{"x-gzip别名", "x-gzip", gzipBytes(text), text},
+		{"gzip多成员", "gzip", append(gzipBytes(text[:half]), gzipBytes(text[half:])...), text},
+		{"deflate(zlib)", "deflate", zlibBytes(text), text},
+		{"deflate(raw)", "deflate", flateBytes(text), text},
+		{"brotli", "br", encodingTestBrotli, text},
+		{"zstd", "zstd", encodingTestZstd, text},
+		{"zstd多帧", "zstd", append(append([]byte(nil), encodingTestZstd...), encodingTestZstd...), append(append([]byte(nil), text...), text...)},
+		{"多重编码", "deflate, gzip", gzipBytes(zlibBytes(text)), text},
+		{"identity", "identity", text, text},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := DecodeContent(tc.data, tc.encoding)
+			if err != nil {
+				t.Fatalf("解码失败：%v", err)
+			}
+			if !bytes.Equal(got, tc.want) {
+				t.Errorf("解码结果不符：期望%d字节，实际%d字节", len(tc.want), len(got))
+			}
+		})
+	}
+
+	if _, err := DecodeContent(text, "sdch"); err == nil {
+		t.Error("未注册的编码应返回错误")
+	}
+	if _, err := DecodeContent(encodingTestBrotli[:40], "br"); err == nil {
+		t.Error("截断的brotli数据应返回错误")
+	}
+	if _, err := DecodeContent(encodingTestZstd[:40], "zstd"); err == nil {
+		t.Error("截断的zstd数据应返回错误")
+	}
+}
+
+// encodingVectors 由参考实现生成的br/zstd一致性测试数据（testdata/encoding）
+// br由libbrotlienc 1.0.9生成，zstd由zstd 1.5.6命令行生成；原文为确定性生成的文本/随机数据，这里只记录长度和SHA-256
+// 覆盖：各压缩级别、多元块/多块、未压缩元块/Raw块、RLE块、npostfix/ndirect距离参数、
+// 最小窗口（lgwin=10/wlog=10）、超过128KB的远距离引用、空数据、无内容长度的帧、多帧及可跳过帧、内容校验
+var encodingVectors = []struct {
+	file   string
+	size   int
+	sha256 string
+}{
+	{"text-q0.br", 65536, "406b1b1d53d6ef0c0beb4ca5db22a0f664229907e66aaad874e83ac415cd6b00"},
+	{"text-q1.br", 65536, "406b1b1d53d6ef0c0beb4ca5db22a0f664229907e66aaad874e83ac415cd6b00"},
+	{"text-q5-lgblock16.br", 179017, "aa5148351c445da9f9b22efdff502d60469db7488a353461bd65f8e484875582"},
+	{"text-q9-postfix.br", 179017, "aa5148351c445da9f9b22efdff502d60469db7488a353461bd65f8e484875582"},
+	{"text-q11-lgwin24.br", 179017, "aa5148351c445da9f9b22efdff502d60469db7488a353461bd65f8e484875582"},
+	{"noise.br", 71680, "384b3657e2c97a0c95246f9c9d6d623f104c884288d3cc88cefd878bf1f256ee"},
+	{"zeros.br", 307200, "7818f5542a0404157573be6cffc0e0c8e68ce3c0f5d17d07ccdd9313fb700baf"},
+	{"far-lgwin18.br", 245760, "b7eb4826ec58e9a99b45a32233eb538635e02e52d927b960676af50ba2a37da0"},
+	{"near-lgwin10.br", 60000, "98a4f9ba0091e5390c9f7cee5056eca9a595284feb7c7406fcb71d4e6f8caede"},
+	{"empty.br", 0, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+	{"text-1.zst", 179017, "aa5148351c445da9f9b22efdff502d60469db7488a353461bd65f8e484875582"},
+	{"text-19-check.zst", 179017, "aa5148351c445da9f9b22efdff502d60469db7488a353461bd65f8e484875582"},
+	{"text-nosize.zst", 179017, "aa5148351c445da9f9b22efdff502d60469db7488a353461bd65f8e484875582"},
+	{"noise.zst", 71680, "384b3657e2c97a0c95246f9c9d6d623f104c884288d3cc88cefd878bf1f256ee"},
+	{"zeros.zst", 307200, "7818f5542a0404157573be6cffc0e0c8e68ce3c0f5d17d07ccdd9313fb700baf"},
+	{"far-wlog18.zst", 245760, "b7eb4826ec58e9a99b45a32233eb538635e02e52d927b960676af50ba2a37da0"},
+	{"near-wlog10.zst", 60000, "98a4f9ba0091e5390c9f7cee5056eca9a595284feb7c7406fcb71d4e6f8caede"},
+	{"empty.zst", 0, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+	{"text-frames.zst", 179017, "aa5148351c445da9f9b22efdff502d60469db7488a353461bd65f8e484875582"},
+}
+
+// readEncodingVector 读取一致性测试数据及对应的Content-Encoding
+func readEncodingVector(t testing.TB, file string) ([]byte, string) {
+	data, err := os.ReadFile(filepath.Join("testdata", "encoding", file))
+	if err != nil {
+		t.Fatalf("读取测试数据失败：%v", err)
+	}
+	if strings.HasSuffix(file, ".br") {
+		return data, EncodingBrotli
+	}
+	return data, EncodingZstd
+}
+
+// TestDecodeContent_Conformance 测试br/zstd解码结果与参考实现一致
+func TestDecodeContent_Conformance(t *testing.T) {
+	for _, v := range encodingVectors {
+		t.Run(v.file, func(t *testing.T) {
+			data, encoding := readEncodingVector(t, v.file)
+			got, err := DecodeContent(data, encoding)
+			if err != nil {
+				t.Fatalf("解码失败：%v", err)
+			}
+			sum := sha256.Sum256(got)
+			if len(got) != v.size || hex.EncodeToString(sum[:]) != v.sha256 {
+				t.Errorf("解码结果与参考实现不一致：%d字节，sha256=%x", len(got), sum)
+			}
+		})
+	}
+
+	// 使用外部字典的zstd帧不支持，应返回错误
+	data, _ := readEncodingVector(t, "text-dict.zst")
+	if _, err := ZstdDecode(data); err == nil || !strings.Contains(err.Error(), "字典") {
+		t.Errorf("使用字典的帧应返回错误：%v", err)
+	}
+}
+
+// TestDecodeContent_Corrupt 测试截断、损坏的br/zstd数据返回错误而不会panic
+func TestDecodeContent_Corrupt(t *testing.T) {
+	for _, v := range encodingVectors {
+		data, encoding := readEncodingVector(t, v.file)
+		for i := 1; i < 8; i++ {
+			n := len(data) * i / 8
+			if _, err := DecodeContent(data[:n], encoding); err == nil && n < len(data) {
+				t.Errorf("%s截断到%d字节应返回错误", v.file, n)
+			}
+		}
+		// 逐个位置翻转字节：不能panic，带内容校验的zstd必须返回错误
+		for pos := 0; pos < len(data); pos += 1 + len(data)/200 {
+			corrupt := append([]byte(nil), data...)
+			corrupt[pos] ^= 0x5A
+			got, err := DecodeContent(corrupt, encoding)
+			if err == nil && v.file == "text-19-check.zst" && len(got) == v.size {
+				t.Errorf("%s第%d字节损坏后应校验失败", v.file, pos)
+			}
+		}
+	}
+}
+
+// TestDecodeContent_Limit 测试解码时限制输出大小，压缩炸弹在解出全部数据前即返回ErrBodyTooLarge
+func TestDecodeContent_Limit(t *testing.T) {
+	for _, file := range []string{"zeros.br", "zeros.zst", "text-q11-lgwin24.br", "text-frames.zst"} {
+		data, encoding := readEncodingVector(t, file)
+		decode := zstdDecode
+		if encoding == EncodingBrotli {
+			decode = brotliDecode
+		}
+		if _, err := decode(data, 64<<10); !errors.Is(err, ErrBodyTooLarge) {
+			t.Errorf("%s超过上限应返回ErrBodyTooLarge：%v", file, err)
+		}
+		if _, err := decode(data, 1<<20); err != nil {
+			t.Errorf("%s未超过上限不应返回错误：%v", file, err)
+		}
+	}
+
+	// gzip/deflate及自定义解码器
+	text := bytes.Repeat([]byte("a"), 100000)
+	RegisterDecoder("x-repeat", func(data []byte) ([]byte, error) { return bytes.Repeat(data, 1000), nil })
+	defer RegisterDecoder("x-repeat", nil)
+	for encoding, data := range map[string][]byte{"gzip": gzipBytes(text), "deflate": zlibBytes(text), "x-repeat": text[:100]} {
+		if _, err := decodeContent(data, encoding, 1000); !errors.Is(err, ErrBodyTooLarge) {
+			t.Errorf("%s超过上限应返回ErrBodyTooLarge：%v", encoding, err)
+		}
+		if got, err := decodeContent(data, encoding, int64(len(text))); err != nil || len(got) != len(text) {
+			t.Errorf("%s恰好等于上限时应正常解码：%d, %v", encoding, len(got), err)
+		}
+	}
+}
+
+// fuzzDecodeLimit 模糊测试时解码结果的大小上限
+const fuzzDecodeLimit = 1 << 20
+
+// fuzzDecodeSeeds 为模糊测试添加一致性测试数据作为种子
+func fuzzDecodeSeeds(f *testing.F, suffix string, extra ...[]byte) {
+	for _, v := range encodingVectors {
+		if strings.HasSuffix(v.file, suffix) {
+			data, _ := readEncodingVector(f, v.file)
+			f.Add(data)
+		}
+	}
+	for _, data := range extra {
+		f.Add(data)
+	}
+}
+
+// FuzzDecodeBrotli 模糊测试brotli解码：任意输入不能panic，输出不能超过大小上限
+func FuzzDecodeBrotli(f *testing.F) {
+	fuzzDecodeSeeds(f, ".br", encodingTestBrotli)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		out, err := brotliDecode(data, fuzzDecodeLimit)
+		if len(out) > fuzzDecodeLimit || (err != nil && out != nil) {
+			t.Errorf("输出超过上限或出错时仍返回数据：%d字节, %v", len(out), err)
+		}
+	})
+}
+
+// FuzzDecodeZstd 模糊测试zstd解码：任意输入不能panic，输出不能超过大小上限
+func FuzzDecodeZstd(f *testing.F) {
+	fuzzDecodeSeeds(f, ".zst", encodingTestZstd)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		out, err := zstdDecode(data, fuzzDecodeLimit)
+		if len(out) > fuzzDecodeLimit || (err != nil && out != nil) {
+			t.Errorf("输出超过上限或出错时仍返回数据：%d字节, %v", len(out), err)
+		}
+	})
+}
+
+// TestRegisterDecoder 测试注册自定义解码器后Accept-Encoding同步更新
+func TestRegisterDecoder(t *testing.T) {
+	if got := AcceptEncoding(); got != "gzip, deflate" {
+		t.Errorf("默认Accept-Encoding错误：%s", got)
+	}
+	SetAcceptEncoding("gzip", "BR", "sdch", "br")
+	if got := AcceptEncoding(); got != "gzip, br" {
+		t.Errorf("应只声明已注册的编码且不重复，实际：%s", got)
+	}
+	SetAcceptEncoding()
+	if got := AcceptEncoding(); got != "gzip, deflate" {
+		t.Errorf("不传参数应恢复默认值，实际：%s", got)
+	}
+	if got := filterAcceptEncoding("gzip, deflate, sdch"); got != "gzip, deflate" {
+		t.Errorf("应过滤无法解码的编码，实际：%s", got)
+	}
+
+	RegisterDecoder("X-Upper", func(data []byte) ([]byte, error) {
+		return bytes.ToUpper(data), nil
+	})
+	defer RegisterDecoder("x-upper", nil)
+	if got := AcceptEncoding(); !strings.HasSuffix(got, ", x-upper") {
+		t.Errorf("注册后Accept-Encoding应包含x-upper，实际：%s", got)
+	}
+	got, err := DecodeContent(gzipBytes([]byte("abc")), "x-upper, gzip")
+	if err != nil || string(got) != "ABC" {
+		t.Errorf("自定义解码器结果错误：%q, %v", got, err)
+	}
+
+	RegisterDecoder("x-upper", nil)
+	if strings.Contains(AcceptEncoding(), "x-upper") {
+		t.Error("移除后Accept-Encoding不应包含x-upper")
+	}
+}
+
+// TestGather_ContentEncoding 测试采集时按Content-Encoding自动解码
+func TestGather_ContentEncoding(t *testing.T) {
+	text := encodingTestText()
+	var gotAcceptEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAcceptEncoding = r.Header.Get("Accept-Encoding")
+		var body []byte
+		switch enc := r.URL.Query().Get("enc"); enc {
+		case "br":
+			body = encodingTestBrotli
+		case "zstd":
+			body = encodingTestZstd
+		case "deflate":
+			body = flateBytes(text)
+		default:
+			body = gzipBytes(text)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Encoding", r.URL.Query().Get("enc"))
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	ga := NewGather("chrome", false)
+	for _, enc := range []string{"gzip", "deflate", "br", "zstd"} {
+		resp, err := ga.GetResponse(server.URL+"/?enc="+enc, "", "")
+		if err != nil {
+			t.Fatalf("%s请求失败：%v", enc, err)
+		}
+		if resp.Text() != string(text) {
+			t.Errorf("%s响应未正确解码：%q", enc, resp.Text())
+		}
+		if resp.Header.Get("Content-Encoding") != "" {
+			t.Errorf("%s解码后应移除Content-Encoding头", enc)
+		}
+	}
+	if gotAcceptEncoding != AcceptEncoding() {
+		t.Errorf("请求的Accept-Encoding应与可解码集合一致，实际：%s", gotAcceptEncoding)
+	}
+
+	// 自定义请求头中的sdch会被过滤
+	gu := NewGatherUtil(map[string]string{"User-Agent": "test", "Accept-Encoding": "gzip, deflate, sdch"}, "", 30, false)
+	if html, _, err := gu.Get(server.URL+"/?enc=gzip", ""); err != nil || html != string(text) {
+		t.Errorf("自定义请求头请求失败：%v", err)
+	}
+	if gotAcceptEncoding != "gzip, deflate" {
+		t.Errorf("Accept-Encoding应过滤sdch，实际：%s", gotAcceptEncoding)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 哨兵错误：可通过errors.Is判断错误类别，GatherStruct与Pool的所有方法行为一致
+// 网络错误会同时保留原始错误，例如：
+//
+//	_, _, err := ga.Get(URL, "")
+//	if errors.Is(err, gather.ErrTimeout) { ... } // 按类别判断
+//	var dnsErr *net.DNSError
+//	if errors.As(err, &dnsErr) { ... } // 仍可取出标准库原始错误
+var (
+	// ErrTimeout 连接/响应头/总超时，或ctx截止时间已到
+	ErrTimeout = errors.New("请求超时")
+	// ErrDNS 域名无法解析
+	ErrDNS = errors.New("DNS解析失败")
+	// ErrTLS 证书校验失败、协议不匹配等TLS握手错误
+	ErrTLS = errors.New("TLS握手失败")
+	// ErrProxy 无法连接代理，或代理拒绝CONNECT请求
+	ErrProxy = errors.New("代理连接失败")
+	// ErrPoolExhausted Pool在等待时间内未获取到空闲实例（沿用旧版本错误文本）
+	ErrPoolExhausted = errors.New("time out,no free client find")
+	// ErrBodyTooLarge 响应体超过SetMaxBodySize设置的上限
+	ErrBodyTooLarge = errors.New("响应体超过大小限制")
+)
+
+// errNoFreeClinetFind 获取池实例超时（保留旧名称，等同于ErrPoolExhausted）
+// 触发场景：并发数超过池大小，且重试超时仍未获取到空闲实例
+var errNoFreeClinetFind = ErrPoolExhausted
+
+// statusErrorBodyLimit StatusError中保留的响应体最大字节数
+const statusErrorBodyLimit = 4096
+
+// StatusError 非2xx状态码错误，可通过errors.As取出状态码、响应头和响应体
+// 示例：
+//
+//	var se *gather.StatusError
+//	if errors.As(err, &se) && se.StatusCode == 429 {
+//	    time.Sleep(se.RetryAfter)
+//	}
+type StatusError struct {
+	StatusCode int           // HTTP状态码
+	Status     string        // 状态行文本（如"404 Not Found"）
+	URL        string        // 最终请求的URL
+	Header     http.Header   // 响应头
+	Body       []byte        // 响应体（已解码，超过4KB时截断）
+	RetryAfter time.Duration // Retry-After响应头换算的等待时长，未设置时为0
+}
+
+// Error 保持与旧版本一致的错误文本
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("http状态码:%d", e.StatusCode)
+}
+
+// newStatusError 根据响应组装StatusError
+func newStatusError(resp *Response) *StatusError {
+	body := resp.Body
+	if len(body) > statusErrorBodyLimit {
+		body = body[:statusErrorBodyLimit]
+	}
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		URL:        resp.FinalURL,
+		Header:     resp.Header,
+		Body:       append([]byte(nil), body...),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseRetryAfter 解析Retry-After响应头，支持秒数和HTTP日期两种格式
+// 无法解析或已过期时返回0
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// classifyError 把标准库的网络错误归类为哨兵错误，同时保留原始错误链
+// 调用方主动取消（context.Canceled）不做归类，原样返回
+func classifyError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+	switch {
+	case isProxyError(err):
+		return fmt.Errorf("%w: %w", ErrProxy, err)
+	case isDNSError(err):
+		return fmt.Errorf("%w: %w", ErrDNS, err)
+	case isTLSError(err):
+		return fmt.Errorf("%w: %w", ErrTLS, err)
+	case isTimeoutError(err):
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return err
+}
+
+// proxyConnectError 代理对CONNECT请求返回了非200状态码，由newTransport安装的OnProxyConnectResponse返回
+type proxyConnectError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *proxyConnectError) Error() string {
+	return "代理拒绝CONNECT请求: " + e.Status
+}
+
+// checkProxyConnect 检查代理对CONNECT请求的响应状态
+// 标准库对非200响应只返回状态文本，这里提前返回带状态码的错误，便于isProxyError识别
+func checkProxyConnect(_ context.Context, _ *url.URL, _ *http.Request, resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		return &proxyConnectError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return nil
+}
+
+// isProxyError 判断是否为代理错误
+// 1. 连接代理失败：标准库以Op为"proxyconnect"的net.OpError包装
+// 2. 代理拒绝CONNECT：checkProxyConnect返回的proxyConnectError
+func isProxyError(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "proxyconnect" {
+		return true
+	}
+	var connectErr *proxyConnectError
+	return errors.As(err, &connectErr)
+}
+
+// isDNSError 判断是否为DNS解析错误
+func isDNSError(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+// isTLSError 判断是否为TLS握手/证书错误
+func isTLSError(err error) bool {
+	var (
+		recordErr    tls.RecordHeaderError
+		alertErr     tls.AlertError
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	if errors.As(err, &recordErr) || errors.As(err, &alertErr) || errors.As(err, &verifyErr) ||
+		errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+		return true
+	}
+	// 部分握手错误只有文本（如"tls: handshake failure"，以及标准库把非TLS应答转换成的文本错误）
+	msg := err.Error()
+	return strings.Contains(msg, "tls: ") || strings.Contains(msg, "server gave HTTP response to HTTPS client")
+}
+
+// isTimeoutError 判断是否为超时错误
+func isTimeoutError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// SetMaxBodySize 设置响应体最大字节数（读取时检查原始数据，解码时边解码边检查），超过时返回ErrBodyTooLarge；<=0表示不限制（默认）
+func (g *GatherStruct) SetMaxBodySize(n int64) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	g.maxBodySize = n
+}
+
+// SetMaxBodySize 为池内所有实例设置响应体最大字节数
+func (p *Pool) SetMaxBodySize(n int64) {
+	for _, ga := range p.pool {
+		ga.SetMaxBodySize(n)
+	}
+}
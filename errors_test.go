@@ -0,0 +1,170 @@
+package gather
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStatusError 测试非2xx状态码返回*StatusError
+func TestStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(strings.Repeat("x", statusErrorBodyLimit+100)))
+	}))
+	defer server.Close()
+
+	ga := NewGather("chrome", false)
+	_, _, err := ga.Get(server.URL, "")
+	var se *StatusError
+	if !errors.As(err, &se) {
+		t.Fatalf("应返回*StatusError，实际：%v", err)
+	}
+	if se.StatusCode != http.StatusTooManyRequests || se.RetryAfter != 120*time.Second {
+		t.Errorf("StatusError字段错误：%+v", se)
+	}
+	if len(se.Body) != statusErrorBodyLimit {
+		t.Errorf("StatusError响应体应截断为%d字节，实际%d", statusErrorBodyLimit, len(se.Body))
+	}
+	if err.Error() != "http状态码:429" {
+		t.Errorf("错误文本应与旧版本一致，实际：%s", err.Error())
+	}
+
+	// 接口在422中返回的JSON错误详情可以从StatusError中读取
+	_, err = ga.GetResponse(testBaseURL+"/error_json", "", "")
+	if !errors.As(err, &se) || !strings.Contains(string(se.Body), "invalid param") {
+		t.Errorf("StatusError应携带响应体，实际：%v", err)
+	}
+
+	// Pool同样返回*StatusError
+	pool := NewGatherUtilPool(map[string]string{"User-Agent": "test"}, "", 30, false, 2)
+	if _, _, err := pool.Get(testBaseURL+"/404", ""); !errors.As(err, &se) || se.StatusCode != http.StatusNotFound {
+		t.Errorf("Pool应返回404的StatusError，实际：%v", err)
+	}
+}
+
+// TestParseRetryAfter 测试Retry-After的两种格式
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"-1", 0},
+		{"Mon, 01 Jan 2024 00:01:00 GMT", time.Minute},
+		{"Sun, 31 Dec 2023 00:00:00 GMT", 0},
+		{"abc", 0},
+	}
+	for _, tc := range testCases {
+		if got := parseRetryAfter(tc.value, now); got != tc.want {
+			t.Errorf("parseRetryAfter(%q)=%v，期望%v", tc.value, got, tc.want)
+		}
+	}
+}
+
+// TestErrorClasses 测试网络错误归类为哨兵错误
+func TestErrorClasses(t *testing.T) {
+	ga := NewGather("chrome", false)
+
+	// 超时：同时满足ErrTimeout与context.DeadlineExceeded
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, _, err := ga.GetContext(ctx, testBaseURL+"/timeout", "")
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("超时应归类为ErrTimeout，实际：%v", err)
+	}
+
+	// 主动取消不归类为超时
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	cancel2()
+	if _, _, err := ga.GetContext(ctx2, testBaseURL+"/get", ""); errors.Is(err, ErrTimeout) || !errors.Is(err, context.Canceled) {
+		t.Errorf("主动取消应返回context.Canceled，实际：%v", err)
+	}
+
+	// TLS：用https访问明文HTTP服务
+	httpsURL := "https://" + strings.TrimPrefix(testBaseURL, "http://") + "/get"
+	if _, _, err := ga.Get(httpsURL, ""); !errors.Is(err, ErrTLS) {
+		t.Errorf("TLS错误应归类为ErrTLS，实际：%v", err)
+	}
+
+	// 代理：代理端口无法连接
+	closed := httptest.NewServer(http.NotFoundHandler())
+	proxyURL := closed.URL
+	closed.Close()
+	gp := NewGatherProxy("chrome", proxyURL, false)
+	if _, _, err := gp.Get(testBaseURL+"/get", ""); !errors.Is(err, ErrProxy) {
+		t.Errorf("代理错误应归类为ErrProxy，实际：%v", err)
+	}
+
+	// 代理：代理拒绝CONNECT请求
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusProxyAuthRequired)
+	}))
+	defer rejecting.Close()
+	gp = NewGatherProxy("chrome", rejecting.URL, false)
+	var connectErr *proxyConnectError
+	if _, _, err := gp.Get("https://example.com/", ""); !errors.Is(err, ErrProxy) || !errors.As(err, &connectErr) || connectErr.StatusCode != http.StatusProxyAuthRequired {
+		t.Errorf("代理拒绝CONNECT应归类为ErrProxy，实际：%v", err)
+	}
+	// 经代理访问的目标站点返回4xx不是代理错误
+	if _, _, err := gp.Get("http://example.com/", ""); errors.Is(err, ErrProxy) {
+		t.Errorf("目标站点的状态码错误不应归类为ErrProxy，实际：%v", err)
+	}
+
+	// DNS：.invalid为保留的不可解析顶级域
+	if _, _, err := ga.Get("http://gather-test.invalid/", ""); !errors.Is(err, ErrDNS) {
+		t.Errorf("DNS错误应归类为ErrDNS，实际：%v", err)
+	}
+}
+
+// TestMaxBodySize 测试响应体大小上限
+func TestMaxBodySize(t *testing.T) {
+	ga := NewGather("chrome", false)
+	ga.SetMaxBodySize(10)
+	if _, _, err := ga.Get(testBaseURL+"/get", ""); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("超出上限应返回ErrBodyTooLarge，实际：%v", err)
+	}
+
+	// 压缩数据本身未超限，但解码后超限（含br/zstd压缩炸弹、多重编码及未声明编码的gzip）
+	zerosBrotli, _ := os.ReadFile(filepath.Join("testdata", "encoding", "zeros.br"))
+	zerosZstd, _ := os.ReadFile(filepath.Join("testdata", "encoding", "zeros.zst"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("enc") {
+		case "br":
+			w.Header().Set("Content-Encoding", "br")
+			w.Write(zerosBrotli)
+		case "zstd":
+			w.Header().Set("Content-Encoding", "zstd")
+			w.Write(zerosZstd)
+		case "multi":
+			w.Header().Set("Content-Encoding", "gzip, deflate")
+			w.Write(zlibBytes(gzipBytes([]byte(strings.Repeat("a", 10000)))))
+		case "sniff":
+			w.Write(gzipBytes([]byte(strings.Repeat("a", 10000))))
+		default:
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipBytes([]byte(strings.Repeat("a", 10000))))
+		}
+	}))
+	defer server.Close()
+	ga.SetMaxBodySize(1000)
+	for _, enc := range []string{"gzip", "br", "zstd", "multi", "sniff"} {
+		if _, _, err := ga.Get(server.URL+"/?enc="+enc, ""); !errors.Is(err, ErrBodyTooLarge) {
+			t.Errorf("%s解码后超出上限应返回ErrBodyTooLarge，实际：%v", enc, err)
+		}
+	}
+
+	ga.SetMaxBodySize(0)
+	if _, _, err := ga.Get(server.URL, ""); err != nil {
+		t.Errorf("不限制时应正常返回，实际：%v", err)
+	}
+}
@@ -6,6 +6,8 @@
 // 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
 package gather
 
+import "context"
+
 // Get 基于GET方法采集数据（自动复用实例内置Cookie）
 // 功能：
 //  1. 自动继承实例先前的Cookie（无需手动传入）
@@ -69,11 +71,60 @@ func (g *GatherStruct) Get(URL, refererURL string) (html, redirectURL string, er
 //	    return
 //	}
 func (g *GatherStruct) GetUtil(URL, refererURL, cookies string) (html, redirectURL string, err error) {
+	return g.GetUtilContext(context.Background(), URL, refererURL, cookies)
+}
+
+// GetContext 同Get，支持通过ctx取消进行中的请求（如关闭采集任务、HTTP处理函数返回）
+func (g *GatherStruct) GetContext(ctx context.Context, URL, refererURL string) (html, redirectURL string, err error) {
+	return g.GetUtilContext(ctx, URL, refererURL, "")
+}
+
+// GetUtilContext 同GetUtil，支持通过ctx取消进行中的请求
+// ctx取消或超时后立即返回ctx相关错误（可通过errors.Is(err, context.Canceled)判断）
+func (g *GatherStruct) GetUtilContext(ctx context.Context, URL, refererURL, cookies string) (html, redirectURL string, err error) {
 	g.locker.Lock()
 	defer g.locker.Unlock()
-	req, err := g.newHttpRequest("GET", URL, refererURL, cookies, nil)
+	req, err := g.newHttpRequest(ctx, "GET", URL, refererURL, cookies, nil)
 	if err != nil {
 		return "", "", err
 	}
 	return g.request(req)
 }
+
+// GetResponse 基于GET方法采集数据，返回完整的Response对象
+// 功能：与GetUtil一致，但额外提供状态码、响应头、原始字节和跳转链路
+//
+// 参数：
+//
+//	URL: 待采集的目标URL（必填）
+//	refererURL: 来源页URL（可选，空值不设置Referer）
+//	cookies: 手动指定的Cookie字符串（可选，留空则继承实例内置Cookie）
+//
+// 返回值：
+//
+//	resp: 完整响应对象（非2xx状态码时与err一同返回，便于读取错误详情）
+//	err: 错误信息（URL无效、网络异常、非2xx状态码等）
+//
+// 示例：
+//
+//	ga := NewGather("chrome", false)
+//	resp, err := ga.GetResponse("https://www.baidu.com/", "", "")
+//	if err != nil {
+//	    log.Printf("GET请求失败: %v", err)
+//	    return
+//	}
+//	fmt.Println(resp.StatusCode, resp.Header.Get("Content-Type"), resp.FinalURL)
+func (g *GatherStruct) GetResponse(URL, refererURL, cookies string) (*Response, error) {
+	return g.GetResponseContext(context.Background(), URL, refererURL, cookies)
+}
+
+// GetResponseContext 同GetResponse，支持通过ctx取消进行中的请求
+func (g *GatherStruct) GetResponseContext(ctx context.Context, URL, refererURL, cookies string) (*Response, error) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	req, err := g.newHttpRequest(ctx, "GET", URL, refererURL, cookies, nil)
+	if err != nil {
+		return nil, err
+	}
+	return g.do(req)
+}
@@ -2,7 +2,9 @@
 package gather
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -71,6 +73,21 @@ func TestMain(m *testing.M) {
 		w.Write([]byte("404 Not Found"))
 	})
 
+	// /redirect：GET测试跳转链路，302到/get并下发Cookie
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "redirect_id", Value: "302", Path: "/"})
+		http.Redirect(w, r, "/get?from=redirect", http.StatusFound)
+	})
+
+	// /error_json：GET测试非2xx响应体（接口在422中返回JSON错误详情）
+	mux.HandleFunc("/error_json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": "invalid param",
+		})
+	})
+
 	// -------------------------- POST 测试接口 --------------------------
 	// /post：普通POST（表单/JSON/XML/二进制）测试
 	mux.HandleFunc("/post", func(w http.ResponseWriter, r *http.Request) {
@@ -473,3 +490,23 @@ func TestGather_EdgeCases(t *testing.T) {
 		t.Logf("代理请求跳转URL：%s", redirectURL)
 	})
 }
+
+// TestGather_GetContext 测试ctx取消能立即中断进行中的请求
+func TestGather_GetContext(t *testing.T) {
+	ga := NewGather("chrome", false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	startTime := time.Now()
+	_, _, err := ga.GetContext(ctx, testBaseURL+"/timeout", "")
+	if err == nil {
+		t.Fatal("ctx超时后应返回错误，实际未返回")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("错误类型不符合预期：期望context.DeadlineExceeded，实际%v", err)
+	}
+	if elapsed := time.Since(startTime); elapsed > 2*time.Second {
+		t.Errorf("ctx取消后未及时返回，耗时%v", elapsed)
+	}
+}
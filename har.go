@@ -0,0 +1,468 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// defaultHARBodyLimit HAR中每个请求体/响应体最多记录的字节数
+const defaultHARBodyLimit = 1 << 20
+
+// HAR HAR 1.2格式的根对象（http://www.softwareishard.com/blog/har-12-spec/）
+// 写出的文件可直接导入Chrome/Firefox开发者工具的Network面板查看
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog HAR日志
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator 生成HAR的工具信息
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry 一次请求（每一跳跳转各为一条）
+type HAREntry struct {
+	StartedDateTime string      `json:"startedDateTime"` // ISO 8601格式的开始时间
+	Time            float64     `json:"time"`            // 总耗时（毫秒）
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Connection      string      `json:"connection,omitempty"`
+
+	started time.Time // 用于排序
+}
+
+// HARRequest 请求信息
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARResponse 响应信息，请求失败时Status为0，Error为错误信息
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+	Error       string         `json:"_error,omitempty"`
+}
+
+// HARCookie Cookie信息
+type HARCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Path     string `json:"path,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+}
+
+// HARNameValue 请求头/响应头/查询参数
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData 请求体
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARContent 响应体（已按Content-Encoding解码，非文本内容为base64）
+type HARContent struct {
+	Size        int64  `json:"size"`
+	Compression int64  `json:"compression,omitempty"`
+	MimeType    string `json:"mimeType"`
+	Text        string `json:"text,omitempty"`
+	Encoding    string `json:"encoding,omitempty"`
+	Comment     string `json:"comment,omitempty"`
+}
+
+// HARTimings 各阶段耗时（毫秒），不适用的阶段为-1
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"` // 含TLS握手时间
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// HARRecorder HAR记录器，记录经过实例/Pool的所有请求（含跳转中间请求、失败请求），并发安全
+// 默认与日志一样脱敏：Cookie、Set-Cookie只保留名称和属性，Authorization、Proxy-Authorization只保留认证方案，
+// URL中的用户名密码替换为xxxxx，写出的文件可以直接附在问题报告中
+// 示例：
+//
+//	recorder := gather.NewHARRecorder(0)
+//	ga.SetHARRecorder(recorder) // 或 pool.SetHARRecorder(recorder)
+//	ga.Get(URL, "")
+//	recorder.WriteFile("debug.har") // 在浏览器开发者工具Network面板中导入查看
+type HARRecorder struct {
+	// IncludeSecrets 为true时按原样记录Cookie、认证头等敏感值（仅在确实需要时开启，需在开始记录前设置）
+	IncludeSecrets bool
+
+	mu          sync.Mutex
+	maxBodySize int64
+	entries     []HAREntry
+}
+
+// NewHARRecorder 创建HAR记录器，maxBodySize为每个请求体/响应体最多记录的字节数（<=0时默认1MB），超出部分截断
+func NewHARRecorder(maxBodySize int64) *HARRecorder {
+	if maxBodySize <= 0 {
+		maxBodySize = defaultHARBodyLimit
+	}
+	return &HARRecorder{maxBodySize: maxBodySize}
+}
+
+// SetHARRecorder 为实例设置HAR记录器（nil表示停止记录）
+func (g *GatherStruct) SetHARRecorder(recorder *HARRecorder) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	g.har = recorder
+}
+
+// SetHARRecorder 为池内所有实例设置同一个HAR记录器
+func (p *Pool) SetHARRecorder(recorder *HARRecorder) {
+	for _, ga := range p.pool {
+		ga.SetHARRecorder(recorder)
+	}
+}
+
+// HAR 返回当前已记录内容的快照（按开始时间排序）
+func (r *HARRecorder) HAR() *HAR {
+	r.mu.Lock()
+	entries := append([]HAREntry(nil), r.entries...)
+	r.mu.Unlock()
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].started.Before(entries[j].started) })
+	if entries == nil {
+		entries = []HAREntry{}
+	}
+	return &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "gather", Version: "1.0"},
+		Entries: entries,
+	}}
+}
+
+// Len 返回已记录的请求数
+func (r *HARRecorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// Reset 清空已记录的内容
+func (r *HARRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+// WriteTo 以JSON格式写出HAR
+func (r *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(r.HAR(), "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// WriteFile 把HAR写入文件（覆盖已有文件）
+func (r *HARRecorder) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := r.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// add 追加一条记录
+func (r *HARRecorder) add(entry HAREntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// wrap 返回记录流量的Transport
+func (r *HARRecorder) wrap(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &harTransport{base: base, recorder: r}
+}
+
+// harTransport 在Transport层记录每一跳请求，跳转的中间请求也会被单独记录
+type harTransport struct {
+	base     http.RoundTripper
+	recorder *HARRecorder
+}
+
+// RoundTrip 执行请求并记录，响应体读取完毕（或关闭）时写入记录
+func (t *harTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timing := newConnTrace()
+	traced := timing.withContext(req)
+	entry := HAREntry{
+		StartedDateTime: timing.start.Format(time.RFC3339Nano),
+		Request:         t.recordRequest(req),
+		started:         timing.start,
+	}
+
+	resp, err := t.base.RoundTrip(traced)
+	if err != nil {
+		entry.Response = HARResponse{
+			HTTPVersion: req.Proto,
+			Cookies:     []HARCookie{},
+			Headers:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+			Error:       err.Error(),
+		}
+		entry.Timings, entry.Time = timing.harTimings(time.Now())
+		entry.ServerIPAddress, entry.Connection = timing.remote()
+		t.recorder.add(entry)
+		return nil, err
+	}
+
+	entry.Response = HARResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     harCookies(resp.Cookies(), t.recorder.IncludeSecrets),
+		Headers:     harHeaders(resp.Header, t.recorder.IncludeSecrets),
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+	}
+	resp.Body = &harBody{
+		ReadCloser: resp.Body,
+		limit:      t.recorder.maxBodySize,
+		finish: func(raw []byte, size int64, truncated bool) {
+			entry.Response.BodySize = size
+			entry.Response.Content = harContent(resp.Header, raw, size, truncated, t.recorder.maxBodySize)
+			entry.Timings, entry.Time = timing.harTimings(time.Now())
+			entry.ServerIPAddress, entry.Connection = timing.remote()
+			t.recorder.add(entry)
+		},
+	}
+	return resp, nil
+}
+
+// recordRequest 记录请求头、Cookie、查询参数和请求体（通过GetBody读取副本，不影响实际发送）
+func (t *harTransport) recordRequest(req *http.Request) HARRequest {
+	record := HARRequest{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: req.Proto,
+		Cookies:     harCookies(req.Cookies(), t.recorder.IncludeSecrets),
+		Headers:     harHeaders(req.Header, t.recorder.IncludeSecrets),
+		QueryString: []HARNameValue{},
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+	if !t.recorder.IncludeSecrets {
+		record.URL = redactURL(req.URL)
+	}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			record.QueryString = append(record.QueryString, HARNameValue{Name: name, Value: value})
+		}
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return record
+	}
+	record.BodySize = req.ContentLength
+	postData := &HARPostData{MimeType: req.Header.Get("Content-Type")}
+	if req.GetBody == nil {
+		postData.Comment = "请求体不可重读，未记录"
+	} else if body, err := req.GetBody(); err == nil {
+		data, _ := io.ReadAll(io.LimitReader(body, t.recorder.maxBodySize+1))
+		body.Close()
+		if int64(len(data)) > t.recorder.maxBodySize {
+			data = data[:t.recorder.maxBodySize]
+			postData.Comment = "已截断"
+		}
+		postData.Text = string(data)
+	}
+	record.PostData = postData
+	return record
+}
+
+// harBody 读取响应体时保留前limit字节，读取完毕或关闭时回调finish（只回调一次）
+type harBody struct {
+	io.ReadCloser
+	limit     int64
+	buf       bytes.Buffer
+	size      int64
+	truncated bool
+	once      sync.Once
+	finish    func(raw []byte, size int64, truncated bool)
+}
+
+// Read 读取并保留响应体
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.size += int64(n)
+		if room := b.limit - int64(b.buf.Len()); room > 0 {
+			b.buf.Write(p[:min(int64(n), room)])
+		}
+		if b.size > b.limit {
+			b.truncated = true
+		}
+	}
+	if err == io.EOF {
+		b.done()
+	}
+	return n, err
+}
+
+// Close 关闭响应体并写入记录
+func (b *harBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	return err
+}
+
+// done 写入记录
+func (b *harBody) done() {
+	b.once.Do(func() { b.finish(b.buf.Bytes(), b.size, b.truncated) })
+}
+
+// harContent 组装响应体记录：按Content-Encoding解码后，文本直接记录，二进制以base64记录
+// 解码结果超过limit字节时记录原始数据
+func harContent(header http.Header, raw []byte, size int64, truncated bool, limit int64) HARContent {
+	content := HARContent{Size: size, MimeType: header.Get("Content-Type")}
+	data := raw
+	if encoding := header.Get("Content-Encoding"); encoding != "" && !truncated {
+		if decoded, err := decodeContent(raw, encoding, limit); err == nil {
+			data = decoded
+			content.Size = int64(len(decoded))
+			content.Compression = content.Size - size
+		}
+	}
+	if truncated {
+		content.Comment = "已截断"
+	}
+	if utf8.Valid(data) {
+		content.Text = string(data)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(data)
+		content.Encoding = "base64"
+	}
+	return content
+}
+
+// harHeaders 转换请求头/响应头，includeSecrets为false时敏感头按redactHeader脱敏
+func harHeaders(header http.Header, includeSecrets bool) []HARNameValue {
+	if !includeSecrets {
+		header = redactHeader(header)
+	}
+	values := []HARNameValue{}
+	for name, list := range header {
+		for _, value := range list {
+			values = append(values, HARNameValue{Name: name, Value: value})
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+	return values
+}
+
+// harCookies 转换Cookie，includeSecrets为false时只保留名称和属性
+func harCookies(cookies []*http.Cookie, includeSecrets bool) []HARCookie {
+	list := []HARCookie{}
+	for _, c := range cookies {
+		cookie := HARCookie{Name: c.Name, Value: c.Value, Path: c.Path, Domain: c.Domain, HTTPOnly: c.HttpOnly, Secure: c.Secure}
+		if !includeSecrets {
+			cookie.Value = redactedValue
+		}
+		if !c.Expires.IsZero() {
+			cookie.Expires = c.Expires.Format(time.RFC3339)
+		}
+		list = append(list, cookie)
+	}
+	return list
+}
+
+// harTimings 计算HAR各阶段耗时及总耗时（毫秒）
+func (t *connTrace) harTimings(end time.Time) (HARTimings, float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ms := func(from, to time.Time) float64 {
+		if from.IsZero() || to.IsZero() {
+			return -1
+		}
+		return float64(to.Sub(from)) / float64(time.Millisecond)
+	}
+	connectEnd := t.connectDone
+	if !t.tlsDone.IsZero() {
+		connectEnd = t.tlsDone
+	}
+	timings := HARTimings{
+		Blocked: -1,
+		DNS:     ms(t.dnsStart, t.dnsDone),
+		Connect: ms(t.connectStart, connectEnd),
+		SSL:     ms(t.tlsStart, t.tlsDone),
+		Send:    max(ms(t.gotConn, t.wroteRequest), 0),
+		Wait:    max(ms(t.wroteRequest, t.firstByte), 0),
+		Receive: max(ms(t.firstByte, end), 0),
+	}
+	return timings, float64(end.Sub(t.start)) / float64(time.Millisecond)
+}
+
+// remote 返回服务器地址和连接标识
+func (t *connTrace) remote() (ip, connection string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.remoteAddr == "" {
+		return "", ""
+	}
+	host, _, err := net.SplitHostPort(t.remoteAddr)
+	if err != nil {
+		host = t.remoteAddr
+	}
+	return host, t.remoteAddr
+}
@@ -0,0 +1,146 @@
+package gather
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestHARRecorder 测试跳转、请求体、响应体、Cookie的记录及写出文件
+func TestHARRecorder(t *testing.T) {
+	server := newRedirectTestServer()
+	defer server.Close()
+
+	recorder := NewHARRecorder(0)
+	ga := NewGather("chrome", false)
+	ga.SetHARRecorder(recorder)
+
+	if _, _, err := ga.Get(server.URL+"/hop?n=2", ""); err != nil {
+		t.Fatalf("请求失败：%v", err)
+	}
+	if _, _, err := ga.PostUtil(server.URL+"/final", "", "", map[string]string{"user": "ydg"}); err != nil {
+		t.Fatalf("请求失败：%v", err)
+	}
+	ga.Get("http://127.0.0.1:1/unreachable", "")
+
+	har := recorder.HAR()
+	entries := har.Log.Entries
+	if har.Log.Version != "1.2" || len(entries) != 5 {
+		t.Fatalf("应记录5个请求（含2跳跳转和1个失败请求），实际%d个", len(entries))
+	}
+	hop := entries[0]
+	if hop.Response.Status != 302 || hop.Response.RedirectURL != "/hop?n=1" || len(hop.Response.Cookies) != 1 || hop.Response.Cookies[0].Name != "hop2" {
+		t.Errorf("第一跳记录错误：%+v", hop.Response)
+	}
+	if len(entries[1].Request.Cookies) == 0 {
+		t.Errorf("第二跳应带上第一跳下发的Cookie：%+v", entries[1].Request.Cookies)
+	}
+	final := entries[2]
+	if final.Response.Status != 200 || !strings.HasPrefix(final.Response.Content.Text, "referer=") || final.Time <= 0 {
+		t.Errorf("最终响应记录错误：%+v", final.Response)
+	}
+	post := entries[3]
+	if post.Request.Method != "POST" || post.Request.PostData == nil || post.Request.PostData.Text != "user=ydg" {
+		t.Errorf("请求体记录错误：%+v", post.Request.PostData)
+	}
+	if failed := entries[4]; failed.Response.Status != 0 || failed.Response.Error == "" {
+		t.Errorf("失败请求应记录错误信息：%+v", failed.Response)
+	}
+
+	// 写出文件并能被解析
+	path := filepath.Join(t.TempDir(), "debug.har")
+	if err := recorder.WriteFile(path); err != nil {
+		t.Fatalf("写出HAR失败：%v", err)
+	}
+	data, _ := os.ReadFile(path)
+	var parsed HAR
+	if err := json.Unmarshal(data, &parsed); err != nil || len(parsed.Log.Entries) != 5 {
+		t.Errorf("HAR文件解析失败：%v", err)
+	}
+
+	recorder.Reset()
+	if recorder.Len() != 0 {
+		t.Error("Reset后应清空记录")
+	}
+}
+
+// TestHARRecorder_Pool 测试Pool共享记录器及响应体解压、截断
+func TestHARRecorder_Pool(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipBytes([]byte("hello")))
+			return
+		}
+		w.Write([]byte(strings.Repeat("a", 100)))
+	}))
+	defer server.Close()
+
+	recorder := NewHARRecorder(64)
+	pool := NewGatherUtilPool(map[string]string{"User-Agent": "test"}, "", 30, false, 2)
+	pool.SetHARRecorder(recorder)
+	for i := 0; i < 3; i++ {
+		if _, _, err := pool.Get(server.URL+"/gzip", ""); err != nil {
+			t.Fatalf("请求失败：%v", err)
+		}
+	}
+	pool.Get(server.URL+"/long", "")
+	entries := recorder.HAR().Log.Entries
+	if len(entries) != 4 {
+		t.Fatalf("Pool内所有实例应共享记录器，实际记录%d个", len(entries))
+	}
+	if content := entries[0].Response.Content; content.Text != "hello" || content.Size != 5 {
+		t.Errorf("压缩的响应体应解码后记录：%+v", content)
+	}
+	if content := entries[3].Response.Content; len(content.Text) != 64 || content.Size != 100 || content.Comment == "" {
+		t.Errorf("超过上限的响应体应截断：%+v", content)
+	}
+}
+
+// TestHARRecorder_Secrets 测试默认脱敏Cookie、Set-Cookie和认证头，IncludeSecrets时原样记录
+func TestHARRecorder_Secrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret-session-value", Path: "/"})
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	record := func(includeSecrets bool) string {
+		recorder := NewHARRecorder(0)
+		recorder.IncludeSecrets = includeSecrets
+		ga := NewGatherUtil(map[string]string{"User-Agent": "test", "Authorization": "Bearer secret-token"}, "", 30, false)
+		ga.SetHARRecorder(recorder)
+		ga.Get(server.URL, "")
+		ga.GetUtil(server.URL+"/again", "", "manual=secret-manual-cookie")
+		path := filepath.Join(t.TempDir(), "debug.har")
+		if err := recorder.WriteFile(path); err != nil {
+			t.Fatalf("写出HAR失败：%v", err)
+		}
+		data, _ := os.ReadFile(path)
+		return string(data)
+	}
+
+	secrets := []string{"secret-session-value", "secret-token", "secret-manual-cookie"}
+	out := record(false)
+	for _, secret := range secrets {
+		if strings.Contains(out, secret) {
+			t.Errorf("HAR文件中不应出现敏感值%q", secret)
+		}
+	}
+	for _, want := range []string{`"Bearer xxxxx"`, "session=xxxxx", "manual=xxxxx", `"name": "session"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HAR文件应保留名称并脱敏：缺少%q", want)
+		}
+	}
+
+	out = record(true)
+	for _, secret := range secrets {
+		if !strings.Contains(out, secret) {
+			t.Errorf("IncludeSecrets时应原样记录%q", secret)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// redactedValue 日志中替换敏感值的占位符（与url.URL.Redacted保持一致）
+const redactedValue = "xxxxx"
+
+// sensitiveHeaders 日志中需要脱敏的请求头/响应头（规范化名称）
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// SetLogger 设置实例的结构化日志（nil表示关闭），日志中的Cookie值、Authorization头和代理账号密码会自动脱敏
+// 事件及级别：
+//  1. Debug：使用的代理（设置日志时记录一次）、每次实际发出的请求（方法、URL、请求头、状态码、耗时、字节数）、命中缓存、Cookie变更
+//  2. Info：重试、被robots.txt禁止的请求
+//  3. Warn：请求失败、Pool获取实例失败
+//
+// 示例：
+//
+//	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+//	ga.SetLogger(logger.With("crawler", "news"))
+func (g *GatherStruct) SetLogger(logger *slog.Logger) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	g.logger = logger
+	if g.J != nil {
+		g.J.setLogger(logger)
+	}
+	g.logProxy()
+}
+
+// SetLogger 为池内所有实例设置结构化日志，同时记录获取实例失败事件
+func (p *Pool) SetLogger(logger *slog.Logger) {
+	for _, ga := range p.pool {
+		ga.SetLogger(logger)
+	}
+	p.locker.Lock()
+	defer p.locker.Unlock()
+	p.logger = logger
+}
+
+// logProxy 记录实例Transport使用的代理（账号密码脱敏），未使用代理时不记录，调用方需持有g.locker
+func (g *GatherStruct) logProxy() {
+	if g.logger == nil || g.Client == nil {
+		return
+	}
+	base := g.Client.Transport
+	if ct, ok := base.(*cassetteTransport); ok {
+		base = ct.base
+	}
+	transport, ok := base.(*http.Transport)
+	if !ok || transport.Proxy == nil {
+		return
+	}
+	// 实例的代理与目标地址无关，用任意请求取出代理地址
+	proxy, err := transport.Proxy(&http.Request{URL: &url.URL{Scheme: "http", Host: "example.com"}})
+	if err != nil || proxy == nil {
+		return
+	}
+	g.logger.LogAttrs(context.Background(), slog.LevelDebug, "gather使用代理", slog.String("proxy", redactURL(proxy)))
+}
+
+// logRequest 记录一次实际发出的请求，调用方需持有g.locker
+func (g *GatherStruct) logRequest(req *http.Request, resp *Response, err error, timing RequestTiming) {
+	if g.logger == nil {
+		return
+	}
+	ctx := req.Context()
+	attrs := []slog.Attr{
+		slog.String("method", requestMethod(req)),
+		slog.String("url", redactURL(req.URL)),
+		slog.Duration("duration", timing.Total),
+	}
+	if resp != nil {
+		attrs = append(attrs,
+			slog.Int("status", resp.StatusCode),
+			slog.Int64("bytes", resp.rawSize),
+		)
+		if resp.FinalURL != req.URL.String() {
+			attrs = append(attrs, slog.String("final_url", redactRawURL(resp.FinalURL)))
+		}
+	}
+	if err != nil {
+		g.logger.LogAttrs(ctx, slog.LevelWarn, "gather请求失败", append(attrs, slog.String("error", err.Error()))...)
+		return
+	}
+	if g.logger.Enabled(ctx, slog.LevelDebug) {
+		attrs = append(attrs, slog.Any("headers", redactHeader(req.Header)))
+		g.logger.LogAttrs(ctx, slog.LevelDebug, "gather请求完成", attrs...)
+	}
+}
+
+// logEvent 记录请求相关的其他事件（重试、命中缓存、robots.txt禁止等），调用方需持有g.locker
+func (g *GatherStruct) logEvent(req *http.Request, level slog.Level, msg string, attrs ...slog.Attr) {
+	if g.logger == nil {
+		return
+	}
+	attrs = append([]slog.Attr{
+		slog.String("method", requestMethod(req)),
+		slog.String("url", redactURL(req.URL)),
+	}, attrs...)
+	g.logger.LogAttrs(req.Context(), level, msg, attrs...)
+}
+
+// logAcquireFailure 记录Pool获取实例失败
+func (p *Pool) logAcquireFailure(logger *slog.Logger, wait time.Duration, err error) {
+	if logger == nil {
+		return
+	}
+	logger.LogAttrs(context.Background(), slog.LevelWarn, "gather获取池实例失败",
+		slog.Int("size", len(p.pool)),
+		slog.Duration("wait", wait),
+		slog.String("error", err.Error()),
+	)
+}
+
+// requestMethod 返回请求方法（空方法按GET处理）
+func requestMethod(req *http.Request) string {
+	if req.Method == "" {
+		return http.MethodGet
+	}
+	return req.Method
+}
+
+// redactURL 返回隐藏了用户名和密码的URL字符串
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	if u.User == nil {
+		return u.String()
+	}
+	clone := *u
+	if _, hasPassword := u.User.Password(); hasPassword {
+		clone.User = url.UserPassword(redactedValue, redactedValue)
+	} else {
+		clone.User = url.User(redactedValue)
+	}
+	return clone.String()
+}
+
+// redactRawURL 对URL字符串脱敏，无法解析时原样返回
+func redactRawURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return redactURL(u)
+}
+
+// redactHeader 返回敏感值已脱敏的头部副本，Cookie类头部保留名称只隐藏值
+func redactHeader(header http.Header) http.Header {
+	redacted := make(http.Header, len(header))
+	for name, values := range header {
+		canonical := http.CanonicalHeaderKey(name)
+		if !sensitiveHeaders[canonical] {
+			redacted[name] = values
+			continue
+		}
+		masked := make([]string, len(values))
+		for i, v := range values {
+			switch canonical {
+			case "Cookie":
+				masked[i] = redactCookieHeader(v)
+			case "Set-Cookie":
+				if c, err := http.ParseSetCookie(v); err == nil {
+					masked[i] = redactCookie(c)
+				} else {
+					masked[i] = redactedValue
+				}
+			default:
+				// 保留认证方案（如Basic、Bearer），隐藏凭证
+				if scheme, _, found := strings.Cut(v, " "); found {
+					masked[i] = scheme + " " + redactedValue
+				} else {
+					masked[i] = redactedValue
+				}
+			}
+		}
+		redacted[name] = masked
+	}
+	return redacted
+}
+
+// redactCookieHeader 隐藏Cookie请求头中的各个值，保留名称
+func redactCookieHeader(v string) string {
+	parts := strings.Split(v, ";")
+	for i, part := range parts {
+		name, _, _ := strings.Cut(strings.TrimSpace(part), "=")
+		parts[i] = name + "=" + redactedValue
+	}
+	return strings.Join(parts, "; ")
+}
+
+// redactCookie 返回隐藏了值的Cookie描述（保留名称、域、路径等属性）
+func redactCookie(c *http.Cookie) string {
+	clone := *c
+	clone.Value = redactedValue
+	clone.Raw, clone.Unparsed = "", nil
+	return clone.String()
+}
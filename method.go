@@ -6,6 +6,8 @@
 // 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
 package gather
 
+import "context"
+
 /*
 GET方式获取数据,自动继承先前的cookies
 URL:指待抓取的URL
@@ -34,11 +36,45 @@ html, redirectURL, err := ga.MethodUtil("OPTIONS","https://weibo.com/xxxxxx",coo
 */
 //GET方式获取数据,手动设置Cookie,Cookie留空则自动继承上次抓取时使用的Cookie
 func (g *GatherStruct) MethodUtil(method, URL, refererURL, cookies string) (html, redirectURL string, err error) {
+	return g.MethodUtilContext(context.Background(), method, URL, refererURL, cookies)
+}
+
+// MethodContext 同Method，支持通过ctx取消进行中的请求
+func (g *GatherStruct) MethodContext(ctx context.Context, method, URL, refererURL string) (html, redirectURL string, err error) {
+	return g.MethodUtilContext(ctx, method, URL, refererURL, "")
+}
+
+// MethodUtilContext 同MethodUtil，支持通过ctx取消进行中的请求
+func (g *GatherStruct) MethodUtilContext(ctx context.Context, method, URL, refererURL, cookies string) (html, redirectURL string, err error) {
 	g.locker.Lock()
 	defer g.locker.Unlock()
-	req, err := g.newHttpRequest(method, URL, refererURL, cookies, nil)
+	req, err := g.newHttpRequest(ctx, method, URL, refererURL, cookies, nil)
 	if err != nil {
 		return "", "", err
 	}
 	return g.request(req)
 }
+
+/*
+MethodResponse 以任意HTTP方法获取数据，返回完整的Response对象
+参数与MethodUtil一致，cookies留空则自动继承上次抓取时使用的Cookie
+非2xx状态码时resp与err一同返回
+
+例:
+ga := NewGather("chrome", false)
+resp, err := ga.MethodResponse("HEAD", "https://www.baidu.com/", "", "")
+*/
+func (g *GatherStruct) MethodResponse(method, URL, refererURL, cookies string) (*Response, error) {
+	return g.MethodResponseContext(context.Background(), method, URL, refererURL, cookies)
+}
+
+// MethodResponseContext 同MethodResponse，支持通过ctx取消进行中的请求
+func (g *GatherStruct) MethodResponseContext(ctx context.Context, method, URL, refererURL, cookies string) (*Response, error) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	req, err := g.newHttpRequest(ctx, method, URL, refererURL, cookies, nil)
+	if err != nil {
+		return nil, err
+	}
+	return g.do(req)
+}
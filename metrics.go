@@ -0,0 +1,432 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultMetricsBuckets 耗时直方图的默认分桶（秒）
+var defaultMetricsBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// defaultMetricsMaxHosts 默认最多按多少个不同主机分别统计（见SetMaxHosts）
+const defaultMetricsMaxHosts = 100
+
+// metricsOtherHost 超出主机数上限或关闭主机标签后，请求统计到的host标签值
+const metricsOtherHost = "other"
+
+// Metrics 指标采集器，不依赖第三方库，并发安全
+// 记录内容：
+//  1. 按主机、方法、状态类别（2xx/3xx/4xx/5xx/error）统计的请求数、请求/响应字节数、耗时直方图
+//  2. 按主机、错误类别（timeout/dns/tls/proxy/canceled/other）统计的错误数
+//  3. Pool的实例总数、使用中/等待中实例数、获取实例的等待耗时直方图及获取失败次数
+//
+// 每次实际发出的请求（含每次重试）计一次，命中缓存的请求不计
+// 为避免采集大量站点时标签无限增长，默认只为最先出现的100个主机单独统计，其余计入host="other"（见SetMaxHosts）
+// 同一个Metrics可同时挂到多个实例和Pool上，通过Handler（Prometheus文本格式）或Publish（expvar）暴露
+// 示例：
+//
+//	metrics := gather.NewMetrics()
+//	pool.SetMetrics(metrics)
+//	ga.SetMetrics(metrics)
+//	http.Handle("/metrics", metrics.Handler())
+//	metrics.Publish("gather") // 同时在/debug/vars中输出
+type Metrics struct {
+	mu       sync.Mutex
+	buckets  []float64
+	maxHosts int                 // 单独统计的主机数上限
+	hosts    map[string]struct{} // 已单独统计的主机
+	requests map[requestMetricKey]*requestMetric
+	errors   map[errorMetricKey]uint64
+	pools    []*poolMetric
+	nextPool int // 下一个登记的Pool的编号
+}
+
+// requestMetricKey 请求指标的标签
+type requestMetricKey struct {
+	host, method, class string
+}
+
+// requestMetric 一组标签下的请求指标
+type requestMetric struct {
+	count         uint64
+	requestBytes  uint64
+	responseBytes uint64
+	duration      histogram
+}
+
+// errorMetricKey 错误指标的标签
+type errorMetricKey struct {
+	host, kind string
+}
+
+// poolMetric 单个Pool的指标
+type poolMetric struct {
+	metrics  *Metrics
+	name     string
+	pool     *Pool
+	wait     histogram
+	failures uint64
+}
+
+// histogram 累积直方图
+type histogram struct {
+	counts []uint64 // 各分桶（非累积）的计数，最后一个为+Inf
+	sum    float64
+	count  uint64
+}
+
+// observe 记录一个观测值
+func (h *histogram) observe(buckets []float64, v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(buckets)+1)
+	}
+	i := sort.SearchFloat64s(buckets, v)
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+// NewMetrics 创建指标采集器，buckets为耗时直方图的分桶上限（秒，升序），不传时使用默认分桶
+func NewMetrics(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = defaultMetricsBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &Metrics{
+		buckets:  buckets,
+		maxHosts: defaultMetricsMaxHosts,
+		hosts:    make(map[string]struct{}),
+		requests: make(map[requestMetricKey]*requestMetric),
+		errors:   make(map[errorMetricKey]uint64),
+	}
+}
+
+// SetMaxHosts 设置最多按多少个不同主机分别统计（默认100），超出后新出现的主机统一计入host="other"；
+// n<=0表示不区分主机，全部计入host="other"。已单独统计的主机不受影响
+func (m *Metrics) SetMaxHosts(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxHosts = max(n, 0)
+}
+
+// hostLabel 主机对应的host标签值，调用方需持有m.mu
+func (m *Metrics) hostLabel(host string) string {
+	if _, ok := m.hosts[host]; ok {
+		return host
+	}
+	if len(m.hosts) >= m.maxHosts {
+		return metricsOtherHost
+	}
+	m.hosts[host] = struct{}{}
+	return host
+}
+
+// SetMetrics 为实例挂载指标采集器（nil表示停止采集）
+func (g *GatherStruct) SetMetrics(m *Metrics) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	g.metrics = m
+}
+
+// SetMetrics 为池内所有实例挂载指标采集器，同时采集池的使用情况
+// 多个Pool挂到同一个Metrics时，按挂载顺序以pool="0"、pool="1"……区分
+// 传nil或改挂其他Metrics时从原Metrics中注销本Pool，不再输出它的指标，也不再持有它的引用；
+// 不再使用的Pool应先SetMetrics(nil)，否则会一直保留在Metrics中
+func (p *Pool) SetMetrics(m *Metrics) {
+	for _, ga := range p.pool {
+		ga.SetMetrics(m)
+	}
+	p.locker.Lock()
+	defer p.locker.Unlock()
+	if p.metrics != nil && p.metrics.metrics != m {
+		p.metrics.metrics.unregisterPool(p)
+	}
+	p.metrics = nil
+	if m != nil {
+		p.metrics = m.registerPool(p)
+	}
+}
+
+// registerPool 登记Pool（重复登记返回已有记录）
+func (m *Metrics) registerPool(p *Pool) *poolMetric {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, pm := range m.pools {
+		if pm.pool == p {
+			return pm
+		}
+	}
+	pm := &poolMetric{metrics: m, name: strconv.Itoa(m.nextPool), pool: p}
+	m.nextPool++
+	m.pools = append(m.pools, pm)
+	return pm
+}
+
+// unregisterPool 注销Pool（编号不复用，避免与已采集的时间序列混淆）
+func (m *Metrics) unregisterPool(p *Pool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pools = slices.DeleteFunc(m.pools, func(pm *poolMetric) bool { return pm.pool == p })
+}
+
+// observeRequest 记录一次实际发出的请求
+func (m *Metrics) observeRequest(req *http.Request, resp *Response, err error, duration time.Duration) {
+	key := requestMetricKey{method: req.Method, class: "error"}
+	if key.method == "" {
+		key.method = http.MethodGet
+	}
+	var responseBytes uint64
+	if resp != nil {
+		key.class = fmt.Sprintf("%dxx", resp.StatusCode/100)
+		responseBytes = uint64(resp.rawSize)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key.host = m.hostLabel(strings.ToLower(req.URL.Host))
+	metric := m.requests[key]
+	if metric == nil {
+		metric = &requestMetric{}
+		m.requests[key] = metric
+	}
+	metric.count++
+	if req.ContentLength > 0 {
+		metric.requestBytes += uint64(req.ContentLength)
+	}
+	metric.responseBytes += responseBytes
+	metric.duration.observe(m.buckets, duration.Seconds())
+	if resp == nil && err != nil {
+		m.errors[errorMetricKey{host: key.host, kind: errorKind(err)}]++
+	}
+}
+
+// observePoolWait 记录一次获取池实例的等待
+func (m *Metrics) observePoolWait(pm *poolMetric, wait time.Duration, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !ok {
+		pm.failures++
+		return
+	}
+	pm.wait.observe(m.buckets, wait.Seconds())
+}
+
+// errorKind 错误类别标签
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, ErrDNS):
+		return "dns"
+	case errors.Is(err, ErrTLS):
+		return "tls"
+	case errors.Is(err, ErrProxy):
+		return "proxy"
+	case errors.Is(err, ErrBodyTooLarge):
+		return "body_too_large"
+	}
+	return "other"
+}
+
+// Handler 返回输出Prometheus文本格式（0.0.4）指标的http.Handler
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.WritePrometheus(w)
+	})
+}
+
+// WritePrometheus 以Prometheus文本格式写出全部指标
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	var b strings.Builder
+	m.mu.Lock()
+	keys := make([]requestMetricKey, 0, len(m.requests))
+	for key := range m.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, c := keys[i], keys[j]
+		if a.host != c.host {
+			return a.host < c.host
+		}
+		if a.method != c.method {
+			return a.method < c.method
+		}
+		return a.class < c.class
+	})
+	requestLabels := func(key requestMetricKey) string {
+		return fmt.Sprintf(`host="%s",method="%s",class="%s"`, escapeLabel(key.host), escapeLabel(key.method), key.class)
+	}
+
+	writeHeader(&b, "gather_requests_total", "counter", "实际发出的请求数")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "gather_requests_total{%s} %d\n", requestLabels(key), m.requests[key].count)
+	}
+	writeHeader(&b, "gather_request_bytes_total", "counter", "请求体字节数")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "gather_request_bytes_total{%s} %d\n", requestLabels(key), m.requests[key].requestBytes)
+	}
+	writeHeader(&b, "gather_response_bytes_total", "counter", "响应体字节数（解压前）")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "gather_response_bytes_total{%s} %d\n", requestLabels(key), m.requests[key].responseBytes)
+	}
+	writeHeader(&b, "gather_request_duration_seconds", "histogram", "请求耗时（秒）")
+	for _, key := range keys {
+		writeHistogram(&b, "gather_request_duration_seconds", requestLabels(key), m.buckets, &m.requests[key].duration)
+	}
+
+	errorKeys := make([]errorMetricKey, 0, len(m.errors))
+	for key := range m.errors {
+		errorKeys = append(errorKeys, key)
+	}
+	sort.Slice(errorKeys, func(i, j int) bool {
+		if errorKeys[i].host != errorKeys[j].host {
+			return errorKeys[i].host < errorKeys[j].host
+		}
+		return errorKeys[i].kind < errorKeys[j].kind
+	})
+	writeHeader(&b, "gather_errors_total", "counter", "按类别统计的请求错误数")
+	for _, key := range errorKeys {
+		fmt.Fprintf(&b, "gather_errors_total{host=\"%s\",kind=\"%s\"} %d\n", escapeLabel(key.host), key.kind, m.errors[key])
+	}
+
+	if len(m.pools) > 0 {
+		writeHeader(&b, "gather_pool_size", "gauge", "池内实例总数")
+		for _, pm := range m.pools {
+			fmt.Fprintf(&b, "gather_pool_size{pool=\"%s\"} %d\n", pm.name, len(pm.pool.pool))
+		}
+		writeHeader(&b, "gather_pool_in_use", "gauge", "使用中的实例数")
+		for _, pm := range m.pools {
+			fmt.Fprintf(&b, "gather_pool_in_use{pool=\"%s\"} %d\n", pm.name, pm.pool.inUse.Load())
+		}
+		writeHeader(&b, "gather_pool_waiting", "gauge", "等待空闲实例的调用数")
+		for _, pm := range m.pools {
+			fmt.Fprintf(&b, "gather_pool_waiting{pool=\"%s\"} %d\n", pm.name, pm.pool.waiting.Load())
+		}
+		writeHeader(&b, "gather_pool_acquire_failures_total", "counter", "获取实例失败（超时或取消）的次数")
+		for _, pm := range m.pools {
+			fmt.Fprintf(&b, "gather_pool_acquire_failures_total{pool=\"%s\"} %d\n", pm.name, pm.failures)
+		}
+		writeHeader(&b, "gather_pool_wait_seconds", "histogram", "获取实例的等待耗时（秒）")
+		for _, pm := range m.pools {
+			writeHistogram(&b, "gather_pool_wait_seconds", fmt.Sprintf(`pool="%s"`, pm.name), m.buckets, &pm.wait)
+		}
+	}
+	m.mu.Unlock()
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeHeader 写出指标的HELP和TYPE行
+func writeHeader(b *strings.Builder, name, typ, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// writeHistogram 写出直方图的累积分桶、总和与总数
+func writeHistogram(b *strings.Builder, name, labels string, buckets []float64, h *histogram) {
+	var cumulative uint64
+	for i, upper := range buckets {
+		if h.counts != nil {
+			cumulative += h.counts[i]
+		}
+		fmt.Fprintf(b, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, strconv.FormatFloat(upper, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(b, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+	fmt.Fprintf(b, "%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(b, "%s_count{%s} %d\n", name, labels, h.count)
+}
+
+// escapeLabel 转义标签值中的反斜杠、双引号和换行
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+// Publish 以name为名称发布到expvar（/debug/vars），同一名称只能发布一次，重复发布会panic（expvar的限制）
+func (m *Metrics) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() any { return m.Snapshot() }))
+}
+
+// MetricsSnapshot 指标快照（用于expvar输出或自行处理）
+type MetricsSnapshot struct {
+	Requests []RequestMetrics `json:"requests"`
+	Errors   []ErrorMetrics   `json:"errors"`
+	Pools    []PoolMetrics    `json:"pools"`
+}
+
+// RequestMetrics 一组标签下的请求指标
+type RequestMetrics struct {
+	Host            string  `json:"host"`
+	Method          string  `json:"method"`
+	Class           string  `json:"class"` // 状态类别：2xx/3xx/4xx/5xx/error
+	Count           uint64  `json:"count"`
+	RequestBytes    uint64  `json:"requestBytes"`
+	ResponseBytes   uint64  `json:"responseBytes"`
+	DurationSeconds float64 `json:"durationSeconds"` // 耗时总和
+}
+
+// ErrorMetrics 按类别统计的错误数
+type ErrorMetrics struct {
+	Host  string `json:"host"`
+	Kind  string `json:"kind"`
+	Count uint64 `json:"count"`
+}
+
+// PoolMetrics Pool的使用情况
+type PoolMetrics struct {
+	Name            string  `json:"name"`
+	Size            int     `json:"size"`
+	InUse           int64   `json:"inUse"`
+	Waiting         int64   `json:"waiting"`
+	Acquired        uint64  `json:"acquired"`
+	AcquireFailures uint64  `json:"acquireFailures"`
+	WaitSeconds     float64 `json:"waitSeconds"` // 等待耗时总和
+}
+
+// Snapshot 返回当前指标的快照
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := MetricsSnapshot{Requests: []RequestMetrics{}, Errors: []ErrorMetrics{}, Pools: []PoolMetrics{}}
+	for key, metric := range m.requests {
+		snapshot.Requests = append(snapshot.Requests, RequestMetrics{
+			Host: key.host, Method: key.method, Class: key.class, Count: metric.count,
+			RequestBytes: metric.requestBytes, ResponseBytes: metric.responseBytes, DurationSeconds: metric.duration.sum,
+		})
+	}
+	sort.Slice(snapshot.Requests, func(i, j int) bool {
+		a, c := snapshot.Requests[i], snapshot.Requests[j]
+		return a.Host+" "+a.Method+" "+a.Class < c.Host+" "+c.Method+" "+c.Class
+	})
+	for key, count := range m.errors {
+		snapshot.Errors = append(snapshot.Errors, ErrorMetrics{Host: key.host, Kind: key.kind, Count: count})
+	}
+	sort.Slice(snapshot.Errors, func(i, j int) bool {
+		return snapshot.Errors[i].Host+" "+snapshot.Errors[i].Kind < snapshot.Errors[j].Host+" "+snapshot.Errors[j].Kind
+	})
+	for _, pm := range m.pools {
+		snapshot.Pools = append(snapshot.Pools, PoolMetrics{
+			Name: pm.name, Size: len(pm.pool.pool), InUse: pm.pool.inUse.Load(), Waiting: pm.pool.waiting.Load(),
+			Acquired: pm.wait.count, AcquireFailures: pm.failures, WaitSeconds: pm.wait.sum,
+		})
+	}
+	return snapshot
+}
@@ -0,0 +1,188 @@
+package gather
+
+import (
+	"encoding/json"
+	"expvar"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestMetrics 测试请求计数、字节数、耗时直方图、错误分类及Prometheus输出
+func TestMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/404" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	metrics := NewMetrics()
+	ga := NewGather("chrome", false)
+	ga.SetMetrics(metrics)
+	ga.Get(server.URL, "")
+	ga.Get(server.URL, "")
+	ga.Post(server.URL, "", map[string]string{"a": "1"})
+	ga.Get(server.URL+"/404", "")
+	ga.Get("http://127.0.0.1:1/", "")
+
+	snapshot := metrics.Snapshot()
+	counts := map[string]RequestMetrics{}
+	for _, r := range snapshot.Requests {
+		counts[r.Host+" "+r.Method+" "+r.Class] = r
+	}
+	if r := counts[host+" GET 2xx"]; r.Count != 2 || r.ResponseBytes != 10 || r.DurationSeconds <= 0 {
+		t.Errorf("GET 2xx统计错误：%+v", r)
+	}
+	if r := counts[host+" POST 2xx"]; r.Count != 1 || r.RequestBytes != uint64(len(url.Values{"a": {"1"}}.Encode())) {
+		t.Errorf("POST 2xx统计错误：%+v", r)
+	}
+	if r := counts[host+" GET 4xx"]; r.Count != 1 {
+		t.Errorf("GET 4xx统计错误：%+v", r)
+	}
+	if r := counts["127.0.0.1:1 GET error"]; r.Count != 1 {
+		t.Errorf("失败请求统计错误：%+v", snapshot.Requests)
+	}
+	if len(snapshot.Errors) != 1 || snapshot.Errors[0].Kind != "other" || snapshot.Errors[0].Count != 1 {
+		t.Errorf("错误分类统计错误：%+v", snapshot.Errors)
+	}
+
+	// Prometheus文本格式
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	text := recorder.Body.String()
+	for _, want := range []string{
+		"# TYPE gather_requests_total counter",
+		`gather_requests_total{host="` + host + `",method="GET",class="2xx"} 2`,
+		`gather_request_duration_seconds_bucket{host="` + host + `",method="GET",class="2xx",le="+Inf"} 2`,
+		`gather_request_duration_seconds_count{host="` + host + `",method="GET",class="2xx"} 2`,
+		`gather_errors_total{host="127.0.0.1:1",kind="other"} 1`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Prometheus输出缺少%q：\n%s", want, text)
+		}
+	}
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type错误：%s", recorder.Header().Get("Content-Type"))
+	}
+}
+
+// TestMetrics_Pool 测试Pool的实例数、使用中/等待中实例数及等待耗时
+func TestMetrics_Pool(t *testing.T) {
+	metrics := NewMetrics()
+	pool := NewGatherUtilPool(map[string]string{"User-Agent": "test"}, "", 30, false, 2)
+	pool.SetMetrics(metrics)
+	pool.SetMetrics(metrics) // 重复挂载不重复登记
+
+	ga, release, err := pool.acquire(nil)
+	if err != nil || ga == nil {
+		t.Fatalf("获取实例失败：%v", err)
+	}
+	if s := metrics.Snapshot().Pools; len(s) != 1 || s[0].Size != 2 || s[0].InUse != 1 || s[0].Waiting != 0 {
+		t.Errorf("使用中实例数错误：%+v", s)
+	}
+	release()
+	pool.Get(testBaseURL+"/get", "")
+
+	s := metrics.Snapshot().Pools[0]
+	if s.InUse != 0 || s.Acquired != 2 || s.AcquireFailures != 0 {
+		t.Errorf("池统计错误：%+v", s)
+	}
+
+	var text strings.Builder
+	metrics.WritePrometheus(&text)
+	for _, want := range []string{`gather_pool_size{pool="0"} 2`, `gather_pool_in_use{pool="0"} 0`, `gather_pool_wait_seconds_count{pool="0"} 2`} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("Prometheus输出缺少%q", want)
+		}
+	}
+
+	// 注销后不再输出，也不再持有Pool的引用；重新挂载时使用新编号
+	pool.SetMetrics(nil)
+	if s := metrics.Snapshot().Pools; len(s) != 0 || len(metrics.pools) != 0 {
+		t.Errorf("注销后不应保留Pool：%+v", s)
+	}
+	pool2 := NewGatherUtilPool(map[string]string{"User-Agent": "test"}, "", 30, false, 1)
+	pool2.SetMetrics(metrics)
+	pool.SetMetrics(metrics)
+	other := NewMetrics()
+	pool2.SetMetrics(other) // 改挂其他Metrics时从原Metrics注销
+	if s := metrics.Snapshot().Pools; len(s) != 1 || s[0].Name != "2" || s[0].Size != 2 {
+		t.Errorf("重新挂载后的Pool记录错误：%+v", s)
+	}
+	if s := other.Snapshot().Pools; len(s) != 1 || s[0].Name != "0" {
+		t.Errorf("改挂后的Pool记录错误：%+v", s)
+	}
+}
+
+// TestMetrics_MaxHosts 测试主机标签数量上限
+func TestMetrics_MaxHosts(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+	server1, server2 := httptest.NewServer(handler), httptest.NewServer(handler)
+	defer server1.Close()
+	defer server2.Close()
+	host1 := strings.TrimPrefix(server1.URL, "http://")
+
+	metrics := NewMetrics()
+	metrics.SetMaxHosts(1)
+	ga := NewGather("chrome", false)
+	ga.SetMetrics(metrics)
+	ga.Get(server1.URL, "")
+	ga.Get(server2.URL, "")
+	ga.Get(server1.URL, "")
+	ga.Get("http://127.0.0.1:1/", "")
+
+	counts := map[string]uint64{}
+	for _, r := range metrics.Snapshot().Requests {
+		counts[r.Host+" "+r.Class] = r.Count
+	}
+	if len(counts) != 3 || counts[host1+" 2xx"] != 2 || counts["other 2xx"] != 1 || counts["other error"] != 1 {
+		t.Errorf("超出上限的主机应计入other：%v", counts)
+	}
+	if e := metrics.Snapshot().Errors; len(e) != 1 || e[0].Host != "other" {
+		t.Errorf("错误统计的主机标签也应受上限约束：%+v", e)
+	}
+
+	// 关闭主机标签
+	metrics = NewMetrics()
+	metrics.SetMaxHosts(0)
+	ga.SetMetrics(metrics)
+	ga.Get(server1.URL, "")
+	ga.Get(server2.URL, "")
+	if r := metrics.Snapshot().Requests; len(r) != 1 || r[0].Host != "other" || r[0].Count != 2 {
+		t.Errorf("关闭主机标签后应全部计入other：%+v", r)
+	}
+}
+
+// TestMetrics_Publish 测试通过expvar发布
+func TestMetrics_Publish(t *testing.T) {
+	metrics := NewMetrics()
+	ga := NewGather("chrome", false)
+	ga.SetMetrics(metrics)
+	ga.Get(testBaseURL+"/get", "")
+	metrics.Publish("gather_test_metrics")
+
+	server := httptest.NewServer(expvar.Handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("读取expvar失败：%v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	var vars struct {
+		Metrics MetricsSnapshot `json:"gather_test_metrics"`
+	}
+	if err := json.Unmarshal(body, &vars); err != nil {
+		t.Fatalf("解析expvar失败：%v", err)
+	}
+	if len(vars.Metrics.Requests) != 1 || vars.Metrics.Requests[0].Count != 1 || vars.Metrics.Requests[0].Class != "2xx" {
+		t.Errorf("expvar输出错误：%+v", vars.Metrics)
+	}
+}
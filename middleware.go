@@ -0,0 +1,59 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"errors"
+	"net/http"
+)
+
+// errMiddlewareNoResponse 中间件既未返回响应也未返回错误
+var errMiddlewareNoResponse = errors.New("中间件未返回响应")
+
+// RoundTripFunc 执行一次采集请求并返回解析后的Response（已解压、已检测字符集）
+type RoundTripFunc func(req *http.Request) (*Response, error)
+
+// Middleware 请求中间件：包装next，可在调用next前修改请求（如添加签名头），
+// 在调用next后读取或修改Response（如记录日志、改写响应体），也可以不调用next直接返回
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// Use 注册中间件，Get/Post/Method/Do等所有方法发出的请求都会经过中间件
+// 执行顺序：先注册的在外层，即请求按注册顺序经过各中间件，响应按相反顺序返回：
+//
+//	ga.Use(A, B) // 请求：A → B → 实际请求；响应：实际请求 → B → A
+//
+// 中间件位于robots.txt检查、缓存和重试之外，每次调用只执行一次（重试不会重复经过中间件）
+// 注意：中间件执行时实例处于加锁状态，不能在中间件中调用同一实例的方法
+//
+// 示例：
+//
+//	ga.Use(func(next gather.RoundTripFunc) gather.RoundTripFunc {
+//	    return func(req *http.Request) (*gather.Response, error) {
+//	        req.Header.Set("X-Sign", sign(req.URL.String()))
+//	        resp, err := next(req)
+//	        if resp != nil {
+//	            log.Println(req.URL, resp.StatusCode, len(resp.Body))
+//	        }
+//	        return resp, err
+//	    }
+//	})
+func (g *GatherStruct) Use(middlewares ...Middleware) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	for _, m := range middlewares {
+		if m != nil {
+			g.middlewares = append(g.middlewares, m)
+		}
+	}
+}
+
+// Use 为池内所有实例注册中间件（顺序规则同GatherStruct.Use）
+func (p *Pool) Use(middlewares ...Middleware) {
+	for _, ga := range p.pool {
+		ga.Use(middlewares...)
+	}
+}
@@ -0,0 +1,87 @@
+package gather
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// traceMiddleware 记录经过顺序的测试中间件
+func traceMiddleware(name string, trace *[]string) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*Response, error) {
+			*trace = append(*trace, name+">")
+			req.Header.Add("X-Trace", name)
+			resp, err := next(req)
+			*trace = append(*trace, "<"+name)
+			return resp, err
+		}
+	}
+}
+
+// TestMiddleware 测试中间件顺序、修改请求与响应、短路返回
+func TestMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + ":" + strings.Join(r.Header.Values("X-Trace"), ",")))
+	}))
+	defer server.Close()
+
+	var trace []string
+	ga := NewGather("chrome", false)
+	ga.Use(traceMiddleware("A", &trace), traceMiddleware("B", &trace))
+	ga.Use(func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*Response, error) {
+			resp, err := next(req)
+			if resp != nil {
+				resp.Body = []byte(strings.ToUpper(string(resp.Body))) // 修改解析后的响应
+			}
+			return resp, err
+		}
+	})
+
+	html, _, err := ga.Get(server.URL, "")
+	if err != nil || html != "GET:A,B" {
+		t.Errorf("中间件应能修改请求和响应，实际：%q, %v", html, err)
+	}
+	if strings.Join(trace, " ") != "A> B> <B <A" {
+		t.Errorf("中间件执行顺序错误：%v", trace)
+	}
+
+	// 所有方法变体都经过中间件
+	trace = nil
+	if html, _, _ := ga.Method("DELETE", server.URL, ""); html != "DELETE:A,B" || len(trace) != 4 {
+		t.Errorf("Method应经过中间件：%q, %v", html, trace)
+	}
+	if html, _, _ := ga.PostUtil(server.URL, "", "", map[string]string{"a": "1"}); html != "POST:A,B" {
+		t.Errorf("PostUtil应经过中间件：%q", html)
+	}
+
+	// 短路：不调用next直接返回错误
+	errBlocked := errors.New("blocked")
+	pool := NewGatherUtilPool(map[string]string{"User-Agent": "test"}, "", 30, false, 2)
+	pool.Use(func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*Response, error) {
+			if strings.HasSuffix(req.URL.Path, "/blocked") {
+				return nil, errBlocked
+			}
+			return next(req)
+		}
+	})
+	if _, _, err := pool.Get(server.URL+"/blocked", ""); !errors.Is(err, errBlocked) {
+		t.Errorf("中间件应能直接返回错误，实际：%v", err)
+	}
+	if html, _, err := pool.Get(server.URL, ""); err != nil || html != "GET:" {
+		t.Errorf("Pool中间件放行失败：%q, %v", html, err)
+	}
+
+	// 既不返回响应也不返回错误
+	ga2 := NewGather("chrome", false)
+	ga2.Use(func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*Response, error) { return nil, nil }
+	})
+	if _, _, err := ga2.Get(server.URL, ""); err == nil {
+		t.Error("中间件未返回响应时应返回错误")
+	}
+}
@@ -17,7 +17,7 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
@@ -347,8 +347,24 @@ type GatherStruct struct {
 	Client      *http.Client      // HTTP客户端实例（包含Transport和CookieJar）
 	Headers     map[string]string // 基础请求头（初始化时赋值，非并发安全）
 	safeHeaders sync.Map          // 并发安全的请求头存储（运行时动态修改）
-	J           *webCookieJar     // Cookie管理器（自动处理Cookie生命周期）
+	J           *WebCookieJar     // Cookie管理器（自动处理Cookie生命周期）
 	locker      sync.Mutex        // 实例级锁，保护结构体字段并发修改
+
+	charsetDisabled bool                // 是否关闭自动字符集转码（默认开启，见SetAutoCharset）
+	maxBodySize     int64               // 响应体最大字节数，<=0表示不限制（见SetMaxBodySize）
+	statusPolicy    StatusPolicy        // 实例级状态码策略（见SetStatusPolicy）
+	redirectPolicy  RedirectPolicy      // 实例级跳转策略（见SetRedirectPolicy）
+	retryPolicy     RetryPolicy         // 实例级重试策略（见SetRetryPolicy）
+	limiter         *HostLimiter        // 按主机限流器，可在多个实例间共享（见SetRateLimiter）
+	robotsPolicy    RobotsPolicy        // robots.txt策略（见SetRobotsPolicy）
+	robots          *robotsCache        // robots.txt缓存，Pool内实例共享
+	cache           CacheStore          // HTTP响应缓存，nil表示不缓存（见SetCache）
+	har             *HARRecorder        // HAR记录器，nil表示不记录（见SetHARRecorder）
+	middlewares     []Middleware        // 请求中间件，按注册顺序由外到内执行（见Use）
+	timingCallback  func(RequestTiming) // 实例级耗时回调（见SetTimingCallback）
+	lastTiming      RequestTiming       // 最近一次请求的耗时（见LastTiming）
+	metrics         *Metrics            // 指标采集器，nil表示不采集（见SetMetrics）
+	logger          *slog.Logger        // 结构化日志，nil表示不记录（见SetLogger）
 }
 
 // NewGather 快捷创建无代理的采集器实例（默认启用慢速配置）
@@ -409,7 +425,7 @@ func NewGatherUtil(headers map[string]string, proxyURL string, timeOut int, isCo
 		if v, exist := headers["User-Agent"]; exist {
 			var defaultHeaders = make(map[string]string)
 			defaultHeaders["Accept"] = "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8"
-			defaultHeaders["Accept-Encoding"] = "gzip, deflate, sdch"
+			defaultHeaders["Accept-Encoding"] = AcceptEncoding() // 与可解码的编码集合保持一致
 			defaultHeaders["Accept-Language"] = "zh-CN,zh;q=0.8"
 			defaultHeaders["Connection"] = "keep-alive"
 			defaultHeaders["Upgrade-Insecure-Requests"] = "1"
@@ -537,9 +553,10 @@ func newTransport(cfg *GatherConfig, proxy func(*http.Request) (*url.URL, error)
 		},
 	}
 
-	// 设置代理（如有）
+	// 设置代理（如有），代理拒绝CONNECT时返回带状态码的错误，归类为ErrProxy
 	if proxy != nil {
 		transport.Proxy = proxy
+		transport.OnProxyConnectResponse = checkProxyConnect
 	}
 
 	return transport
@@ -569,8 +586,6 @@ func NewGatherProxyHasPassUtil(headers map[string]string, proxyURL, user, pass s
 // 2. 添加用户名密码到代理URL的认证字段
 // 3. 基于当前全局配置创建Transport，保证配置一致性
 func getHttpTransportHasPass(proxyUrl, user, pass string) *http.Transport {
-	log.Printf("初始化带认证代理：%s, 用户名：%s", proxyUrl, user)
-
 	// 读锁获取当前全局配置
 	configLocker.RLock()
 	cfg := globalConfig
@@ -615,7 +630,7 @@ func NewGatherUtilHasPass(headers map[string]string, proxyURL, user, pass string
 		if v, exist := headers["User-Agent"]; exist {
 			var defaultHeaders = make(map[string]string)
 			defaultHeaders["Accept"] = "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8"
-			defaultHeaders["Accept-Encoding"] = "gzip, deflate, sdch"
+			defaultHeaders["Accept-Encoding"] = AcceptEncoding() // 与可解码的编码集合保持一致
 			defaultHeaders["Accept-Language"] = "zh-CN,zh;q=0.8"
 			defaultHeaders["Connection"] = "keep-alive"
 			defaultHeaders["Upgrade-Insecure-Requests"] = "1"
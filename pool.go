@@ -8,9 +8,10 @@ package gather
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -25,6 +26,11 @@ type Pool struct {
 	locker sync.Mutex      // 兼容旧逻辑的锁（当前核心逻辑已不依赖，仅做兼容）
 	sem    chan struct{}   // 信号量：控制并发获取实例，容量=池大小，避免资源耗尽
 	config PoolConfig      // 池配置项，所有参数可自定义，有合理默认值
+
+	inUse   atomic.Int64 // 使用中的实例数（指标统计）
+	waiting atomic.Int64 // 等待空闲实例的调用数（指标统计）
+	metrics *poolMetric  // 池的指标记录（未挂载Metrics时为nil）
+	logger  *slog.Logger // 结构化日志，nil表示不记录（见SetLogger）
 }
 
 // PoolConfig 池的完整配置结构体，覆盖所有可配置参数
@@ -53,10 +59,6 @@ var defaultPoolConfig = PoolConfig{
 	IsUseSemaphore:           true, // 信号量是核心优化，无论什么场景都建议开启
 }
 
-// 错误定义：获取池实例超时
-// 触发场景：并发数超过池大小，且重试超时仍未获取到空闲实例
-var errNoFreeClinetFind = fmt.Errorf("time out,no free client find")
-
 // ---------------------- 内部工具方法：动态初始化快速配置 ----------------------
 // initFastConfigByTimeout 根据传入的请求超时时间，动态初始化快连接配置
 // 参数：timeoutSecond - Pool初始化时传入的请求超时时间（秒）
@@ -185,29 +187,12 @@ func NewGatherUtilPoolWithConfig(headers map[string]string, proxyURL string, tim
 //	redirectURL: 重定向地址（内网API通常无重定向，为空）
 //	err:        错误信息（超时/连接失败/获取实例失败等）
 func (p *Pool) Get(URL, refererURL string) (html, redirectURL string, err error) {
-	// 创建获取实例的超时上下文：超时时间=TimeoutSecond
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(p.config.TimeoutSecond)*time.Second)
-	defer cancel() // 函数结束时释放上下文，避免内存泄漏
-
-	// 信号量控制：获取一个可用实例（无可用则等待，超时则返回错误）
-	if p.config.IsUseSemaphore {
-		select {
-		case <-p.sem:
-			defer func() { p.sem <- struct{}{} }() // 函数结束时释放实例，归还信号量
-		case <-ctx.Done():
-			return "", "", errNoFreeClinetFind
-		}
-	}
-
-	// 查找空闲实例下标
-	poolIndex := p.getPoolIndex(ctx)
-	if poolIndex == -1 {
-		return "", "", errNoFreeClinetFind
-	}
-	defer p.unUsed.Store(poolIndex, true) // 函数结束时标记实例为空闲
+	return p.GetUtilContext(context.Background(), URL, refererURL, "")
+}
 
-	// 调用GatherStruct的GetUtil方法发送请求
-	return p.pool[poolIndex].GetUtil(URL, refererURL, "")
+// GetContext 同Get，ctx同时作用于“等待空闲实例”和“请求执行”两个阶段
+func (p *Pool) GetContext(ctx context.Context, URL, refererURL string) (html, redirectURL string, err error) {
+	return p.GetUtilContext(ctx, URL, refererURL, "")
 }
 
 // ---------------------- 核心请求方法：GetUtil（带Cookie） ----------------------
@@ -220,25 +205,20 @@ func (p *Pool) Get(URL, refererURL string) (html, redirectURL string, err error)
 //
 // 返回值：和Get方法一致
 func (p *Pool) GetUtil(URL, refererURL, cookies string) (html, redirectURL string, err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(p.config.TimeoutSecond)*time.Second)
-	defer cancel()
-
-	if p.config.IsUseSemaphore {
-		select {
-		case <-p.sem:
-			defer func() { p.sem <- struct{}{} }()
-		case <-ctx.Done():
-			return "", "", errNoFreeClinetFind
-		}
-	}
+	return p.GetUtilContext(context.Background(), URL, refererURL, cookies)
+}
 
-	poolIndex := p.getPoolIndex(ctx)
-	if poolIndex == -1 {
-		return "", "", errNoFreeClinetFind
+// GetUtilContext 同GetUtil，ctx同时作用于“等待空闲实例”和“请求执行”两个阶段
+func (p *Pool) GetUtilContext(ctx context.Context, URL, refererURL, cookies string) (html, redirectURL string, err error) {
+	// 获取空闲实例（无可用则等待，超时或ctx取消则返回错误），函数结束时归还
+	ga, release, err := p.acquire(ctx)
+	if err != nil {
+		return "", "", err
 	}
-	defer p.unUsed.Store(poolIndex, true)
+	defer release()
 
-	return p.pool[poolIndex].GetUtil(URL, refererURL, cookies)
+	// 调用GatherStruct的GetUtilContext方法发送请求
+	return ga.GetUtilContext(ctx, URL, refererURL, cookies)
 }
 
 // ---------------------- 核心请求方法：Post（无Cookie） ----------------------
@@ -251,25 +231,12 @@ func (p *Pool) GetUtil(URL, refererURL, cookies string) (html, redirectURL strin
 //
 // 返回值：和Get方法一致
 func (p *Pool) Post(URL, refererURL string, postMap map[string]string) (html, redirectURL string, err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(p.config.TimeoutSecond)*time.Second)
-	defer cancel()
-
-	if p.config.IsUseSemaphore {
-		select {
-		case <-p.sem:
-			defer func() { p.sem <- struct{}{} }()
-		case <-ctx.Done():
-			return "", "", errNoFreeClinetFind
-		}
-	}
-
-	poolIndex := p.getPoolIndex(ctx)
-	if poolIndex == -1 {
-		return "", "", errNoFreeClinetFind
-	}
-	defer p.unUsed.Store(poolIndex, true)
+	return p.PostUtilContext(context.Background(), URL, refererURL, "", postMap)
+}
 
-	return p.pool[poolIndex].Post(URL, refererURL, postMap)
+// PostContext 同Post，ctx同时作用于“等待空闲实例”和“请求执行”两个阶段
+func (p *Pool) PostContext(ctx context.Context, URL, refererURL string, postMap map[string]string) (html, redirectURL string, err error) {
+	return p.PostUtilContext(ctx, URL, refererURL, "", postMap)
 }
 
 // ---------------------- 核心请求方法：PostUtil（带Cookie） ----------------------
@@ -283,25 +250,214 @@ func (p *Pool) Post(URL, refererURL string, postMap map[string]string) (html, re
 //
 // 返回值：和Get方法一致
 func (p *Pool) PostUtil(URL, refererURL, cookies string, postMap map[string]string) (html, redirectURL string, err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(p.config.TimeoutSecond)*time.Second)
-	defer cancel()
+	return p.PostUtilContext(context.Background(), URL, refererURL, cookies, postMap)
+}
+
+// PostUtilContext 同PostUtil，ctx同时作用于“等待空闲实例”和“请求执行”两个阶段
+func (p *Pool) PostUtilContext(ctx context.Context, URL, refererURL, cookies string, postMap map[string]string) (html, redirectURL string, err error) {
+	ga, release, err := p.acquire(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	defer release()
+
+	return ga.PostUtilContext(ctx, URL, refererURL, cookies, postMap)
+}
+
+// ---------------------- 完整响应方法：GetResponse/PostResponse/PostXxxResponse/MethodResponse/Do ----------------------
+// GetResponse 发送GET请求并返回完整的Response对象（状态码、响应头、原始字节、跳转链路）
+// 参数与GetUtil一致，非2xx状态码时resp与err一同返回
+func (p *Pool) GetResponse(URL, refererURL, cookies string) (*Response, error) {
+	return p.GetResponseContext(context.Background(), URL, refererURL, cookies)
+}
+
+// GetResponseContext 同GetResponse，ctx同时作用于“等待空闲实例”和“请求执行”两个阶段
+func (p *Pool) GetResponseContext(ctx context.Context, URL, refererURL, cookies string) (*Response, error) {
+	ga, release, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return ga.GetResponseContext(ctx, URL, refererURL, cookies)
+}
+
+// PostResponse 发送表单POST请求并返回完整的Response对象
+// 参数与PostUtil一致，非2xx状态码时resp与err一同返回
+func (p *Pool) PostResponse(URL, refererURL, cookies string, postMap map[string]string) (*Response, error) {
+	return p.PostResponseContext(context.Background(), URL, refererURL, cookies, postMap)
+}
+
+// PostResponseContext 同PostResponse，ctx同时作用于“等待空闲实例”和“请求执行”两个阶段
+func (p *Pool) PostResponseContext(ctx context.Context, URL, refererURL, cookies string, postMap map[string]string) (*Response, error) {
+	ga, release, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return ga.PostResponseContext(ctx, URL, refererURL, cookies, postMap)
+}
+
+// PostBytesResponse POST二进制数据，返回完整的Response对象
+// 参数与PostBytes一致，非2xx状态码时resp与err一同返回
+func (p *Pool) PostBytesResponse(URL, refererURL, cookies string, postBytes []byte) (*Response, error) {
+	return p.PostBytesResponseContext(context.Background(), URL, refererURL, cookies, postBytes)
+}
+
+// PostBytesResponseContext 同PostBytesResponse，ctx同时作用于“等待空闲实例”和“请求执行”两个阶段
+func (p *Pool) PostBytesResponseContext(ctx context.Context, URL, refererURL, cookies string, postBytes []byte) (*Response, error) {
+	ga, release, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return ga.PostBytesResponseContext(ctx, URL, refererURL, cookies, postBytes)
+}
+
+// PostXMLResponse 以XML的方式post数据，返回完整的Response对象
+// 参数与PostXMLUtil一致，非2xx状态码时resp与err一同返回
+func (p *Pool) PostXMLResponse(URL, refererURL, cookies, postXML string) (*Response, error) {
+	return p.PostXMLResponseContext(context.Background(), URL, refererURL, cookies, postXML)
+}
+
+// PostXMLResponseContext 同PostXMLResponse，ctx同时作用于“等待空闲实例”和“请求执行”两个阶段
+func (p *Pool) PostXMLResponseContext(ctx context.Context, URL, refererURL, cookies, postXML string) (*Response, error) {
+	ga, release, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return ga.PostXMLResponseContext(ctx, URL, refererURL, cookies, postXML)
+}
+
+// PostJsonResponse 以json的方式post数据，返回完整的Response对象
+// 参数与PostJsonUtil一致，非2xx状态码时resp与err一同返回
+func (p *Pool) PostJsonResponse(URL, refererURL, cookies, postJson string) (*Response, error) {
+	return p.PostJsonResponseContext(context.Background(), URL, refererURL, cookies, postJson)
+}
+
+// PostJsonResponseContext 同PostJsonResponse，ctx同时作用于“等待空闲实例”和“请求执行”两个阶段
+func (p *Pool) PostJsonResponseContext(ctx context.Context, URL, refererURL, cookies, postJson string) (*Response, error) {
+	ga, release, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return ga.PostJsonResponseContext(ctx, URL, refererURL, cookies, postJson)
+}
+
+// PostMultipartFormDataResponse multipart/form-data方式POST数据，返回完整的Response对象
+// 参数与PostMultipartFormDataUtil一致，非2xx状态码时resp与err一同返回
+func (p *Pool) PostMultipartFormDataResponse(URL, refererURL, cookies, boundary string, postValueMap map[string]string, postFileMap map[string]MultipartPostFile) (*Response, error) {
+	return p.PostMultipartFormDataResponseContext(context.Background(), URL, refererURL, cookies, boundary, postValueMap, postFileMap)
+}
+
+// PostMultipartFormDataResponseContext 同PostMultipartFormDataResponse，ctx同时作用于“等待空闲实例”和“请求执行”两个阶段
+func (p *Pool) PostMultipartFormDataResponseContext(ctx context.Context, URL, refererURL, cookies, boundary string, postValueMap map[string]string, postFileMap map[string]MultipartPostFile) (*Response, error) {
+	ga, release, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return ga.PostMultipartFormDataResponseContext(ctx, URL, refererURL, cookies, boundary, postValueMap, postFileMap)
+}
+
+// MethodResponse 以指定方法（HEAD、PUT、DELETE等）发送请求，返回完整的Response对象
+// 参数与MethodUtil一致，非2xx状态码时resp与err一同返回
+func (p *Pool) MethodResponse(method, URL, refererURL, cookies string) (*Response, error) {
+	return p.MethodResponseContext(context.Background(), method, URL, refererURL, cookies)
+}
+
+// MethodResponseContext 同MethodResponse，ctx同时作用于“等待空闲实例”和“请求执行”两个阶段
+func (p *Pool) MethodResponseContext(ctx context.Context, method, URL, refererURL, cookies string) (*Response, error) {
+	ga, release, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return ga.MethodResponseContext(ctx, method, URL, refererURL, cookies)
+}
+
+// Do 使用池内空闲实例执行调用方自行构建的请求，返回完整的Response对象
+// 等待空闲实例阶段同样遵循req.Context()的取消/超时
+func (p *Pool) Do(req *http.Request) (*Response, error) {
+	ga, release, err := p.acquire(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return ga.Do(req)
+}
+
+// ---------------------- 内部工具方法：获取/归还池实例 ----------------------
+// acquire 获取一个空闲实例，返回实例及归还函数
+// 核心逻辑：
+// 1. 基于调用方ctx创建获取实例的超时上下文：超时时间=TimeoutSecond
+// 2. 信号量控制：获取一个可用实例（无可用则等待，超时则返回错误）
+// 3. 查找空闲实例下标，release负责标记空闲并归还信号量
+// 4. 调用方ctx取消时立即返回ctx.Err()，池等待超时则返回ErrPoolExhausted
+func (p *Pool) acquire(parent context.Context) (ga *GatherStruct, release func(), err error) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(parent, time.Duration(p.config.TimeoutSecond)*time.Second)
+	defer cancel() // 获取结束即释放上下文，避免内存泄漏
+
+	p.locker.Lock()
+	pm, logger := p.metrics, p.logger
+	p.locker.Unlock()
+	start := time.Now()
+	p.waiting.Add(1)
+	defer func() {
+		p.waiting.Add(-1)
+		if pm != nil {
+			pm.metrics.observePoolWait(pm, time.Since(start), err == nil)
+		}
+		if err != nil {
+			p.logAcquireFailure(logger, time.Since(start), err)
+		}
+	}()
 
 	if p.config.IsUseSemaphore {
 		select {
 		case <-p.sem:
-			defer func() { p.sem <- struct{}{} }()
 		case <-ctx.Done():
-			return "", "", errNoFreeClinetFind
+			return nil, nil, acquireError(parent)
 		}
 	}
 
 	poolIndex := p.getPoolIndex(ctx)
 	if poolIndex == -1 {
-		return "", "", errNoFreeClinetFind
+		if p.config.IsUseSemaphore {
+			p.sem <- struct{}{}
+		}
+		return nil, nil, acquireError(parent)
+	}
+
+	p.inUse.Add(1)
+	release = func() {
+		p.inUse.Add(-1)
+		p.unUsed.Store(poolIndex, true) // 标记实例为空闲
+		if p.config.IsUseSemaphore {
+			p.sem <- struct{}{} // 归还信号量
+		}
 	}
-	defer p.unUsed.Store(poolIndex, true)
+	return p.pool[poolIndex], release, nil
+}
 
-	return p.pool[poolIndex].PostUtil(URL, refererURL, cookies, postMap)
+// acquireError 区分获取实例失败的原因：调用方取消优先返回ctx错误，否则为池等待超时
+func acquireError(parent context.Context) error {
+	if err := parent.Err(); err != nil {
+		return err
+	}
+	return errNoFreeClinetFind
 }
 
 // ---------------------- 内部工具方法：查找空闲实例下标 ----------------------
@@ -341,8 +497,12 @@ func (p *Pool) getPoolIndex(ctx context.Context) int {
 			break
 		}
 
-		// 未找到则休眠重试间隔，避免CPU空转
-		time.Sleep(time.Duration(p.config.RetryIntervalMs) * time.Millisecond)
+		// 未找到则休眠重试间隔，避免CPU空转；休眠期间ctx取消则立即返回
+		select {
+		case <-ctx.Done():
+			return -1
+		case <-time.After(time.Duration(p.config.RetryIntervalMs) * time.Millisecond):
+		}
 	}
 
 	return poolIndex
@@ -2,8 +2,13 @@
 package gather
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"sync"
 	"sync/atomic" // 新增：导入原子操作包
@@ -474,3 +479,71 @@ func TestPool_PostUtil(t *testing.T) {
 		t.Errorf("自定义Cookie未生效，返回Cookie：%v", cookies)
 	}
 }
+
+// TestPool_GetContext 测试调用方ctx取消时，排队等待空闲实例的请求立即返回
+func TestPool_GetContext(t *testing.T) {
+	customConfig := PoolConfig{
+		TimeoutSecond:   10,
+		RetryIntervalMs: 100,
+		IsUseSemaphore:  true,
+	}
+	pool := NewGatherUtilPoolWithConfig(make(map[string]string), "", 10, false, 1, customConfig)
+
+	// 占用唯一实例，令后续请求进入排队
+	_, release, err := pool.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("占用实例失败：%v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		cancel()
+	}()
+
+	startTime := time.Now()
+	_, _, err = pool.GetContext(ctx, testBaseURL+"/get", "")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("错误类型不符合预期：期望context.Canceled，实际%v", err)
+	}
+	if elapsed := time.Since(startTime); elapsed > 2*time.Second {
+		t.Errorf("ctx取消后排队请求未及时返回，耗时%v", elapsed)
+	}
+}
+
+// TestPool_Response 测试Pool的各类完整响应方法均经过池内实例执行
+func TestPool_Response(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s|%s", r.Method, body)
+	}))
+	defer server.Close()
+	pool := NewGatherUtilPool(make(map[string]string), "", 10, false, 1)
+
+	check := func(name string, resp *Response, err error, method, body string) {
+		t.Helper()
+		if err != nil || !strings.HasPrefix(string(resp.Body), method+"|") || !strings.HasSuffix(string(resp.Body), body) {
+			t.Errorf("%s结果错误：%v, %v", name, resp, err)
+		}
+	}
+	resp, err := pool.PostBytesResponse(server.URL, "", "", []byte("raw"))
+	check("PostBytesResponse", resp, err, "POST", "|raw")
+	resp, err = pool.PostXMLResponse(server.URL, "", "", "<a/>")
+	check("PostXMLResponse", resp, err, "POST", "|<a/>")
+	resp, err = pool.PostJsonResponse(server.URL, "", "", `{"a":1}`)
+	check("PostJsonResponse", resp, err, "POST", `|{"a":1}`)
+	resp, err = pool.PostMultipartFormDataResponse(server.URL, "", "", "", map[string]string{"k": "v"}, nil)
+	check("PostMultipartFormDataResponse", resp, err, "POST", "--\r\n")
+	resp, err = pool.MethodResponse(http.MethodPut, server.URL, "", "")
+	check("MethodResponse", resp, err, "PUT", "|")
+
+	// 占用唯一实例时，ctx取消后立即返回
+	_, release, _ := pool.acquire(context.Background())
+	defer release()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pool.PostJsonResponseContext(ctx, server.URL, "", "", "{}"); !errors.Is(err, context.Canceled) {
+		t.Errorf("等待空闲实例时ctx取消应返回context.Canceled，实际：%v", err)
+	}
+}
@@ -8,6 +8,7 @@ package gather
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"mime/multipart"
 	"net/http"
@@ -87,34 +88,60 @@ postMap["password"] = "abcdef"
 html, redirectURL, err := ga.PostUtil("https://weibo.com/xxxxx", "",cookies, postMap)
 */
 func (g *GatherStruct) PostUtil(URL, refererURL, cookies string, postMap map[string]string) (html, redirectURL string, err error) {
+	return g.PostUtilContext(context.Background(), URL, refererURL, cookies, postMap)
+}
+
+// PostContext 同Post，支持通过ctx取消进行中的请求
+func (g *GatherStruct) PostContext(ctx context.Context, URL, refererURL string, postMap map[string]string) (html, redirectURL string, err error) {
+	return g.PostUtilContext(ctx, URL, refererURL, "", postMap)
+}
+
+// PostUtilContext 同PostUtil，支持通过ctx取消进行中的请求
+func (g *GatherStruct) PostUtilContext(ctx context.Context, URL, refererURL, cookies string, postMap map[string]string) (html, redirectURL string, err error) {
 	g.locker.Lock()
 	defer g.locker.Unlock()
 
-	// 构建POST表单数据
-	postValues := url.Values{}
-	for k, v := range postMap {
-		postValues.Set(k, v)
+	req, err := g.newPostFormRequest(ctx, URL, refererURL, cookies, postMap)
+	if err != nil {
+		return "", "", err
 	}
-	postDataBytes := []byte(postValues.Encode())
-	postBytesReader := bytes.NewReader(postDataBytes)
+	return g.request(req)
+}
 
-	// 规范Content-Type：移除多余的param=value，补充utf-8
-	if _, exist := g.safeHeaders.Load("Content-Type"); !exist {
-		g.safeHeaders.Store("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
-	}
+// PostResponse 以表单方式POST数据，返回完整的Response对象
+// 参数与PostUtil一致，非2xx状态码时resp与err一同返回
+func (g *GatherStruct) PostResponse(URL, refererURL, cookies string, postMap map[string]string) (*Response, error) {
+	return g.PostResponseContext(context.Background(), URL, refererURL, cookies, postMap)
+}
+
+// PostResponseContext 同PostResponse，支持通过ctx取消进行中的请求
+func (g *GatherStruct) PostResponseContext(ctx context.Context, URL, refererURL, cookies string, postMap map[string]string) (*Response, error) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
 
-	req, err := g.newHttpRequest("POST", URL, refererURL, cookies, postBytesReader)
+	req, err := g.newPostFormRequest(ctx, URL, refererURL, cookies, postMap)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
-	return g.request(req)
+	return g.do(req)
 }
 
 // PostUtilReq 构建POST请求对象（不执行请求）
 func (g *GatherStruct) PostUtilReq(URL, refererURL, cookies string, postMap map[string]string) (*http.Request, error) {
+	return g.PostUtilReqContext(context.Background(), URL, refererURL, cookies, postMap)
+}
+
+// PostUtilReqContext 同PostUtilReq，构建的请求绑定ctx，执行时可通过ctx取消
+func (g *GatherStruct) PostUtilReqContext(ctx context.Context, URL, refererURL, cookies string, postMap map[string]string) (*http.Request, error) {
 	g.locker.Lock()
 	defer g.locker.Unlock()
 
+	return g.newPostFormRequest(ctx, URL, refererURL, cookies, postMap)
+}
+
+// newPostFormRequest 构建表单POST请求（调用方需持有g.locker）
+func (g *GatherStruct) newPostFormRequest(ctx context.Context, URL, refererURL, cookies string, postMap map[string]string) (*http.Request, error) {
+	// 构建POST表单数据
 	postValues := url.Values{}
 	for k, v := range postMap {
 		postValues.Set(k, v)
@@ -122,20 +149,52 @@ func (g *GatherStruct) PostUtilReq(URL, refererURL, cookies string, postMap map[
 	postDataBytes := []byte(postValues.Encode())
 	postBytesReader := bytes.NewReader(postDataBytes)
 
-	// 规范Content-Type
+	// 规范Content-Type：移除多余的param=value，补充utf-8
 	if _, exist := g.safeHeaders.Load("Content-Type"); !exist {
 		g.safeHeaders.Store("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
 	}
 
-	return g.newHttpRequest("POST", URL, refererURL, cookies, postBytesReader)
+	return g.newHttpRequest(ctx, "POST", URL, refererURL, cookies, postBytesReader)
 }
 
 // POST二进制数据
 // 补充说明：默认Content-Type为application/octet-stream，可通过safeHeaders自定义
 func (g *GatherStruct) PostBytes(URL, refererURL, cookies string, postBytes []byte) (html, redirectURL string, err error) {
+	return g.PostBytesContext(context.Background(), URL, refererURL, cookies, postBytes)
+}
+
+// PostBytesContext 同PostBytes，支持通过ctx取消进行中的请求
+func (g *GatherStruct) PostBytesContext(ctx context.Context, URL, refererURL, cookies string, postBytes []byte) (html, redirectURL string, err error) {
 	g.locker.Lock()
 	defer g.locker.Unlock()
 
+	req, err := g.newPostBytesRequest(ctx, URL, refererURL, cookies, postBytes)
+	if err != nil {
+		return "", "", err
+	}
+	return g.request(req)
+}
+
+// PostBytesResponse POST二进制数据，返回完整的Response对象
+// 参数与PostBytes一致，非2xx状态码时resp与err一同返回
+func (g *GatherStruct) PostBytesResponse(URL, refererURL, cookies string, postBytes []byte) (*Response, error) {
+	return g.PostBytesResponseContext(context.Background(), URL, refererURL, cookies, postBytes)
+}
+
+// PostBytesResponseContext 同PostBytesResponse，支持通过ctx取消进行中的请求
+func (g *GatherStruct) PostBytesResponseContext(ctx context.Context, URL, refererURL, cookies string, postBytes []byte) (*Response, error) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+
+	req, err := g.newPostBytesRequest(ctx, URL, refererURL, cookies, postBytes)
+	if err != nil {
+		return nil, err
+	}
+	return g.do(req)
+}
+
+// newPostBytesRequest 构建二进制POST请求（调用方需持有g.locker）
+func (g *GatherStruct) newPostBytesRequest(ctx context.Context, URL, refererURL, cookies string, postBytes []byte) (*http.Request, error) {
 	postBytesReader := bytes.NewReader(postBytes)
 
 	// 为二进制POST设置默认Content-Type
@@ -143,11 +202,7 @@ func (g *GatherStruct) PostBytes(URL, refererURL, cookies string, postBytes []by
 		g.safeHeaders.Store("Content-Type", "application/octet-stream")
 	}
 
-	req, err := g.newHttpRequest("POST", URL, refererURL, cookies, postBytesReader)
-	if err != nil {
-		return "", "", err
-	}
-	return g.request(req)
+	return g.newHttpRequest(ctx, "POST", URL, refererURL, cookies, postBytesReader)
 }
 
 /*
@@ -180,19 +235,52 @@ postXML := `<?xml version="1.0" encoding="utf-8"?><login><user>ydg</user><passwo
 html, redirectURL, err := ga.PostXMLUtil(`https://weibo.com/xxxxx`, "", cookies, postXML)
 */
 func (g *GatherStruct) PostXMLUtil(URL, refererURL, cookies, postXML string) (html, redirectURL string, err error) {
+	return g.PostXMLUtilContext(context.Background(), URL, refererURL, cookies, postXML)
+}
+
+// PostXMLContext 同PostXML，支持通过ctx取消进行中的请求
+func (g *GatherStruct) PostXMLContext(ctx context.Context, URL, refererURL, postXML string) (html, redirectURL string, err error) {
+	return g.PostXMLUtilContext(ctx, URL, refererURL, "", postXML)
+}
+
+// PostXMLUtilContext 同PostXMLUtil，支持通过ctx取消进行中的请求
+func (g *GatherStruct) PostXMLUtilContext(ctx context.Context, URL, refererURL, cookies, postXML string) (html, redirectURL string, err error) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+
+	req, err := g.newPostXMLRequest(ctx, URL, refererURL, cookies, postXML)
+	if err != nil {
+		return "", "", err
+	}
+	return g.request(req)
+}
+
+// PostXMLResponse 以XML的方式post数据，返回完整的Response对象
+// 参数与PostXMLUtil一致，非2xx状态码时resp与err一同返回
+func (g *GatherStruct) PostXMLResponse(URL, refererURL, cookies, postXML string) (*Response, error) {
+	return g.PostXMLResponseContext(context.Background(), URL, refererURL, cookies, postXML)
+}
+
+// PostXMLResponseContext 同PostXMLResponse，支持通过ctx取消进行中的请求
+func (g *GatherStruct) PostXMLResponseContext(ctx context.Context, URL, refererURL, cookies, postXML string) (*Response, error) {
 	g.locker.Lock()
 	defer g.locker.Unlock()
 
+	req, err := g.newPostXMLRequest(ctx, URL, refererURL, cookies, postXML)
+	if err != nil {
+		return nil, err
+	}
+	return g.do(req)
+}
+
+// newPostXMLRequest 构建XML POST请求（调用方需持有g.locker）
+func (g *GatherStruct) newPostXMLRequest(ctx context.Context, URL, refererURL, cookies, postXML string) (*http.Request, error) {
 	// 规范XML的Content-Type，补充utf-8
 	if _, exist := g.safeHeaders.Load("Content-Type"); !exist {
 		g.safeHeaders.Store("Content-Type", "application/xml; charset=utf-8")
 	}
 
-	req, err := g.newHttpRequest("POST", URL, refererURL, cookies, strings.NewReader(postXML))
-	if err != nil {
-		return "", "", err
-	}
-	return g.request(req)
+	return g.newHttpRequest(ctx, "POST", URL, refererURL, cookies, strings.NewReader(postXML))
 }
 
 /*
@@ -226,19 +314,52 @@ postJson := `{"user":"ydg","password":"abcdef"}`
 html, redirectURL, err := ga.PostJsonUtil(`https://weibo.com/xxxxx`, "", cookies, postJson)
 */
 func (g *GatherStruct) PostJsonUtil(URL, refererURL, cookies, postJson string) (html, redirectURL string, err error) {
+	return g.PostJsonUtilContext(context.Background(), URL, refererURL, cookies, postJson)
+}
+
+// PostJsonContext 同PostJson，支持通过ctx取消进行中的请求
+func (g *GatherStruct) PostJsonContext(ctx context.Context, URL, refererURL, postJson string) (html, redirectURL string, err error) {
+	return g.PostJsonUtilContext(ctx, URL, refererURL, "", postJson)
+}
+
+// PostJsonUtilContext 同PostJsonUtil，支持通过ctx取消进行中的请求
+func (g *GatherStruct) PostJsonUtilContext(ctx context.Context, URL, refererURL, cookies, postJson string) (html, redirectURL string, err error) {
 	g.locker.Lock()
 	defer g.locker.Unlock()
 
+	req, err := g.newPostJsonRequest(ctx, URL, refererURL, cookies, postJson)
+	if err != nil {
+		return "", "", err
+	}
+	return g.request(req)
+}
+
+// PostJsonResponse 以json的方式post数据，返回完整的Response对象
+// 参数与PostJsonUtil一致，非2xx状态码时resp与err一同返回（便于读取接口返回的JSON错误详情）
+func (g *GatherStruct) PostJsonResponse(URL, refererURL, cookies, postJson string) (*Response, error) {
+	return g.PostJsonResponseContext(context.Background(), URL, refererURL, cookies, postJson)
+}
+
+// PostJsonResponseContext 同PostJsonResponse，支持通过ctx取消进行中的请求
+func (g *GatherStruct) PostJsonResponseContext(ctx context.Context, URL, refererURL, cookies, postJson string) (*Response, error) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+
+	req, err := g.newPostJsonRequest(ctx, URL, refererURL, cookies, postJson)
+	if err != nil {
+		return nil, err
+	}
+	return g.do(req)
+}
+
+// newPostJsonRequest 构建JSON POST请求（调用方需持有g.locker）
+func (g *GatherStruct) newPostJsonRequest(ctx context.Context, URL, refererURL, cookies, postJson string) (*http.Request, error) {
 	// 规范JSON的Content-Type，补充utf-8
 	if _, exist := g.safeHeaders.Load("Content-Type"); !exist {
 		g.safeHeaders.Store("Content-Type", "application/json; charset=utf-8")
 	}
 
-	req, err := g.newHttpRequest("POST", URL, refererURL, cookies, strings.NewReader(postJson))
-	if err != nil {
-		return "", "", err
-	}
-	return g.request(req)
+	return g.newHttpRequest(ctx, "POST", URL, refererURL, cookies, strings.NewReader(postJson))
 }
 
 // MultipartPostFile multipart/form-data 上传文件的结构体（修正驼峰命名）
@@ -274,27 +395,76 @@ postFileMap: 上传文件参数（name->MultipartPostFile）
 4. 错误包装，便于问题定位。
 */
 func (g *GatherStruct) PostMultipartFormDataUtil(URL, refererURL, cookies, boundary string, postValueMap map[string]string, postFileMap map[string]MultipartPostFile) (html, redirectURL string, err error) {
+	return g.PostMultipartFormDataUtilContext(context.Background(), URL, refererURL, cookies, boundary, postValueMap, postFileMap)
+}
+
+// PostMultipartFormDataContext 同PostMultipartFormData，支持通过ctx取消进行中的请求
+func (g *GatherStruct) PostMultipartFormDataContext(ctx context.Context, URL, refererURL, boundary string, postValueMap map[string]string, postFileMap map[string]MultipartPostFile) (html, redirectURL string, err error) {
+	return g.PostMultipartFormDataUtilContext(ctx, URL, refererURL, "", boundary, postValueMap, postFileMap)
+}
+
+// PostMultipartFormDataUtilContext 同PostMultipartFormDataUtil，支持通过ctx取消进行中的请求（大文件上传时尤为有用）
+func (g *GatherStruct) PostMultipartFormDataUtilContext(ctx context.Context, URL, refererURL, cookies, boundary string, postValueMap map[string]string, postFileMap map[string]MultipartPostFile) (html, redirectURL string, err error) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+
+	req, err := g.newPostMultipartRequest(ctx, URL, refererURL, cookies, boundary, postValueMap, postFileMap)
+	if err != nil {
+		return "", "", err
+	}
+
+	// 执行请求并返回结果
+	html, redirectURL, err = g.request(req)
+	if err != nil {
+		return "", "", fmt.Errorf("执行multipart POST请求失败：%w", err)
+	}
+	return html, redirectURL, nil
+}
+
+// PostMultipartFormDataResponse multipart/form-data方式POST数据，返回完整的Response对象
+// 参数与PostMultipartFormDataUtil一致，非2xx状态码时resp与err一同返回
+func (g *GatherStruct) PostMultipartFormDataResponse(URL, refererURL, cookies, boundary string, postValueMap map[string]string, postFileMap map[string]MultipartPostFile) (*Response, error) {
+	return g.PostMultipartFormDataResponseContext(context.Background(), URL, refererURL, cookies, boundary, postValueMap, postFileMap)
+}
+
+// PostMultipartFormDataResponseContext 同PostMultipartFormDataResponse，支持通过ctx取消进行中的请求
+func (g *GatherStruct) PostMultipartFormDataResponseContext(ctx context.Context, URL, refererURL, cookies, boundary string, postValueMap map[string]string, postFileMap map[string]MultipartPostFile) (*Response, error) {
 	g.locker.Lock()
 	defer g.locker.Unlock()
 
+	req, err := g.newPostMultipartRequest(ctx, URL, refererURL, cookies, boundary, postValueMap, postFileMap)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := g.do(req)
+	if err != nil {
+		return resp, fmt.Errorf("执行multipart POST请求失败：%w", err)
+	}
+	return resp, nil
+}
+
+// newPostMultipartRequest 构建multipart/form-data POST请求（调用方需持有g.locker）
+func (g *GatherStruct) newPostMultipartRequest(ctx context.Context, URL, refererURL, cookies, boundary string, postValueMap map[string]string, postFileMap map[string]MultipartPostFile) (*http.Request, error) {
 	// 1. 初始化multipart writer
 	var body bytes.Buffer
 	writer := multipart.NewWriter(&body)
-	defer writer.Close()
+	// 注意：不能defer writer.Close()，第5步已显式Close，再次Close会向body追加结束边界，
+	// 导致请求体长度与ContentLength不一致
 
 	// 2. 设置自定义/默认boundary
 	if boundary == "" {
 		boundary = writer.Boundary() // 使用标准库生成的安全边界
 	} else {
 		if err := writer.SetBoundary(boundary); err != nil {
-			return "", "", fmt.Errorf("设置multipart边界失败：%w", err)
+			return nil, fmt.Errorf("设置multipart边界失败：%w", err)
 		}
 	}
 
 	// 3. 添加普通文本参数
 	for name, value := range postValueMap {
 		if err := writer.WriteField(name, value); err != nil {
-			return "", "", fmt.Errorf("添加文本参数[%s]失败：%w", name, err)
+			return nil, fmt.Errorf("添加文本参数[%s]失败：%w", name, err)
 		}
 	}
 
@@ -319,33 +489,27 @@ func (g *GatherStruct) PostMultipartFormDataUtil(URL, refererURL, cookies, bound
 		// 4.2 创建自定义Header的Part（替代CreateFormFile，确保Content-Type生效）
 		part, err := writer.CreatePart(header)
 		if err != nil {
-			return "", "", fmt.Errorf("创建文件Part[%s]失败：%w", name, err)
+			return nil, fmt.Errorf("创建文件Part[%s]失败：%w", name, err)
 		}
 
 		// 4.3 写入文件二进制内容
 		if _, err := part.Write(file.Content); err != nil {
-			return "", "", fmt.Errorf("写入文件[%s]内容失败：%w", file.FileName, err)
+			return nil, fmt.Errorf("写入文件[%s]内容失败：%w", file.FileName, err)
 		}
 	}
 
 	// 5. 完成multipart数据构建
 	if err := writer.Close(); err != nil {
-		return "", "", fmt.Errorf("关闭multipart writer失败：%w", err)
+		return nil, fmt.Errorf("关闭multipart writer失败：%w", err)
 	}
 
 	// 6. 设置请求的Content-Type（包含boundary）
 	g.safeHeaders.Store("Content-Type", writer.FormDataContentType())
 
 	// 7. 构建HTTP请求
-	req, err := g.newHttpRequest("POST", URL, refererURL, cookies, &body)
-	if err != nil {
-		return "", "", fmt.Errorf("构建POST请求失败：%w", err)
-	}
-
-	// 8. 执行请求并返回结果
-	html, redirectURL, err = g.request(req)
+	req, err := g.newHttpRequest(ctx, "POST", URL, refererURL, cookies, &body)
 	if err != nil {
-		return "", "", fmt.Errorf("执行multipart POST请求失败：%w", err)
+		return nil, fmt.Errorf("构建POST请求失败：%w", err)
 	}
-	return html, redirectURL, nil
+	return req, nil
 }
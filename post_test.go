@@ -2,9 +2,14 @@
 package gather
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -58,6 +63,28 @@ func TestGather_Post(t *testing.T) {
 	}
 }
 
+// TestGather_PostUtilReqContext 测试构建的POST请求绑定传入的ctx
+func TestGather_PostUtilReqContext(t *testing.T) {
+	ga := NewGather("chrome", false)
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := ga.PostUtilReqContext(ctx, testBaseURL+"/post", "", "", map[string]string{"user": "ydg"})
+	if err != nil || req.Context() != ctx || req.Method != http.MethodPost {
+		t.Fatalf("构建POST请求错误：%v, %v", req, err)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != "user=ydg" {
+		t.Errorf("请求体错误：%s", body)
+	}
+	cancel()
+	req, _ = ga.PostUtilReqContext(ctx, testBaseURL+"/post", "", "", map[string]string{"user": "ydg"})
+	if _, err := ga.Client.Do(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("ctx取消后执行请求应返回context.Canceled，实际：%v", err)
+	}
+	if req, _ := ga.PostUtilReq(testBaseURL+"/post", "", "", nil); req.Context() != context.Background() {
+		t.Errorf("PostUtilReq应使用context.Background()")
+	}
+}
+
 // TestGather_PostJson 测试JSON格式POST
 func TestGather_PostJson(t *testing.T) {
 	ga := NewGather("chrome", false)
@@ -213,6 +240,30 @@ func TestGather_PostMultipartFormData(t *testing.T) {
 	}
 }
 
+// TestGather_PostMultipartContentLength 测试multipart请求体只有一个结束边界，且长度与ContentLength一致
+func TestGather_PostMultipartContentLength(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%d %d %d", r.ContentLength, len(body), strings.Count(string(body), "--gather-boundary--"))
+	}))
+	defer ts.Close()
+
+	ga := NewGather("chrome", false)
+	html, _, err := ga.PostMultipartFormData(ts.URL, "", "gather-boundary",
+		map[string]string{"username": "ydg"},
+		map[string]MultipartPostFile{"avatar": {FileName: "test.png", ContentType: "image/png", Content: []byte("test image content")}})
+	if err != nil {
+		t.Fatalf("文件上传失败：%v", err)
+	}
+	var contentLength, bodyLength, closing int
+	if _, err := fmt.Sscanf(html, "%d %d %d", &contentLength, &bodyLength, &closing); err != nil {
+		t.Fatalf("解析返回结果失败：%v, %q", err, html)
+	}
+	if contentLength != bodyLength || closing != 1 {
+		t.Errorf("ContentLength=%d，实际请求体长度=%d，结束边界数=%d，期望长度一致且只有一个结束边界", contentLength, bodyLength, closing)
+	}
+}
+
 // TestGather_ConcurrentPOST 【普通POST高并发测试】验证协程安全（50协程）
 func TestGather_ConcurrentPOST(t *testing.T) {
 	if testing.Short() {
@@ -0,0 +1,300 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// embeddedPublicSuffixList 内置的公共后缀列表（https://publicsuffix.org/list/public_suffix_list.dat，MPL-2.0）
+// 更新方法：用新下载的文件替换data/public_suffix_list.dat后重新编译，或运行时通过LoadPublicSuffixList加载
+//
+//go:embed data/public_suffix_list.dat
+var embeddedPublicSuffixList []byte
+
+// PublicSuffixList 公共后缀列表（如com、com.cn、gov.cn、co.uk、github.io），
+// 用于拒绝作用于公共后缀的超级Cookie（如Domain=com.cn），以及计算注册域名（eTLD+1）
+// 支持官方格式的普通规则、通配规则（*.ck）和例外规则（!www.ck），国际化域名规则同时按Punycode形式匹配
+type PublicSuffixList struct {
+	rules      map[string]bool // 普通规则，如com.cn
+	wildcards  map[string]bool // 通配规则去掉"*."后的部分，如ck
+	exceptions map[string]bool // 例外规则去掉"!"后的部分，如www.ck
+}
+
+var (
+	defaultSuffixOnce     sync.Once
+	embeddedSuffixList    *PublicSuffixList
+	defaultSuffixOverride atomic.Pointer[PublicSuffixList]
+)
+
+// DefaultPublicSuffixList 返回默认的公共后缀列表：设置过SetDefaultPublicSuffixList时为设置的列表，否则为内置列表
+func DefaultPublicSuffixList() *PublicSuffixList {
+	if list := defaultSuffixOverride.Load(); list != nil {
+		return list
+	}
+	defaultSuffixOnce.Do(func() {
+		list, err := ParsePublicSuffixList(bytes.NewReader(embeddedPublicSuffixList))
+		if err != nil {
+			panic("FATAL: 内置公共后缀列表解析失败：" + err.Error())
+		}
+		embeddedSuffixList = list
+	})
+	return embeddedSuffixList
+}
+
+// SetDefaultPublicSuffixList 替换全局默认的公共后缀列表（nil恢复为内置列表），
+// 影响所有未单独设置列表的CookieJar以及跳转策略的同站判断
+// 示例：
+//
+//	list, err := gather.LoadPublicSuffixList("/usr/share/publicsuffix/public_suffix_list.dat")
+//	if err == nil {
+//	    gather.SetDefaultPublicSuffixList(list)
+//	}
+func SetDefaultPublicSuffixList(list *PublicSuffixList) {
+	defaultSuffixOverride.Store(list)
+}
+
+// LoadPublicSuffixList 从文件加载公共后缀列表（官方public_suffix_list.dat格式）
+func LoadPublicSuffixList(path string) (*PublicSuffixList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParsePublicSuffixList(f)
+}
+
+// ParsePublicSuffixList 解析官方格式的公共后缀列表：每行一条规则，取行首到第一个空白字符，"//"开头为注释
+// 可以只包含自定义的规则，不包含任何规则时返回错误
+func ParsePublicSuffixList(r io.Reader) (*PublicSuffixList, error) {
+	list := &PublicSuffixList{
+		rules:      make(map[string]bool),
+		wildcards:  make(map[string]bool),
+		exceptions: make(map[string]bool),
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			line = line[:i]
+		}
+		rule := strings.ToLower(strings.Trim(line, "."))
+		switch {
+		case strings.HasPrefix(rule, "!"):
+			list.add(list.exceptions, rule[1:])
+		case strings.HasPrefix(rule, "*."):
+			list.add(list.wildcards, rule[2:])
+		default:
+			list.add(list.rules, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if list.Len() == 0 {
+		return nil, errors.New("公共后缀列表中没有任何规则")
+	}
+	return list, nil
+}
+
+// add 添加规则，含非ASCII字符的规则同时添加其Punycode形式（URL中的主机名为Punycode）
+func (l *PublicSuffixList) add(set map[string]bool, rule string) {
+	if rule == "" {
+		return
+	}
+	set[rule] = true
+	if ascii := toPunycodeDomain(rule); ascii != rule {
+		set[ascii] = true
+	}
+}
+
+// Len 返回规则数量（国际化域名规则的Unicode与Punycode形式分别计数）
+func (l *PublicSuffixList) Len() int {
+	return len(l.rules) + len(l.wildcards) + len(l.exceptions)
+}
+
+// PublicSuffix 返回domain的公共后缀，如www.example.com.cn返回com.cn；没有匹配的规则时按默认规则"*"返回最后一段
+func (l *PublicSuffixList) PublicSuffix(domain string) string {
+	domain = strings.Trim(strings.ToLower(domain), ".")
+	// 例外规则优先：!www.ck表示www.ck不是公共后缀，其公共后缀为ck
+	for i := 0; ; {
+		if l.exceptions[domain[i:]] {
+			if dot := strings.IndexByte(domain[i:], '.'); dot >= 0 {
+				return domain[i+dot+1:]
+			}
+		}
+		dot := strings.IndexByte(domain[i:], '.')
+		if dot < 0 {
+			break
+		}
+		i += dot + 1
+	}
+	// 由长到短匹配普通规则和通配规则，第一个匹配的即为最长的公共后缀
+	for i := 0; ; {
+		candidate := domain[i:]
+		if l.rules[candidate] {
+			return candidate
+		}
+		dot := strings.IndexByte(candidate, '.')
+		if dot < 0 {
+			return candidate
+		}
+		if l.wildcards[candidate[dot+1:]] {
+			return candidate
+		}
+		i += dot + 1
+	}
+}
+
+// IsPublicSuffix 判断domain本身是否为公共后缀（如com、com.cn、github.io）
+func (l *PublicSuffixList) IsPublicSuffix(domain string) bool {
+	domain = strings.Trim(strings.ToLower(domain), ".")
+	return domain != "" && l.PublicSuffix(domain) == domain
+}
+
+// RegistrableDomain 返回domain的注册域名（公共后缀再加一段，即eTLD+1），如news.sina.com.cn返回sina.com.cn
+// domain本身为公共后缀时返回空字符串
+func (l *PublicSuffixList) RegistrableDomain(domain string) string {
+	domain = strings.Trim(strings.ToLower(domain), ".")
+	suffix := l.PublicSuffix(domain)
+	if len(domain) <= len(suffix) {
+		return ""
+	}
+	rest := domain[:len(domain)-len(suffix)-1]
+	if dot := strings.LastIndexByte(rest, '.'); dot >= 0 {
+		rest = rest[dot+1:]
+	}
+	return rest + "." + suffix
+}
+
+// toPunycodeDomain 把域名中含非ASCII字符的各段转为Punycode（xn--），纯ASCII域名原样返回
+func toPunycodeDomain(domain string) string {
+	if isASCII(domain) {
+		return domain
+	}
+	labels := strings.Split(domain, ".")
+	for i, label := range labels {
+		if !isASCII(label) {
+			labels[i] = "xn--" + punycodeEncode(label)
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+// isASCII 判断字符串是否只含ASCII字符
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// Punycode参数（RFC 3492第5节）
+const (
+	punycodeBase        = 36
+	punycodeTMin        = 1
+	punycodeTMax        = 26
+	punycodeSkew        = 38
+	punycodeDamp        = 700
+	punycodeInitialBias = 72
+	punycodeInitialN    = 128
+)
+
+// punycodeEncode RFC 3492第6.3节编码算法（不含xn--前缀）
+func punycodeEncode(label string) string {
+	runes := []rune(label)
+	var out []byte
+	for _, r := range runes {
+		if r < utf8.RuneSelf {
+			out = append(out, byte(r))
+		}
+	}
+	basic := len(out)
+	handled := basic
+	if basic > 0 {
+		out = append(out, '-')
+	}
+	n, delta, bias := rune(punycodeInitialN), 0, punycodeInitialBias
+	for handled < len(runes) {
+		// 找出尚未处理的最小码点
+		m := rune(utf8.MaxRune)
+		for _, r := range runes {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+		delta += int(m-n) * (handled + 1)
+		n = m
+		for _, r := range runes {
+			if r < n {
+				delta++
+			}
+			if r != n {
+				continue
+			}
+			q := delta
+			for k := punycodeBase; ; k += punycodeBase {
+				t := k - bias
+				if t < punycodeTMin {
+					t = punycodeTMin
+				} else if t > punycodeTMax {
+					t = punycodeTMax
+				}
+				if q < t {
+					break
+				}
+				out = append(out, punycodeDigit(t+(q-t)%(punycodeBase-t)))
+				q = (q - t) / (punycodeBase - t)
+			}
+			out = append(out, punycodeDigit(q))
+			bias = punycodeAdapt(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+	return string(out)
+}
+
+// punycodeAdapt RFC 3492第6.1节偏差调整
+func punycodeAdapt(delta, numPoints int, first bool) int {
+	if first {
+		delta /= punycodeDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+	k := 0
+	for delta > ((punycodeBase-punycodeTMin)*punycodeTMax)/2 {
+		delta /= punycodeBase - punycodeTMin
+		k += punycodeBase
+	}
+	return k + (punycodeBase-punycodeTMin+1)*delta/(delta+punycodeSkew)
+}
+
+// punycodeDigit 0-25对应a-z，26-35对应0-9
+func punycodeDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
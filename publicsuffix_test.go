@@ -0,0 +1,126 @@
+package gather
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPublicSuffixList 测试内置公共后缀列表的匹配规则
+func TestPublicSuffixList(t *testing.T) {
+	list := DefaultPublicSuffixList()
+	testCases := []struct{ domain, suffix, registrable string }{
+		{"www.example.com", "com", "example.com"},
+		{"news.sina.com.cn", "com.cn", "sina.com.cn"},
+		{"www.gov.cn", "gov.cn", "www.gov.cn"},
+		{"a.b.example.co.uk", "co.uk", "example.co.uk"},
+		{"user.github.io", "github.io", "user.github.io"},
+		{"com.cn", "com.cn", ""},
+		{"cn", "cn", ""},
+		{"www.example.ck", "example.ck", "www.example.ck"}, // 通配规则*.ck
+		{"www.ck", "ck", "www.ck"},                         // 例外规则!www.ck
+		{"example.unknowntld", "unknowntld", "example.unknowntld"},
+		{"WWW.Example.COM.", "com", "example.com"},
+		{"xn--fiqs8s", "xn--fiqs8s", ""},                            // 中国
+		{"www.xn--55qx5d.cn", "xn--55qx5d.cn", "www.xn--55qx5d.cn"}, // 公司.cn
+		{"公司.cn", "公司.cn", ""},
+	}
+	for _, tc := range testCases {
+		if got := list.PublicSuffix(tc.domain); got != tc.suffix {
+			t.Errorf("PublicSuffix(%s)=%s，期望%s", tc.domain, got, tc.suffix)
+		}
+		if got := list.RegistrableDomain(tc.domain); got != tc.registrable {
+			t.Errorf("RegistrableDomain(%s)=%s，期望%s", tc.domain, got, tc.registrable)
+		}
+	}
+	if !list.IsPublicSuffix(".gov.cn") || list.IsPublicSuffix("example.com") || list.IsPublicSuffix("") {
+		t.Errorf("IsPublicSuffix判断错误")
+	}
+}
+
+// TestPunycodeEncode 测试国际化域名的Punycode编码（RFC 3492示例及常见中文域名）
+func TestPunycodeEncode(t *testing.T) {
+	testCases := map[string]string{
+		"中国":      "xn--fiqs8s",
+		"公司.cn":   "xn--55qx5d.cn",
+		"bücher":  "xn--bcher-kva",
+		"example": "example",
+	}
+	for domain, want := range testCases {
+		if got := toPunycodeDomain(domain); got != want {
+			t.Errorf("toPunycodeDomain(%s)=%s，期望%s", domain, got, want)
+		}
+	}
+}
+
+// TestPublicSuffixList_Custom 测试加载自定义列表并替换默认列表
+func TestPublicSuffixList_Custom(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "suffix.dat")
+	os.WriteFile(path, []byte("// 自定义列表\ncom\nshop.example.com   注释\n*.internal\n!public.internal\n"), 0o600)
+	list, err := LoadPublicSuffixList(path)
+	if err != nil {
+		t.Fatalf("加载失败：%v", err)
+	}
+	if list.PublicSuffix("a.shop.example.com") != "shop.example.com" || list.PublicSuffix("a.b.internal") != "b.internal" ||
+		list.PublicSuffix("public.internal") != "internal" {
+		t.Errorf("自定义规则匹配错误")
+	}
+	if _, err := ParsePublicSuffixList(strings.NewReader("// 只有注释\n")); err == nil {
+		t.Errorf("没有规则时应返回错误")
+	}
+	if _, err := LoadPublicSuffixList(filepath.Join(t.TempDir(), "missing.dat")); err == nil {
+		t.Errorf("文件不存在时应返回错误")
+	}
+
+	// 替换默认列表影响跳转同站判断，nil恢复内置列表
+	SetDefaultPublicSuffixList(list)
+	if registrableDomain("a.shop.example.com") != "a.shop.example.com" {
+		t.Errorf("替换默认列表后应使用自定义规则")
+	}
+	SetDefaultPublicSuffixList(nil)
+	if registrableDomain("a.shop.example.com") != "example.com" {
+		t.Errorf("恢复后应使用内置列表")
+	}
+}
+
+// TestWebCookieJar_PublicSuffix 测试拒绝作用于公共后缀的超级Cookie
+func TestWebCookieJar_PublicSuffix(t *testing.T) {
+	jar := newWebCookieJar(false)
+	jar.SetCookies(mustParseURL(t, "http://www.example.com.cn/"), []*http.Cookie{
+		{Name: "super", Value: "1", Domain: "com.cn"},
+		{Name: "ok", Value: "2", Domain: "example.com.cn"},
+	})
+	jar.SetCookies(mustParseURL(t, "http://www.beijing.gov.cn/"), []*http.Cookie{{Name: "gov", Value: "3", Domain: ".gov.cn"}})
+	jar.SetCookies(mustParseURL(t, "https://user.github.io/"), []*http.Cookie{{Name: "pages", Value: "4", Domain: "github.io"}})
+	// Domain恰好等于公共后缀主机时按host-only处理
+	jar.SetCookies(mustParseURL(t, "http://github.io/"), []*http.Cookie{{Name: "self", Value: "5", Domain: "github.io"}})
+
+	if got := jarCookieString(t, jar, "http://other.com.cn/"); got != "" {
+		t.Errorf("公共后缀Cookie不应泄露到其他网站：%q", got)
+	}
+	if got := jarCookieString(t, jar, "http://www.example.com.cn/"); got != "ok=2" {
+		t.Errorf("注册域名Cookie应保留：%q", got)
+	}
+	if got := jarCookieString(t, jar, "http://www.shanghai.gov.cn/"); got != "" {
+		t.Errorf(".gov.cn Cookie应被拒绝：%q", got)
+	}
+	if got := jarCookieString(t, jar, "https://other.github.io/"); got != "" {
+		t.Errorf("github.io Cookie应被拒绝：%q", got)
+	}
+	if c, ok := jar.Get("github.io", "self"); !ok || c.Domain != "github.io" {
+		t.Errorf("Domain等于主机时应按host-only保存：%+v", c)
+	}
+	if _, err := jar.ImportCookieString(".com.cn", "a=1"); err == nil {
+		t.Errorf("不应允许导入公共后缀的域Cookie")
+	}
+
+	// 单独为jar设置列表
+	custom, _ := ParsePublicSuffixList(strings.NewReader("example.com.cn\n"))
+	jar.SetPublicSuffixList(custom)
+	jar.SetCookies(mustParseURL(t, "http://a.example.com.cn/"), []*http.Cookie{{Name: "blocked", Value: "1", Domain: "example.com.cn"}})
+	if _, ok := jar.Get("example.com.cn", "blocked"); ok {
+		t.Errorf("自定义列表中的公共后缀应被拒绝")
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HostLimitConfig 单个主机的限流参数，各项<=0表示不限制
+type HostLimitConfig struct {
+	RPS           float64       // 每秒允许的请求数（令牌生成速率）
+	Burst         int           // 令牌桶容量（允许的突发请求数），<=0时为1
+	MinDelay      time.Duration // 同一主机相邻两次请求的最小间隔
+	MaxConcurrent int           // 同一主机的最大并发请求数
+}
+
+// HostLimiter 按主机（host:port）限流的令牌桶限流器，并发安全
+// 同一个HostLimiter可以同时挂到多个GatherStruct和Pool上，所有挂载者共享同一套配额
+// 示例：
+//
+//	limiter := gather.NewHostLimiter(gather.HostLimitConfig{RPS: 2, Burst: 5, MaxConcurrent: 3})
+//	pool.SetRateLimiter(limiter) // 池内100个实例对同一主机最多3个并发、每秒2个请求
+//	ga.SetRateLimiter(limiter)   // 单实例也共享同一配额
+type HostLimiter struct {
+	mu        sync.Mutex
+	config    HostLimitConfig            // 默认配置
+	overrides map[string]HostLimitConfig // 按主机单独设置的配置
+	hosts     map[string]*hostBucket
+	lastSweep time.Time // 上次清理空闲主机的时间
+}
+
+// hostLimiterSweepInterval 清理空闲主机状态的间隔，避免采集大量主机时限流状态无限增长
+const hostLimiterSweepInterval = time.Minute
+
+// hostBucket 单个主机的限流状态
+type hostBucket struct {
+	config    HostLimitConfig
+	tokens    float64       // 当前令牌数（可为负，表示已被预约的令牌）
+	updated   time.Time     // 上次计算令牌的时间
+	nextStart time.Time     // 下一个请求最早可开始的时间（MinDelay约束）
+	inFlight  int           // 持有并发槽位（已放行、尚未release）的请求数
+	freed     chan struct{} // 有槽位归还或配置变更时关闭，唤醒等待槽位的请求，没有等待者时为nil
+	active    int           // 正在等待或持有配额（尚未release）的请求数，>0时不会被清理
+}
+
+// NewHostLimiter 创建按主机限流的限流器，config作为所有主机的默认配置
+func NewHostLimiter(config HostLimitConfig) *HostLimiter {
+	return &HostLimiter{
+		config:    config,
+		overrides: make(map[string]HostLimitConfig),
+		hosts:     make(map[string]*hostBucket),
+	}
+}
+
+// SetHostConfig 为指定主机单独设置限流参数（如按robots.txt的Crawl-delay放慢某个站点）
+// host格式与URL中的Host一致（如"www.example.com"或"127.0.0.1:8080"）
+// 已有该主机的状态时原地更新，进行中的请求继续占用并发槽位，新的MaxConcurrent立即生效
+func (l *HostLimiter) SetHostConfig(host string, config HostLimitConfig) {
+	host = strings.ToLower(host)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.overrides[host] = config
+	if b, ok := l.hosts[host]; ok {
+		b.setConfig(config, time.Now())
+	}
+}
+
+// bucket 获取（或创建）主机的限流状态，调用方需持有l.mu
+func (l *HostLimiter) bucket(host string) *hostBucket {
+	if b, ok := l.hosts[host]; ok {
+		return b
+	}
+	if now := time.Now(); now.Sub(l.lastSweep) >= hostLimiterSweepInterval {
+		l.sweep(now)
+	}
+	config, ok := l.overrides[host]
+	if !ok {
+		config = l.config
+	}
+	if config.Burst <= 0 {
+		config.Burst = 1
+	}
+	b := &hostBucket{config: config, tokens: float64(config.Burst), updated: time.Now()}
+	l.hosts[host] = b
+	return b
+}
+
+// setConfig 原地更新限流参数：按旧速率结算到now的令牌后切换配置，并唤醒等待槽位的请求重新判断，调用方需持有l.mu
+func (b *hostBucket) setConfig(config HostLimitConfig, now time.Time) {
+	if config.Burst <= 0 {
+		config.Burst = 1
+	}
+	if b.config.RPS > 0 {
+		b.tokens += now.Sub(b.updated).Seconds() * b.config.RPS
+	} else {
+		b.tokens = float64(config.Burst) // 之前不限速，令牌视为已满
+	}
+	b.tokens = min(b.tokens, float64(config.Burst))
+	b.updated = now
+	if config.MinDelay <= 0 {
+		b.nextStart = time.Time{}
+	}
+	b.config = config
+	b.wake()
+}
+
+// wake 唤醒所有等待并发槽位的请求，调用方需持有l.mu
+func (b *hostBucket) wake() {
+	if b.freed != nil {
+		close(b.freed)
+		b.freed = nil
+	}
+}
+
+// sweep 清理空闲的主机状态，调用方需持有l.mu
+// 只清理与新建状态等价的主机：没有进行中的请求、令牌已补满、最小间隔已过，清理后再次请求的行为不变
+func (l *HostLimiter) sweep(now time.Time) {
+	l.lastSweep = now
+	for host, b := range l.hosts {
+		if b.active > 0 || now.Before(b.nextStart) {
+			continue
+		}
+		if b.config.RPS > 0 && b.tokens+now.Sub(b.updated).Seconds()*b.config.RPS < float64(b.config.Burst) {
+			continue
+		}
+		delete(l.hosts, host)
+	}
+}
+
+// Wait 等待直到可以向host发起请求，返回的release必须在请求结束后调用（归还并发槽位）
+// ctx取消时立即返回ctx.Err()
+func (l *HostLimiter) Wait(ctx context.Context, host string) (release func(), err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	host = strings.ToLower(host)
+	l.mu.Lock()
+	b := l.bucket(host)
+	b.active++
+	l.mu.Unlock()
+	// done 请求结束或放弃等待时调用，之后该主机状态才可能被清理
+	done := func() {
+		l.mu.Lock()
+		b.active--
+		l.mu.Unlock()
+	}
+
+	// 1. 并发槽位：按当前配置判断，配置变更或有槽位归还时被唤醒重新判断
+	for {
+		l.mu.Lock()
+		if b.config.MaxConcurrent <= 0 || b.inFlight < b.config.MaxConcurrent {
+			b.inFlight++
+			l.mu.Unlock()
+			break
+		}
+		if b.freed == nil {
+			b.freed = make(chan struct{})
+		}
+		freed := b.freed
+		l.mu.Unlock()
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			done()
+			return nil, ctx.Err()
+		}
+	}
+	release = func() {
+		l.mu.Lock()
+		b.inFlight--
+		b.wake()
+		l.mu.Unlock()
+	}
+
+	// 2. 令牌与最小间隔：先预约开始时间，再在锁外等待
+	l.mu.Lock()
+	now := time.Now()
+	start := now
+	if b.config.RPS > 0 {
+		b.tokens += now.Sub(b.updated).Seconds() * b.config.RPS
+		if b.tokens > float64(b.config.Burst) {
+			b.tokens = float64(b.config.Burst)
+		}
+		b.updated = now
+		b.tokens--
+		if b.tokens < 0 {
+			start = now.Add(time.Duration(-b.tokens / b.config.RPS * float64(time.Second)))
+		}
+	}
+	prevNextStart := b.nextStart
+	if b.config.MinDelay > 0 {
+		if b.nextStart.After(start) {
+			start = b.nextStart
+		}
+		b.nextStart = start.Add(b.config.MinDelay)
+	}
+	reservedNextStart := b.nextStart
+	l.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			// 归还预约的令牌；之后没有其他请求预约时一并撤销最小间隔的预约
+			// （已有后续预约时保留，只会让后续请求多等，不会违反最小间隔）
+			l.mu.Lock()
+			if b.config.RPS > 0 {
+				b.tokens++
+			}
+			if b.config.MinDelay > 0 && b.nextStart.Equal(reservedNextStart) {
+				b.nextStart = prevNextStart
+			}
+			l.mu.Unlock()
+			release()
+			done()
+			return nil, ctx.Err()
+		}
+	}
+	var once sync.Once
+	slotRelease := release
+	return func() {
+		once.Do(func() {
+			slotRelease()
+			done()
+		})
+	}, nil
+}
+
+// limitedTransport 按每一跳的目标主机限流的Transport，由clientFor在挂载了限流器时安装
+// 跳转的每一跳（跳到其他主机或跳回同一主机）都单独等待配额，并发槽位在该跳的响应体关闭时归还
+type limitedTransport struct {
+	base    http.RoundTripper
+	limiter *HostLimiter
+}
+
+// RoundTrip 等待目标主机的配额后发出请求
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.limiter.Wait(req.Context(), req.URL.Host)
+	if err != nil {
+		// 不发出请求，按RoundTripper约定关闭请求体
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	// 第一跳的限流等待不计入耗时（跳转请求的Response字段为上一跳的响应）
+	if req.Response == nil {
+		if trace := connTraceFrom(req.Context()); trace != nil {
+			trace.begin()
+		}
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releaseOnClose 关闭响应体时归还限流器的并发槽位
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+// Close 关闭响应体并归还槽位
+func (b *releaseOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// SetRateLimiter 为实例挂载按主机限流器（nil表示取消限流）
+// 每次实际发出请求（含重试及跳转的每一跳）前都会按该跳的目标主机等待配额
+func (g *GatherStruct) SetRateLimiter(limiter *HostLimiter) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	g.limiter = limiter
+}
+
+// SetRateLimiter 为池内所有实例挂载同一个限流器，池内实例共享同一套主机配额
+func (p *Pool) SetRateLimiter(limiter *HostLimiter) {
+	for _, ga := range p.pool {
+		ga.SetRateLimiter(limiter)
+	}
+}
@@ -0,0 +1,301 @@
+package gather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newConcurrencyServer 记录同时处理中的最大请求数，每个请求处理delay时间
+func newConcurrencyServer(delay time.Duration) (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	current, peak := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		current++
+		peak = max(peak, current)
+		mu.Unlock()
+		time.Sleep(delay)
+		mu.Lock()
+		current--
+		mu.Unlock()
+		w.Write([]byte("ok"))
+	}))
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return peak
+	}
+}
+
+// TestHostLimiter_Wait 测试令牌桶速率、突发及最小间隔
+func TestHostLimiter_Wait(t *testing.T) {
+	// 突发5个立即放行，之后按每秒50个（20ms一个）放行
+	limiter := NewHostLimiter(HostLimitConfig{RPS: 50, Burst: 5})
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		release, err := limiter.Wait(context.Background(), "example.com")
+		if err != nil {
+			t.Fatalf("Wait失败：%v", err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond || elapsed > time.Second {
+		t.Errorf("10个请求（突发5）应耗时约100ms，实际：%v", elapsed)
+	}
+
+	// 不同主机互不影响
+	start = time.Now()
+	if release, err := limiter.Wait(context.Background(), "other.com"); err != nil {
+		t.Fatalf("Wait失败：%v", err)
+	} else {
+		release()
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("其他主机不应等待，实际：%v", elapsed)
+	}
+
+	// 最小间隔
+	limiter = NewHostLimiter(HostLimitConfig{MinDelay: 30 * time.Millisecond})
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		release, _ := limiter.Wait(context.Background(), "example.com")
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 55*time.Millisecond {
+		t.Errorf("3个请求间隔30ms应耗时约60ms，实际：%v", elapsed)
+	}
+
+	// 按主机单独配置
+	limiter.SetHostConfig("EXAMPLE.com", HostLimitConfig{})
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		release, _ := limiter.Wait(context.Background(), "example.com")
+		release()
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("单独取消限流后不应等待，实际：%v", elapsed)
+	}
+}
+
+// TestHostLimiter_Cancel 测试等待期间ctx取消
+func TestHostLimiter_Cancel(t *testing.T) {
+	limiter := NewHostLimiter(HostLimitConfig{RPS: 1, MaxConcurrent: 1})
+	release, err := limiter.Wait(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Wait失败：%v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Wait(ctx, "example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("并发槽位被占满时应等待至ctx到期，实际：%v", err)
+	}
+	release()
+	release() // 重复调用无副作用
+
+	// 令牌不足时ctx到期
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel2()
+	if _, err := limiter.Wait(ctx2, "example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("令牌不足时应等待至ctx到期，实际：%v", err)
+	}
+}
+
+// TestHostLimiter_CancelMinDelay 测试等待期间ctx取消时撤销最小间隔的预约
+func TestHostLimiter_CancelMinDelay(t *testing.T) {
+	limiter := NewHostLimiter(HostLimitConfig{MinDelay: 200 * time.Millisecond})
+	begin := time.Now()
+	if _, err := limiter.Wait(context.Background(), "example.com"); err != nil {
+		t.Fatalf("Wait失败：%v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Wait(ctx, "example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("最小间隔未到时应等待至ctx到期，实际：%v", err)
+	}
+	// 被取消的请求不占用间隔：下一个请求在第一个请求200ms后即可开始，而不是400ms
+	if _, err := limiter.Wait(context.Background(), "example.com"); err != nil {
+		t.Fatalf("Wait失败：%v", err)
+	}
+	if elapsed := time.Since(begin); elapsed < 190*time.Millisecond || elapsed > 350*time.Millisecond {
+		t.Errorf("取消的预约应撤销，实际等待：%v", elapsed)
+	}
+}
+
+// TestHostLimiter_Sweep 测试清理空闲主机状态，进行中及配额未恢复的主机保留
+func TestHostLimiter_Sweep(t *testing.T) {
+	limiter := NewHostLimiter(HostLimitConfig{RPS: 10, MinDelay: time.Second})
+	for i := 0; i < 1000; i++ {
+		release, err := limiter.Wait(context.Background(), fmt.Sprintf("host%d.example.com", i))
+		if err != nil {
+			t.Fatalf("Wait失败：%v", err)
+		}
+		release()
+	}
+	busy, _ := limiter.Wait(context.Background(), "busy.example.com")
+
+	limiter.mu.Lock()
+	limiter.sweep(time.Now()) // 令牌和最小间隔都未恢复，不能清理
+	recent := len(limiter.hosts)
+	limiter.sweep(time.Now().Add(time.Hour))
+	remain := len(limiter.hosts)
+	limiter.mu.Unlock()
+	if recent != 1001 {
+		t.Errorf("配额未恢复的主机不应清理，剩余%d个", recent)
+	}
+	if remain != 1 {
+		t.Errorf("应只保留进行中的主机，剩余%d个", remain)
+	}
+	busy()
+	limiter.mu.Lock()
+	limiter.sweep(time.Now().Add(time.Hour))
+	remain = len(limiter.hosts)
+	limiter.mu.Unlock()
+	if remain != 0 {
+		t.Errorf("release后应可清理，剩余%d个", remain)
+	}
+}
+
+// TestPool_RateLimiter 测试Pool内实例共享同一主机的并发与速率配额
+func TestPool_RateLimiter(t *testing.T) {
+	server, peak := newConcurrencyServer(20 * time.Millisecond)
+	defer server.Close()
+
+	limiter := NewHostLimiter(HostLimitConfig{MaxConcurrent: 2})
+	pool := NewGatherUtilPool(map[string]string{"User-Agent": "test"}, "", 30, false, 10)
+	pool.SetRateLimiter(limiter)
+	ga := NewGather("chrome", false)
+	ga.SetRateLimiter(limiter)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			if i%4 == 0 {
+				_, _, err = ga.Get(server.URL, "")
+			} else {
+				_, _, err = pool.Get(server.URL, "")
+			}
+			if err != nil {
+				t.Errorf("请求失败：%v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if peak() > 2 {
+		t.Errorf("同一主机最大并发应为2，实际：%d", peak())
+	}
+
+	// 速率限制：每秒50个，5个请求至少间隔4×20ms
+	fast, _ := newConcurrencyServer(0)
+	defer fast.Close()
+	pool.SetRateLimiter(NewHostLimiter(HostLimitConfig{RPS: 50}))
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, _, err := pool.Get(fast.URL, ""); err != nil {
+			t.Fatalf("请求失败：%v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Errorf("限流后5个请求耗时过短：%v", elapsed)
+	}
+}
+
+// TestRateLimiter_Redirect 测试跳转的每一跳按各自的目标主机限流
+func TestRateLimiter_Redirect(t *testing.T) {
+	target, peak := newConcurrencyServer(30 * time.Millisecond)
+	defer target.Close()
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/loop" {
+			http.Redirect(w, r, "/done", http.StatusFound)
+			return
+		}
+		if r.URL.Path == "/done" {
+			w.Write([]byte("done"))
+			return
+		}
+		http.Redirect(w, r, target.URL, http.StatusFound)
+	}))
+	defer origin.Close()
+
+	// A跳到B：A不限流，B的并发上限对跳转过去的请求同样生效
+	limiter := NewHostLimiter(HostLimitConfig{})
+	limiter.SetHostConfig(strings.TrimPrefix(target.URL, "http://"), HostLimitConfig{MaxConcurrent: 1})
+	pool := NewGatherUtilPool(map[string]string{"User-Agent": "test"}, "", 30, false, 4)
+	pool.SetRateLimiter(limiter)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if html, _, err := pool.Get(origin.URL, ""); err != nil || html != "ok" {
+				t.Errorf("请求失败：%q, %v", html, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if peak() != 1 {
+		t.Errorf("跳转到的主机最大并发应为1，实际：%d", peak())
+	}
+
+	// 跳回同一主机：第二跳同样遵守最小间隔
+	limiter.SetHostConfig(strings.TrimPrefix(origin.URL, "http://"), HostLimitConfig{MinDelay: 100 * time.Millisecond})
+	ga := NewGather("chrome", false)
+	ga.SetRateLimiter(limiter)
+	start := time.Now()
+	if html, _, err := ga.Get(origin.URL+"/loop", ""); err != nil || html != "done" {
+		t.Fatalf("请求失败：%q, %v", html, err)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("跳回同一主机的请求应等待最小间隔，实际：%v", elapsed)
+	}
+}
+
+// TestHostLimiter_SetHostConfigInFlight 测试修改配置时保留进行中请求占用的并发槽位
+func TestHostLimiter_SetHostConfigInFlight(t *testing.T) {
+	limiter := NewHostLimiter(HostLimitConfig{MaxConcurrent: 1})
+	release, err := limiter.Wait(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Wait失败：%v", err)
+	}
+	limiter.SetHostConfig("example.com", HostLimitConfig{MaxConcurrent: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Wait(ctx, "example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("修改配置后进行中的请求仍应占用槽位，实际：%v", err)
+	}
+
+	// 调大并发上限时，等待中的请求立即放行
+	acquired := make(chan func(), 1)
+	go func() {
+		second, err := limiter.Wait(context.Background(), "example.com")
+		if err != nil {
+			t.Errorf("Wait失败：%v", err)
+		}
+		acquired <- second
+	}()
+	time.Sleep(20 * time.Millisecond)
+	limiter.SetHostConfig("example.com", HostLimitConfig{MaxConcurrent: 2})
+	select {
+	case second := <-acquired:
+		second()
+	case <-time.After(time.Second):
+		t.Fatal("调大并发上限后等待中的请求应放行")
+	}
+	release()
+
+	limiter.mu.Lock()
+	inFlight := limiter.hosts["example.com"].inFlight
+	limiter.mu.Unlock()
+	if inFlight != 0 {
+		t.Errorf("全部release后进行中的请求数应为0，实际：%d", inFlight)
+	}
+}
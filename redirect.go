@@ -0,0 +1,230 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrTooManyRedirects 跳转次数超过RedirectPolicy.MaxHops
+	ErrTooManyRedirects = errors.New("跳转次数过多")
+	// ErrRedirectBlocked 跳转被RedirectPolicy拦截（如禁止跨域跳转）
+	ErrRedirectBlocked = errors.New("跳转被拦截")
+)
+
+// defaultMaxRedirects 未设置MaxHops时最多跟随的跳转次数（与标准库一致：跟随9次跳转、共发出10个请求后停止）
+const defaultMaxRedirects = 9
+
+// RedirectPolicy 跳转策略，零值与标准库默认行为一致（最多跟随9次跳转）
+// 每一跳的URL、状态码、Location和Set-Cookie均记录在Response.Redirects中
+type RedirectPolicy struct {
+	Disable        bool     // 不跟随跳转，3xx响应直接作为最终结果返回（视为成功）
+	MaxHops        int      // 最多跟随的跳转次数（不含初始请求），<=0表示默认9（与标准库一致），再跳转时返回ErrTooManyRedirects
+	SameSiteOnly   bool     // 禁止跨站跳转：仅允许跳转到与初始URL同一注册域名（如a.example.com→www.example.com）的地址
+	AllowedDomains []string // SameSiteOnly开启时额外允许跳转的域名（含其子域名）
+	UpdateReferer  bool     // 像浏览器一样，每一跳都把Referer更新为上一跳的URL（https→http时不发送）
+
+	// OnRedirect 每次跟随跳转前回调，可用于调试或自定义控制
+	// next为即将发起的请求（可修改请求头），hop为引发本次跳转的响应记录
+	// 返回http.ErrUseLastResponse则停止跟随并返回当前3xx响应，返回其他错误则中止请求
+	OnRedirect func(next *http.Request, hop RedirectHop) error
+}
+
+// redirectPolicyKey 请求级跳转策略在context中的键
+type redirectPolicyKey struct{}
+
+// WithRedirectPolicy 返回携带请求级跳转策略的ctx，配合XxxContext系列方法使用
+// 请求级策略会完整替换实例级策略
+// 示例：
+//
+//	ctx := gather.WithRedirectPolicy(context.Background(), gather.RedirectPolicy{MaxHops: 3, SameSiteOnly: true})
+//	resp, err := ga.GetResponseContext(ctx, URL, "", "")
+//	for _, hop := range resp.Redirects {
+//	    fmt.Println(hop.StatusCode, hop.URL, hop.Location, hop.SetCookies)
+//	}
+func WithRedirectPolicy(ctx context.Context, policy RedirectPolicy) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, redirectPolicyKey{}, policy)
+}
+
+// SetRedirectPolicy 设置实例级跳转策略
+func (g *GatherStruct) SetRedirectPolicy(policy RedirectPolicy) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	policy.AllowedDomains = slices.Clone(policy.AllowedDomains)
+	g.redirectPolicy = policy
+}
+
+// SetRedirectPolicy 为池内所有实例设置跳转策略
+func (p *Pool) SetRedirectPolicy(policy RedirectPolicy) {
+	for _, ga := range p.pool {
+		ga.SetRedirectPolicy(policy)
+	}
+}
+
+// redirectPolicyFor 返回本次请求生效的跳转策略：请求级优先，否则使用实例级
+func (g *GatherStruct) redirectPolicyFor(req *http.Request) RedirectPolicy {
+	if policy, ok := req.Context().Value(redirectPolicyKey{}).(RedirectPolicy); ok {
+		return policy
+	}
+	return g.redirectPolicy
+}
+
+// isZero 是否为默认策略（无需替换标准库的跳转处理）
+func (rp RedirectPolicy) isZero() bool {
+	return !rp.Disable && rp.MaxHops <= 0 && !rp.SameSiteOnly && !rp.UpdateReferer && rp.OnRedirect == nil
+}
+
+// checkRedirect 实现http.Client.CheckRedirect
+func (rp RedirectPolicy) checkRedirect(req *http.Request, via []*http.Request) error {
+	if rp.Disable {
+		return http.ErrUseLastResponse
+	}
+	maxHops := rp.MaxHops
+	if maxHops <= 0 {
+		maxHops = defaultMaxRedirects
+	}
+	// via为已发出的请求（含初始请求），即将跟随的是第len(via)次跳转
+	if len(via) > maxHops {
+		return fmt.Errorf("%w: 已跟随%d次跳转", ErrTooManyRedirects, maxHops)
+	}
+	if rp.SameSiteOnly && !rp.allowHost(via[0].URL.Hostname(), req.URL.Hostname()) {
+		return fmt.Errorf("%w: 禁止从%s跨站跳转到%s", ErrRedirectBlocked, via[0].URL.Host, req.URL.Host)
+	}
+	if rp.UpdateReferer {
+		if referer := redirectReferer(via[len(via)-1].URL, req.URL); referer != "" {
+			req.Header.Set("Referer", referer)
+		} else {
+			req.Header.Del("Referer")
+		}
+	}
+	if rp.OnRedirect != nil && req.Response != nil {
+		return rp.OnRedirect(req, newRedirectHop(req.Response))
+	}
+	return nil
+}
+
+// stoppedAt 判断最终响应是否为被本策略停止跟随的跳转响应
+func (rp RedirectPolicy) stoppedAt(resp *http.Response) bool {
+	if !rp.Disable && rp.OnRedirect == nil {
+		return false
+	}
+	return resp.StatusCode >= 300 && resp.StatusCode < 400 && resp.Header.Get("Location") != ""
+}
+
+// allowHost 判断是否允许从origin跳转到target
+func (rp RedirectPolicy) allowHost(origin, target string) bool {
+	origin, target = strings.ToLower(origin), strings.ToLower(target)
+	if origin == target || registrableDomain(origin) == registrableDomain(target) {
+		return true
+	}
+	for _, domain := range rp.AllowedDomains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if target == domain || strings.HasSuffix(target, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// redirectReferer 浏览器式的跳转Referer：去掉用户信息和片段，https→http时不发送
+func redirectReferer(last, next *url.URL) string {
+	if last.Scheme == "https" && next.Scheme == "http" {
+		return ""
+	}
+	ref := *last
+	ref.User = nil
+	ref.Fragment = ""
+	ref.RawFragment = ""
+	return ref.String()
+}
+
+// registrableDomain 按默认公共后缀列表（见DefaultPublicSuffixList）计算主机的注册域名（eTLD+1），
+// IP地址以及本身为公共后缀的主机（如localhost）原样返回
+func registrableDomain(host string) string {
+	return registrableDomainIn(DefaultPublicSuffixList(), host)
+}
+
+// registrableDomainIn 与registrableDomain相同，但按指定的公共后缀列表计算
+func registrableDomainIn(list *PublicSuffixList, host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if strings.Contains(host, ":") || isIPv4(host) {
+		return host
+	}
+	if domain := list.RegistrableDomain(host); domain != "" {
+		return domain
+	}
+	return host
+}
+
+// isIPv4 判断是否为IPv4地址（只需区分域名与IP）
+func isIPv4(host string) bool {
+	parts := strings.Split(host, ".")
+	if len(parts) != 4 {
+		return false
+	}
+	for _, p := range parts {
+		if p == "" || strings.Trim(p, "0123456789") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// clientFor 返回执行本次请求的Client
+// 需要定制跳转行为（3xx作为最终结果、跳转策略）时复制一份Client再设置CheckRedirect，不修改实例共享的Client
+// 开启HAR记录时同样复制一份Client，把Transport包装为记录器
+// 使用实例自带的jar时同样复制一份Client，由jar按每一跳实际发送的Referer和方法判断SameSite
+// 挂载了限流器时同样复制一份Client，把Transport包装为按每一跳的目标主机限流
+func (g *GatherStruct) clientFor(req *http.Request, status StatusPolicy, redirect RedirectPolicy) *http.Client {
+	customRedirect := status.TreatRedirectAsFinal || !redirect.isZero()
+	sameSiteJar := g.J != nil && g.Client.Jar == g.J
+	if !customRedirect && g.har == nil && !sameSiteJar && g.limiter == nil {
+		return g.Client
+	}
+	client := *g.Client
+	if customRedirect {
+		if status.TreatRedirectAsFinal {
+			redirect.Disable = true
+		}
+		client.CheckRedirect = redirect.checkRedirect
+	}
+	if sameSiteJar {
+		// 跳转时Referer会被更新（UpdateReferer或标准库自动设置），302/303还会把POST改为GET，
+		// 因此在每一跳的CheckRedirect之后按该跳的请求刷新jar视图
+		view := &cookieSiteJar{jar: g.J}
+		view.update(req)
+		client.Jar = view
+		check := client.CheckRedirect
+		if check == nil {
+			check = RedirectPolicy{}.checkRedirect // 与标准库默认策略一致
+		}
+		client.CheckRedirect = func(next *http.Request, via []*http.Request) error {
+			if err := check(next, via); err != nil {
+				return err
+			}
+			view.update(next)
+			return nil
+		}
+	}
+	if g.har != nil {
+		client.Transport = g.har.wrap(client.Transport)
+	}
+	if g.limiter != nil {
+		client.Transport = &limitedTransport{base: client.Transport, limiter: g.limiter}
+	}
+	return &client
+}
@@ -0,0 +1,184 @@
+package gather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newRedirectTestServer 跳转测试Server
+// /hop?n=3：依次302到/hop?n=2、/hop?n=1、/final，每跳下发一个Cookie
+// /cross：302到localhost（与127.0.0.1不同站）
+// /final：返回收到的Referer
+func newRedirectTestServer() *httptest.Server {
+	mux := http.NewServeMux()
+	var serverURL string
+	mux.HandleFunc("/hop", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+		http.SetCookie(w, &http.Cookie{Name: fmt.Sprintf("hop%d", n), Value: "1", Path: "/"})
+		if n <= 1 {
+			http.Redirect(w, r, "/final", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/hop?n=%d", n-1), http.StatusFound)
+	})
+	mux.HandleFunc("/cross", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(serverURL, "127.0.0.1", "localhost", 1)+"/final", http.StatusFound)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("referer=" + r.Header.Get("Referer")))
+	})
+	server := httptest.NewServer(mux)
+	serverURL = server.URL
+	return server
+}
+
+// TestRedirectPolicy_Record 测试跳转链路记录
+func TestRedirectPolicy_Record(t *testing.T) {
+	server := newRedirectTestServer()
+	defer server.Close()
+
+	ga := NewGather("chrome", false)
+	resp, err := ga.GetResponse(server.URL+"/hop?n=3", "", "")
+	if err != nil {
+		t.Fatalf("请求失败：%v", err)
+	}
+	if len(resp.Redirects) != 3 {
+		t.Fatalf("应记录3跳，实际%d跳", len(resp.Redirects))
+	}
+	hop := resp.Redirects[0]
+	if hop.StatusCode != http.StatusFound || hop.Location != "/hop?n=2" || len(hop.SetCookies) != 1 || hop.SetCookies[0].Name != "hop3" {
+		t.Errorf("第一跳记录错误：%+v", hop)
+	}
+	if !strings.HasSuffix(resp.FinalURL, "/final") {
+		t.Errorf("最终URL错误：%s", resp.FinalURL)
+	}
+}
+
+// TestRedirectPolicy_MaxHops 测试MaxHops=N时恰好跟随N次跳转，默认与标准库一致
+func TestRedirectPolicy_MaxHops(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+		http.Redirect(w, r, fmt.Sprintf("/?n=%d", n+1), http.StatusFound)
+	}))
+	defer server.Close()
+	ga := NewGather("chrome", false)
+
+	for _, maxHops := range []int{1, 2, 5, 10} {
+		requests.Store(0)
+		ctx := WithRedirectPolicy(context.Background(), RedirectPolicy{MaxHops: maxHops})
+		if _, err := ga.GetResponseContext(ctx, server.URL+"/?n=0", "", ""); !errors.Is(err, ErrTooManyRedirects) {
+			t.Errorf("MaxHops=%d：应返回ErrTooManyRedirects，实际：%v", maxHops, err)
+		}
+		// 初始请求 + 跟随的跳转
+		if followed := requests.Load() - 1; followed != int32(maxHops) {
+			t.Errorf("MaxHops=%d：应跟随%d次跳转，实际%d次", maxHops, maxHops, followed)
+		}
+	}
+
+	// 默认策略与标准库一致：跟随9次跳转（共10个请求）后停止
+	requests.Store(0)
+	_, err := ga.GetResponse(server.URL+"/?n=0", "", "")
+	if followed := requests.Load() - 1; err == nil || followed != defaultMaxRedirects {
+		t.Errorf("默认应跟随%d次跳转，实际%d次：%v", defaultMaxRedirects, followed, err)
+	}
+	requests.Store(0)
+	http.Get(server.URL + "/?n=0")
+	if stdlib := requests.Load() - 1; stdlib != defaultMaxRedirects {
+		t.Errorf("默认跳转次数应与标准库一致：标准库跟随%d次", stdlib)
+	}
+
+	// 恰好N次跳转的链路在MaxHops=N时成功，Response.Redirects记录N跳
+	redirects := newRedirectTestServer()
+	defer redirects.Close()
+	ctx := WithRedirectPolicy(context.Background(), RedirectPolicy{MaxHops: 3})
+	if resp, err := ga.GetResponseContext(ctx, redirects.URL+"/hop?n=3", "", ""); err != nil || len(resp.Redirects) != 3 {
+		t.Errorf("MaxHops=3时应跟随3次跳转：%v", err)
+	}
+}
+
+// TestRedirectPolicy 测试禁止跳转、最大跳数、跨站限制、Referer更新
+func TestRedirectPolicy(t *testing.T) {
+	server := newRedirectTestServer()
+	defer server.Close()
+	ga := NewGather("chrome", false)
+
+	// 禁止跳转：返回第一跳的302，视为成功
+	ctx := WithRedirectPolicy(context.Background(), RedirectPolicy{Disable: true})
+	resp, err := ga.GetResponseContext(ctx, server.URL+"/hop?n=3", "", "")
+	if err != nil || resp.StatusCode != http.StatusFound || resp.Location() != "/hop?n=2" {
+		t.Errorf("禁止跳转应返回302本身，实际：%v, %v", resp, err)
+	}
+
+	// 最大跳数：/hop?n=3共3次跳转
+	ctx = WithRedirectPolicy(context.Background(), RedirectPolicy{MaxHops: 2})
+	if _, err := ga.GetResponseContext(ctx, server.URL+"/hop?n=3", "", ""); !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("超过最大跳数应返回ErrTooManyRedirects，实际：%v", err)
+	}
+	ctx = WithRedirectPolicy(context.Background(), RedirectPolicy{MaxHops: 3})
+	if _, err := ga.GetResponseContext(ctx, server.URL+"/hop?n=3", "", ""); err != nil {
+		t.Errorf("跳数未超限时应成功，实际：%v", err)
+	}
+
+	// 跨站限制
+	ga.SetRedirectPolicy(RedirectPolicy{SameSiteOnly: true})
+	if _, _, err := ga.Get(server.URL+"/cross", ""); !errors.Is(err, ErrRedirectBlocked) {
+		t.Errorf("跨站跳转应被拦截，实际：%v", err)
+	}
+	ga.SetRedirectPolicy(RedirectPolicy{SameSiteOnly: true, AllowedDomains: []string{"localhost"}})
+	if _, _, err := ga.Get(server.URL+"/cross", ""); err != nil {
+		t.Errorf("AllowedDomains中的域名应允许跳转，实际：%v", err)
+	}
+
+	// Referer更新：默认保留调用方设置的Referer，开启后更新为上一跳URL
+	ga.SetRedirectPolicy(RedirectPolicy{})
+	html, _, _ := ga.Get(server.URL+"/hop?n=1", "http://example.com/")
+	if html != "referer=http://example.com/" {
+		t.Errorf("默认应保留原始Referer，实际：%s", html)
+	}
+	ga.SetRedirectPolicy(RedirectPolicy{UpdateReferer: true})
+	html, _, _ = ga.Get(server.URL+"/hop?n=1", "http://example.com/")
+	if html != "referer="+server.URL+"/hop?n=1" {
+		t.Errorf("Referer应更新为上一跳URL，实际：%s", html)
+	}
+
+	// OnRedirect回调可中途停止
+	var seen []string
+	ctx = WithRedirectPolicy(context.Background(), RedirectPolicy{OnRedirect: func(next *http.Request, hop RedirectHop) error {
+		seen = append(seen, hop.Location)
+		if next.URL.Path == "/final" {
+			return http.ErrUseLastResponse
+		}
+		return nil
+	}})
+	resp, err = ga.GetResponseContext(ctx, server.URL+"/hop?n=2", "", "")
+	if err != nil || resp.StatusCode != http.StatusFound || len(seen) != 2 || len(resp.Redirects) != 1 {
+		t.Errorf("OnRedirect停止跟随结果错误：%v, %v, %v", resp, err, seen)
+	}
+}
+
+// TestRegistrableDomain 测试注册域名近似计算
+func TestRegistrableDomain(t *testing.T) {
+	testCases := map[string]string{
+		"www.example.com":    "example.com",
+		"a.b.example.com.cn": "example.com.cn",
+		"news.sina.com.cn":   "sina.com.cn",
+		"www.gov.cn":         "www.gov.cn",
+		"example.co.uk":      "example.co.uk",
+		"127.0.0.1":          "127.0.0.1",
+		"localhost":          "localhost",
+	}
+	for host, want := range testCases {
+		if got := registrableDomain(host); got != want {
+			t.Errorf("registrableDomain(%s)=%s，期望%s", host, got, want)
+		}
+	}
+}
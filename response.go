@@ -0,0 +1,289 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// Response 完整的响应对象，与(html, redirectURL, err)三元组并行提供
+// 适用场景：需要读取状态码、响应头（Set-Cookie/Location/Content-Disposition/分页头等）、
+// 原始字节或跳转链路时使用，无需再直接调用Client.Do
+type Response struct {
+	StatusCode int            // HTTP状态码（如200、404）
+	Status     string         // 状态行文本（如"200 OK"）
+	Header     http.Header    // 最终响应的响应头
+	Body       []byte         // 响应体原始字节（已按Content-Encoding自动解码，保持服务器原始字符集）
+	Charset    string         // 检测到的字符集规范名称（如utf-8、gbk、gb18030、big5），非文本内容为空
+	FinalURL   string         // 最终实际访问的URL（处理完所有跳转后的地址）
+	Redirects  []RedirectHop  // 中间跳转记录（按发生顺序，不含最终响应），无跳转时为空
+	Request    *http.Request  // 发起本次采集的原始请求
+	Raw        *http.Response // 最终的标准库响应对象（Body已读取并关闭，仅用于读取元信息；来自缓存时为nil）
+	FromCache  bool           // 响应来自缓存（新鲜期内直接命中，或服务器返回304后使用缓存内容）
+	Timing     RequestTiming  // 各阶段耗时（来自缓存时为零值）
+
+	transcode bool  // Text()是否按Charset转码为UTF-8（关闭自动转码时为false）
+	rawSize   int64 // 解码前的响应体字节数（用于指标统计）
+}
+
+// RedirectHop 单次跳转记录
+type RedirectHop struct {
+	URL        string         // 本跳请求的URL
+	StatusCode int            // 本跳返回的状态码（如301、302）
+	Location   string         // 本跳返回的Location（跳转目标，可能为相对地址）
+	SetCookies []*http.Cookie // 本跳下发的Cookie（登录流程常在中间302中下发会话Cookie）
+	Header     http.Header    // 本跳的响应头
+}
+
+// newRedirectHop 根据引发跳转的响应生成跳转记录
+func newRedirectHop(resp *http.Response) RedirectHop {
+	return RedirectHop{
+		URL:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		Location:   resp.Header.Get("Location"),
+		SetCookies: resp.Cookies(),
+		Header:     resp.Header,
+	}
+}
+
+// Text 以UTF-8字符串形式返回响应体
+// 开启自动转码（默认）时，GBK/GB18030/Big5等编码的页面会按Charset转码为UTF-8；
+// 关闭自动转码或内容非文本时，原样返回
+func (r *Response) Text() string {
+	if r == nil {
+		return ""
+	}
+	if !r.transcode || r.Charset == "" || r.Charset == CharsetUTF8 {
+		return string(r.Body)
+	}
+	text, err := ToUTF8(r.Body, r.Charset)
+	if err != nil {
+		return string(r.Body)
+	}
+	return string(text)
+}
+
+// ContentType 返回响应的Content-Type头
+func (r *Response) ContentType() string {
+	if r == nil || r.Header == nil {
+		return ""
+	}
+	return r.Header.Get("Content-Type")
+}
+
+// Cookies 解析并返回最终响应中的Set-Cookie
+func (r *Response) Cookies() []*http.Cookie {
+	if r == nil || r.Raw == nil {
+		return nil
+	}
+	return r.Raw.Cookies()
+}
+
+// Location 返回Location响应头（仅在3xx响应被当作最终结果时有意义）
+func (r *Response) Location() string {
+	if r == nil || r.Header == nil {
+		return ""
+	}
+	return r.Header.Get("Location")
+}
+
+// Do 执行调用方自行构建的请求，返回完整的Response对象
+// 说明：
+//  1. 请求会经过实例的Cookie管理、自动解压等完整处理流程
+//  2. 调用方构建的请求头不会被实例默认请求头覆盖
+//  3. 状态码不被状态码策略接受（默认为非2xx）时err非nil，但resp仍然返回，可继续读取状态码、响应头和响应体
+//
+// 示例：
+//
+//	ga := NewGather("chrome", false)
+//	req, _ := http.NewRequest("GET", "https://www.baidu.com/", nil)
+//	resp, err := ga.Do(req)
+//	if err != nil {
+//	    return
+//	}
+//	fmt.Println(resp.StatusCode, resp.Header.Get("Content-Type"), len(resp.Body))
+func (g *GatherStruct) Do(req *http.Request) (*Response, error) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	return g.do(req)
+}
+
+// do 执行HTTP请求并组装Response对象，所有采集方法最终都汇聚到这里
+// 状态码不被策略接受时同时返回Response和*StatusError
+func (g *GatherStruct) do(req *http.Request) (*Response, error) {
+	// 核心参数空值校验
+	if req == nil {
+		panic("FATAL: HTTP请求对象为nil，无法执行请求")
+	}
+	if g == nil || g.Client == nil {
+		panic("FATAL: GatherStruct/Client 未初始化，无法执行请求")
+	}
+	// 依次经过中间件（先注册的在外层），最内层为实际执行
+	if len(g.middlewares) == 0 {
+		return g.dispatch(req)
+	}
+	next := RoundTripFunc(g.dispatch)
+	for i := len(g.middlewares) - 1; i >= 0; i-- {
+		next = g.middlewares[i](next)
+	}
+	resp, err := next(req)
+	if resp == nil && err == nil {
+		err = errMiddlewareNoResponse
+	}
+	return resp, err
+}
+
+// dispatch 中间件链最内层：robots.txt检查 → 缓存 → 重试 → 单次请求
+func (g *GatherStruct) dispatch(req *http.Request) (*Response, error) {
+	// 开启robots.txt检查时，被禁止的请求不发出
+	if err := g.checkRobots(req); err != nil {
+		g.logEvent(req, slog.LevelInfo, "gather请求被robots.txt禁止")
+		return nil, err
+	}
+	// 开启缓存时先查缓存，过期则发起条件请求
+	if g.cache != nil {
+		resp, err := g.doCached(req)
+		if resp != nil && resp.FromCache {
+			g.logEvent(req, slog.LevelDebug, "gather命中缓存", slog.Int("status", resp.StatusCode))
+		}
+		return resp, err
+	}
+	// 按重试策略执行（未设置重试策略时只执行一次）
+	return g.doWithRetry(req)
+}
+
+// doOnce 执行一次HTTP请求并组装Response对象，同时记录各阶段耗时
+func (g *GatherStruct) doOnce(req *http.Request) (*Response, error) {
+	trace := newConnTrace()
+	response, err := g.roundTrip(trace.withContext(req), trace)
+
+	finalURL := req.URL.String()
+	if response != nil {
+		finalURL = response.FinalURL
+	}
+	timing := trace.timing(finalURL, err)
+	g.lastTiming = timing
+	if response != nil {
+		response.Timing = timing
+	}
+	if callback := g.timingCallbackFor(req); callback != nil {
+		callback(timing)
+	}
+	if g.metrics != nil {
+		g.metrics.observeRequest(req, response, err, timing.Total)
+	}
+	g.logRequest(req, response, err, timing)
+	return response, err
+}
+
+// roundTrip 执行一次HTTP请求（限流、发送、读取并解码响应体）并组装Response对象
+func (g *GatherStruct) roundTrip(req *http.Request, trace *connTrace) (*Response, error) {
+	// 执行请求，网络错误归类为ErrTimeout/ErrDNS/ErrTLS/ErrProxy（保留原始错误链）
+	policy := g.statusPolicyFor(req)
+	redirectPolicy := g.redirectPolicyFor(req)
+	// 挂载了限流器时，clientFor安装的Transport按每一跳的目标主机限流，并发槽位在该跳的响应体关闭后归还
+	trace.begin()
+	resp, err := g.clientFor(req, policy, redirectPolicy).Do(req)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	defer resp.Body.Close()
+
+	// 读取响应体，设置了大小上限时多读1字节用于判断是否超限
+	if g.maxBodySize > 0 && resp.ContentLength > g.maxBodySize {
+		return nil, fmt.Errorf("%w: Content-Length %d 超过 %d 字节", ErrBodyTooLarge, resp.ContentLength, g.maxBodySize)
+	}
+	var bodyReader io.Reader = resp.Body
+	if g.maxBodySize > 0 {
+		bodyReader = io.LimitReader(resp.Body, g.maxBodySize+1)
+	}
+	respBody, err := io.ReadAll(bodyReader)
+	trace.done()
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	if g.maxBodySize > 0 && int64(len(respBody)) > g.maxBodySize {
+		return nil, fmt.Errorf("%w: 超过 %d 字节", ErrBodyTooLarge, g.maxBodySize)
+	}
+
+	rawSize := int64(len(respBody))
+	// 按Content-Encoding静默解码（gzip/deflate/br/zstd及多重编码），失败则直接使用原始数据
+	// 设置了大小上限时解码过程中即检查，压缩炸弹在解出全部数据前就会返回ErrBodyTooLarge
+	decodeLimit := int64(-1)
+	if g.maxBodySize > 0 {
+		decodeLimit = g.maxBodySize
+	}
+	if body, err := decodeResponseBody(resp.Header, respBody, decodeLimit); err == nil {
+		respBody = body
+	} else if errors.Is(err, ErrBodyTooLarge) {
+		return nil, err
+	}
+
+	response := &Response{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       respBody,
+		FinalURL:   resp.Request.URL.String(),
+		Redirects:  redirectHops(resp),
+		Request:    req,
+		Raw:        resp,
+		transcode:  !g.charsetDisabled,
+		rawSize:    rawSize,
+	}
+
+	// 仅对文本类内容检测字符集，避免图片等二进制内容被误转码
+	if isTextContent(resp.Header.Get("Content-Type"), respBody) {
+		response.Charset = DetectCharset(resp.Header.Get("Content-Type"), respBody)
+	}
+
+	// 状态码策略不接受时返回StatusError（可通过errors.As取出状态码、响应头、响应体和Retry-After）
+	// 跳转策略主动停止跟随时，返回的3xx响应视为最终结果
+	if redirectPolicy.stoppedAt(resp) {
+		policy.TreatRedirectAsFinal = true
+	}
+	if !policy.accepts(resp.StatusCode) {
+		return response, newStatusError(response)
+	}
+	return response, nil
+}
+
+// isTextContent 判断响应是否为文本内容（未声明Content-Type时按内容嗅探）
+func isTextContent(contentType string, body []byte) bool {
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType := strings.ToLower(contentType)
+	if idx := strings.IndexByte(mediaType, ';'); idx >= 0 {
+		mediaType = mediaType[:idx]
+	}
+	mediaType = strings.TrimSpace(mediaType)
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.Contains(mediaType, "json") ||
+		strings.Contains(mediaType, "xml") ||
+		strings.Contains(mediaType, "javascript") ||
+		strings.Contains(mediaType, "html")
+}
+
+// redirectHops 通过标准库的Request.Response链路还原中间跳转记录
+// 标准库在跟随跳转时，会把“引发本次跳转的响应”挂在新请求的Response字段上
+func redirectHops(resp *http.Response) []RedirectHop {
+	var hops []RedirectHop
+	for prev := resp.Request.Response; prev != nil && prev.Request != nil; prev = prev.Request.Response {
+		hops = append(hops, newRedirectHop(prev))
+	}
+	// 链路是从后往前收集的，翻转为发生顺序
+	for i, j := 0, len(hops)-1; i < j; i, j = i+1, j-1 {
+		hops[i], hops[j] = hops[j], hops[i]
+	}
+	return hops
+}
@@ -0,0 +1,94 @@
+package gather
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestGather_GetResponse 测试完整Response对象：状态码、响应头、跳转链路
+func TestGather_GetResponse(t *testing.T) {
+	ga := NewGather("chrome", false)
+
+	resp, err := ga.GetResponse(testBaseURL+"/redirect", "", "")
+	if err != nil {
+		t.Fatalf("GetResponse请求失败：%v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("状态码异常，期望200，实际%d", resp.StatusCode)
+	}
+	if !strings.Contains(resp.ContentType(), "application/json") {
+		t.Errorf("Content-Type异常：%s", resp.ContentType())
+	}
+	if resp.FinalURL != testBaseURL+"/get?from=redirect" {
+		t.Errorf("最终URL异常：%s", resp.FinalURL)
+	}
+	if !strings.Contains(resp.Text(), "success") || len(resp.Body) == 0 {
+		t.Errorf("响应体异常：%s", resp.Text())
+	}
+	if resp.Request == nil || resp.Request.URL.Path != "/redirect" {
+		t.Errorf("原始请求记录异常：%v", resp.Request)
+	}
+
+	// 验证跳转链路
+	if len(resp.Redirects) != 1 {
+		t.Fatalf("跳转记录数异常，期望1，实际%d", len(resp.Redirects))
+	}
+	hop := resp.Redirects[0]
+	if hop.StatusCode != http.StatusFound || hop.URL != testBaseURL+"/redirect" {
+		t.Errorf("跳转记录异常：%+v", hop)
+	}
+	if hop.Header.Get("Location") != "/get?from=redirect" {
+		t.Errorf("跳转Location异常：%s", hop.Header.Get("Location"))
+	}
+	if !strings.Contains(hop.Header.Get("Set-Cookie"), "redirect_id=302") {
+		t.Errorf("跳转Set-Cookie异常：%s", hop.Header.Get("Set-Cookie"))
+	}
+}
+
+// TestGather_GetResponse_Non2xx 测试非2xx时Response与错误一同返回
+func TestGather_GetResponse_Non2xx(t *testing.T) {
+	ga := NewGather("chrome", false)
+
+	resp, err := ga.GetResponse(testBaseURL+"/error_json", "", "")
+	if err == nil {
+		t.Fatal("422应返回错误，实际未返回")
+	}
+	if resp == nil {
+		t.Fatal("非2xx时应同时返回Response")
+	}
+	if resp.StatusCode != http.StatusUnprocessableEntity || !strings.Contains(resp.Text(), "invalid param") {
+		t.Errorf("非2xx响应内容异常：%d %s", resp.StatusCode, resp.Text())
+	}
+}
+
+// TestGather_Do 测试执行自定义请求
+func TestGather_Do(t *testing.T) {
+	ga := NewGather("chrome", false)
+
+	req, err := http.NewRequest("GET", testBaseURL+"/get", nil)
+	if err != nil {
+		t.Fatalf("构建请求失败：%v", err)
+	}
+	req.Header.Set("X-Custom", "custom-value")
+	resp, err := ga.Do(req)
+	if err != nil {
+		t.Fatalf("Do请求失败：%v", err)
+	}
+	if !strings.Contains(resp.Text(), "custom-value") {
+		t.Errorf("自定义请求头未生效：%s", resp.Text())
+	}
+}
+
+// TestPool_PostResponse 测试Pool的PostResponse方法
+func TestPool_PostResponse(t *testing.T) {
+	pool := NewGatherUtilPool(map[string]string{}, "", 10, false, 2)
+
+	resp, err := pool.PostResponse(testBaseURL+"/post", "", "", map[string]string{"name": "pool"})
+	if err != nil {
+		t.Fatalf("Pool.PostResponse请求失败：%v", err)
+	}
+	if resp.StatusCode != http.StatusOK || !strings.Contains(resp.Text(), `"name":"pool"`) {
+		t.Errorf("Pool.PostResponse响应异常：%d %s", resp.StatusCode, resp.Text())
+	}
+}
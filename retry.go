@@ -0,0 +1,213 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// 重试策略默认值
+const (
+	defaultRetryBaseDelay = 200 * time.Millisecond
+	defaultRetryMaxDelay  = 10 * time.Second
+)
+
+// defaultRetryStatuses 默认重试的状态码：限流及网关类临时错误
+var defaultRetryStatuses = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// defaultRetryErrors 默认重试的错误类别：超时、连接被拒绝/重置、连接意外断开
+var defaultRetryErrors = []error{ErrTimeout, syscall.ECONNREFUSED, syscall.ECONNRESET, io.EOF, io.ErrUnexpectedEOF}
+
+// RetryPolicy 重试策略，零值表示不重试
+// 等待时间按指数退避计算：BaseDelay * 2^(第几次重试-1)，不超过MaxDelay，再按Jitter比例随机缩短
+// 响应带有Retry-After时以其为准（同样不超过MaxDelay）
+type RetryPolicy struct {
+	MaxAttempts        int           // 最大尝试次数（含首次请求），<=1表示不重试
+	BaseDelay          time.Duration // 首次重试前的等待时间，<=0时默认200ms
+	MaxDelay           time.Duration // 单次等待时间上限，<=0时默认10秒
+	Jitter             float64       // 随机抖动比例（0~1），如0.2表示实际等待时间在计算值的80%~100%之间
+	RetryStatuses      []int         // 需要重试的状态码，nil时默认429/502/503/504
+	RetryErrors        []error       // 需要重试的错误类别（按errors.Is匹配），nil时默认超时、连接拒绝/重置、连接意外断开
+	RetryNonIdempotent bool          // 是否重试POST/PATCH等非幂等请求（默认只重试GET/HEAD/OPTIONS/TRACE/PUT/DELETE）
+
+	// OnRetry 每次重试等待前回调（attempt为即将进行的第几次尝试），可用于记录日志
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// DefaultRetryPolicy 常用的重试策略：最多3次尝试，200ms起指数退避，20%抖动
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   defaultRetryBaseDelay,
+		MaxDelay:    defaultRetryMaxDelay,
+		Jitter:      0.2,
+	}
+}
+
+// retryPolicyKey 请求级重试策略在context中的键
+type retryPolicyKey struct{}
+
+// WithRetryPolicy 返回携带请求级重试策略的ctx，配合XxxContext系列方法使用
+// 请求级策略会完整替换实例级策略
+func WithRetryPolicy(ctx context.Context, policy RetryPolicy) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+// SetRetryPolicy 设置实例级重试策略
+// 示例：
+//
+//	ga.SetRetryPolicy(gather.DefaultRetryPolicy())
+//	html, _, err := ga.Get(URL, "") // 遇到超时/429/503等会自动退避重试
+func (g *GatherStruct) SetRetryPolicy(policy RetryPolicy) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	policy.RetryStatuses = slices.Clone(policy.RetryStatuses)
+	policy.RetryErrors = slices.Clone(policy.RetryErrors)
+	g.retryPolicy = policy
+}
+
+// SetRetryPolicy 为池内所有实例设置重试策略
+func (p *Pool) SetRetryPolicy(policy RetryPolicy) {
+	for _, ga := range p.pool {
+		ga.SetRetryPolicy(policy)
+	}
+}
+
+// retryPolicyFor 返回本次请求生效的重试策略：请求级优先，否则使用实例级
+func (g *GatherStruct) retryPolicyFor(req *http.Request) RetryPolicy {
+	if policy, ok := req.Context().Value(retryPolicyKey{}).(RetryPolicy); ok {
+		return policy
+	}
+	return g.retryPolicy
+}
+
+// doWithRetry 按重试策略执行请求
+// 每次重试都基于原始请求克隆新请求，并通过GetBody重新生成请求体
+// （PostUtil/PostBytes/PostJson/PostXML/PostMultipartFormDataUtil构建的请求均支持GetBody）
+func (g *GatherStruct) doWithRetry(req *http.Request) (*Response, error) {
+	policy := g.retryPolicyFor(req)
+	if policy.MaxAttempts <= 1 || !policy.allowMethod(req.Method) {
+		return g.doOnce(req)
+	}
+	// 有请求体却无法重建时不能重试
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	if hasBody && req.GetBody == nil {
+		return g.doOnce(req)
+	}
+
+	attemptReq := req
+	for attempt := 1; ; attempt++ {
+		resp, err := g.doOnce(attemptReq)
+		// 调用方ctx已取消或到期时，重试没有意义
+		if attempt >= policy.MaxAttempts || req.Context().Err() != nil || !policy.shouldRetry(resp, err) {
+			return resp, err
+		}
+
+		delay := policy.delay(attempt, resp)
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt+1, err, delay)
+		}
+		g.logEvent(req, slog.LevelInfo, "gather重试请求",
+			slog.Int("attempt", attempt+1), slog.Duration("delay", delay), slog.String("error", err.Error()))
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return resp, err
+		case <-timer.C:
+		}
+
+		attemptReq = req.Clone(req.Context())
+		if hasBody {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			attemptReq.Body = body
+		}
+	}
+}
+
+// allowMethod 判断请求方法是否允许重试
+func (rp RetryPolicy) allowMethod(method string) bool {
+	if rp.RetryNonIdempotent {
+		return true
+	}
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// shouldRetry 判断本次结果是否需要重试
+func (rp RetryPolicy) shouldRetry(resp *Response, err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		statuses := rp.RetryStatuses
+		if statuses == nil {
+			statuses = defaultRetryStatuses
+		}
+		return slices.Contains(statuses, se.StatusCode)
+	}
+	retryErrors := rp.RetryErrors
+	if retryErrors == nil {
+		retryErrors = defaultRetryErrors
+	}
+	for _, target := range retryErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// delay 计算第attempt次尝试失败后的等待时间
+func (rp RetryPolicy) delay(attempt int, resp *Response) time.Duration {
+	base, maxDelay := rp.BaseDelay, rp.MaxDelay
+	if base <= 0 {
+		base = defaultRetryBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+
+	// 服务器明确给出Retry-After时以其为准
+	if resp != nil {
+		if retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); retryAfter > 0 {
+			return min(retryAfter, maxDelay)
+		}
+	}
+
+	d := base
+	for i := 1; i < attempt && d < maxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, maxDelay)
+	if rp.Jitter > 0 {
+		jitter := min(rp.Jitter, 1)
+		d -= time.Duration(float64(d) * jitter * rand.Float64())
+	}
+	return d
+}
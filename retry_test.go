@@ -0,0 +1,154 @@
+package gather
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newFlakyServer 前failTimes次请求返回503，之后返回200并回显请求体
+func newFlakyServer(failTimes int) (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		count++
+		n := count
+		mu.Unlock()
+		if n <= failTimes {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok:" + string(body)))
+	}))
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return count
+	}
+}
+
+// fastRetryPolicy 测试用的快速重试策略
+func fastRetryPolicy(attempts int) RetryPolicy {
+	return RetryPolicy{MaxAttempts: attempts, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, Jitter: 0.5}
+}
+
+// TestRetryPolicy_Get 测试GET按状态码重试
+func TestRetryPolicy_Get(t *testing.T) {
+	server, count := newFlakyServer(2)
+	defer server.Close()
+
+	ga := NewGather("chrome", false)
+	ga.SetRetryPolicy(fastRetryPolicy(3))
+	var retries []int
+	ctx := WithRetryPolicy(context.Background(), RetryPolicy{
+		MaxAttempts: 3, BaseDelay: time.Millisecond,
+		OnRetry: func(attempt int, err error, delay time.Duration) { retries = append(retries, attempt) },
+	})
+	html, _, err := ga.GetContext(ctx, server.URL, "")
+	if err != nil || html != "ok:" || count() != 3 {
+		t.Errorf("应在第3次尝试成功，实际：%q, %v, 请求%d次", html, err, count())
+	}
+	if len(retries) != 2 || retries[0] != 2 || retries[1] != 3 {
+		t.Errorf("OnRetry回调次数错误：%v", retries)
+	}
+
+	// 尝试次数用尽，返回最后一次的StatusError
+	server2, count2 := newFlakyServer(5)
+	defer server2.Close()
+	_, _, err = ga.Get(server2.URL, "")
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusServiceUnavailable || count2() != 3 {
+		t.Errorf("重试用尽应返回503，实际：%v, 请求%d次", err, count2())
+	}
+
+	// 不在重试列表中的状态码不重试
+	ga.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, RetryStatuses: []int{http.StatusTooManyRequests}})
+	server3, count3 := newFlakyServer(1)
+	defer server3.Close()
+	if _, _, err = ga.Get(server3.URL, ""); err == nil || count3() != 1 {
+		t.Errorf("503不在重试列表中时不应重试，实际请求%d次", count3())
+	}
+}
+
+// TestRetryPolicy_Post 测试非幂等请求的重试开关及请求体重放
+func TestRetryPolicy_Post(t *testing.T) {
+	ga := NewGather("chrome", false)
+
+	// 默认不重试POST
+	server, count := newFlakyServer(1)
+	defer server.Close()
+	ga.SetRetryPolicy(fastRetryPolicy(3))
+	if _, _, err := ga.PostUtil(server.URL, "", "", map[string]string{"a": "1"}); err == nil || count() != 1 {
+		t.Errorf("默认不应重试POST，实际请求%d次", count())
+	}
+
+	// 开启后重试，且每次重试都带上完整请求体
+	policy := fastRetryPolicy(3)
+	policy.RetryNonIdempotent = true
+	ga.SetRetryPolicy(policy)
+
+	server2, count2 := newFlakyServer(2)
+	defer server2.Close()
+	html, _, err := ga.PostUtil(server2.URL, "", "", map[string]string{"a": "1"})
+	if err != nil || html != "ok:a=1" || count2() != 3 {
+		t.Errorf("PostUtil重试后请求体应完整，实际：%q, %v, 请求%d次", html, err, count2())
+	}
+
+	server3, _ := newFlakyServer(1)
+	defer server3.Close()
+	html, _, err = ga.PostBytes(server3.URL, "", "", []byte("raw-bytes"))
+	if err != nil || html != "ok:raw-bytes" {
+		t.Errorf("PostBytes重试后请求体应完整，实际：%q, %v", html, err)
+	}
+
+	server4, _ := newFlakyServer(1)
+	defer server4.Close()
+	html, _, err = ga.PostMultipartFormDataUtil(server4.URL, "", "", "", map[string]string{"k": "v"}, nil)
+	if err != nil || len(html) < len("ok:")+10 {
+		t.Errorf("PostMultipartFormDataUtil重试后请求体应完整，实际：%q, %v", html, err)
+	}
+}
+
+// TestRetryPolicy_Delay 测试退避时间计算
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second}
+	for i, w := range want {
+		if got := policy.delay(i+1, nil); got != w {
+			t.Errorf("第%d次退避时间=%v，期望%v", i+1, got, w)
+		}
+	}
+
+	// Retry-After优先，且不超过MaxDelay
+	resp := &Response{Header: http.Header{"Retry-After": []string{"30"}}}
+	if got := policy.delay(1, resp); got != time.Second {
+		t.Errorf("Retry-After应被MaxDelay限制，实际：%v", got)
+	}
+
+	// 抖动范围
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.delay(1, nil); got < 50*time.Millisecond || got > 100*time.Millisecond {
+			t.Fatalf("抖动后的等待时间超出范围：%v", got)
+		}
+	}
+}
+
+// TestPool_RetryPolicy 测试Pool的重试策略
+func TestPool_RetryPolicy(t *testing.T) {
+	server, count := newFlakyServer(1)
+	defer server.Close()
+	pool := NewGatherUtilPool(map[string]string{"User-Agent": "test"}, "", 30, false, 2)
+	pool.SetRetryPolicy(fastRetryPolicy(2))
+	if html, _, err := pool.Get(server.URL, ""); err != nil || html != "ok:" || count() != 2 {
+		t.Errorf("Pool应重试成功，实际：%q, %v, 请求%d次", html, err, count())
+	}
+}
@@ -0,0 +1,509 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRobotsDisallowed 请求被目标站点的robots.txt禁止（开启RobotsPolicy.Enforce时返回）
+var ErrRobotsDisallowed = errors.New("robots.txt禁止访问")
+
+// robots.txt相关默认值
+const (
+	defaultRobotsTTL = 24 * time.Hour   // 缓存有效期
+	robotsErrorTTL   = 10 * time.Minute // 服务器5xx时“全部禁止”的缓存有效期
+	robotsMaxSize    = 500 << 10        // 最多解析500KB（与Google一致）
+)
+
+// RobotsError 请求被robots.txt禁止，errors.Is(err, ErrRobotsDisallowed)为true
+type RobotsError struct {
+	URL       string // 被禁止的URL
+	UserAgent string // 匹配时使用的User-Agent
+}
+
+// Error 错误文本
+func (e *RobotsError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrRobotsDisallowed, e.URL)
+}
+
+// Unwrap 支持errors.Is(err, ErrRobotsDisallowed)
+func (e *RobotsError) Unwrap() error {
+	return ErrRobotsDisallowed
+}
+
+// RobotsPolicy robots.txt策略，零值表示不检查
+type RobotsPolicy struct {
+	Enforce   bool          // 每次请求前检查robots.txt，禁止访问时返回*RobotsError，不发出请求
+	UserAgent string        // 匹配规则时使用的User-Agent，空时使用请求头中的User-Agent
+	CacheTTL  time.Duration // robots.txt缓存有效期，<=0时默认24小时
+}
+
+// SetRobotsPolicy 设置实例的robots.txt策略
+// 示例：
+//
+//	ga := gather.NewGather("baidu", false) // 按Baiduspider的规则匹配
+//	ga.SetRobotsPolicy(gather.RobotsPolicy{Enforce: true})
+//	_, _, err := ga.Get(URL, "")
+//	if errors.Is(err, gather.ErrRobotsDisallowed) { ... }
+func (g *GatherStruct) SetRobotsPolicy(policy RobotsPolicy) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	g.robotsPolicy = policy
+	if g.robots == nil {
+		g.robots = newRobotsCache()
+	}
+}
+
+// SetRobotsPolicy 为池内所有实例设置robots.txt策略，池内实例共享同一份robots.txt缓存
+func (p *Pool) SetRobotsPolicy(policy RobotsPolicy) {
+	cache := newRobotsCache()
+	for _, ga := range p.pool {
+		ga.locker.Lock()
+		ga.robotsPolicy = policy
+		ga.robots = cache
+		ga.locker.Unlock()
+	}
+}
+
+// Robots 获取URL所在站点的robots.txt（优先使用缓存），通过实例自身的Client（代理、超时等配置）下载
+// 站点不存在robots.txt（4xx）时返回允许全部访问的规则，服务器错误（5xx）时返回禁止全部访问的规则
+func (g *GatherStruct) Robots(ctx context.Context, URL string) (*Robots, error) {
+	u, err := url.Parse(URL)
+	if err != nil {
+		return nil, err
+	}
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	return g.robotsFor(ctx, u)
+}
+
+// RobotsAllowed 判断实例（按RobotsPolicy.UserAgent或实例User-Agent）是否允许访问URL
+func (g *GatherStruct) RobotsAllowed(URL string) (bool, error) {
+	u, err := url.Parse(URL)
+	if err != nil {
+		return false, err
+	}
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	robots, err := g.robotsFor(context.Background(), u)
+	if err != nil {
+		return false, err
+	}
+	return robots.Allowed(g.robotsUserAgent(""), robotsPath(u)), nil
+}
+
+// CrawlDelay 返回URL所在站点robots.txt中对本实例生效的Crawl-delay，未设置时为0
+// 可配合HostLimiter.SetHostConfig使用：
+//
+//	delay, _ := ga.CrawlDelay(URL)
+//	limiter.SetHostConfig(host, gather.HostLimitConfig{MinDelay: delay})
+func (g *GatherStruct) CrawlDelay(URL string) (time.Duration, error) {
+	u, err := url.Parse(URL)
+	if err != nil {
+		return 0, err
+	}
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	robots, err := g.robotsFor(context.Background(), u)
+	if err != nil {
+		return 0, err
+	}
+	return robots.CrawlDelay(g.robotsUserAgent("")), nil
+}
+
+// Robots 同GatherStruct.Robots，使用池内任一空闲实例下载
+func (p *Pool) Robots(ctx context.Context, URL string) (*Robots, error) {
+	ga, release, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return ga.Robots(ctx, URL)
+}
+
+// robotsUserAgent 返回匹配规则时使用的User-Agent：策略指定优先，其次为请求头，最后为实例默认请求头
+func (g *GatherStruct) robotsUserAgent(requestUA string) string {
+	if g.robotsPolicy.UserAgent != "" {
+		return g.robotsPolicy.UserAgent
+	}
+	if requestUA != "" {
+		return requestUA
+	}
+	if v, ok := g.safeHeaders.Load("User-Agent"); ok {
+		if ua, ok := v.(string); ok {
+			return ua
+		}
+	}
+	return g.Headers["User-Agent"]
+}
+
+// checkRobots 按实例的robots.txt策略检查请求，调用方需持有g.locker
+func (g *GatherStruct) checkRobots(req *http.Request) error {
+	if !g.robotsPolicy.Enforce || req.URL.Path == "/robots.txt" {
+		return nil
+	}
+	robots, err := g.robotsFor(req.Context(), req.URL)
+	if err != nil {
+		return err
+	}
+	ua := g.robotsUserAgent(req.Header.Get("User-Agent"))
+	if !robots.Allowed(ua, robotsPath(req.URL)) {
+		return &RobotsError{URL: req.URL.String(), UserAgent: ua}
+	}
+	return nil
+}
+
+// robotsFor 从缓存获取或下载robots.txt，调用方需持有g.locker
+func (g *GatherStruct) robotsFor(ctx context.Context, u *url.URL) (*Robots, error) {
+	if g.robots == nil {
+		g.robots = newRobotsCache()
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("不支持的协议：%s", u.Scheme)
+	}
+	origin := u.Scheme + "://" + strings.ToLower(u.Host)
+	if robots := g.robots.get(origin); robots != nil {
+		return robots, nil
+	}
+	robots, ttl, err := g.fetchRobots(ctx, origin)
+	if err != nil {
+		return nil, err
+	}
+	if g.robotsPolicy.CacheTTL > 0 && ttl == defaultRobotsTTL {
+		ttl = g.robotsPolicy.CacheTTL
+	}
+	g.robots.set(origin, robots, ttl)
+	return robots, nil
+}
+
+// fetchRobots 通过实例的Client下载origin下的robots.txt，返回解析结果和缓存时长
+func (g *GatherStruct) fetchRobots(ctx context.Context, origin string) (*Robots, time.Duration, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if ua := g.robotsUserAgent(""); ua != "" {
+		req.Header.Set("User-Agent", ua)
+	}
+	resp, err := g.clientFor(req, StatusPolicy{}, RedirectPolicy{}).Do(req)
+	if err != nil {
+		return nil, 0, classifyError(err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		// 服务器错误：暂时视为全部禁止（与主流搜索引擎一致）
+		return &Robots{disallowAll: true}, robotsErrorTTL, nil
+	case resp.StatusCode >= 300:
+		// 不存在robots.txt（4xx）或跳转次数过多：全部允许
+		return &Robots{}, defaultRobotsTTL, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, robotsMaxSize))
+	if err != nil {
+		return nil, 0, classifyError(err)
+	}
+	if decoded, err := decodeResponseBody(resp.Header, body, robotsMaxSize); err == nil {
+		body = decoded
+	}
+	return ParseRobots(body), defaultRobotsTTL, nil
+}
+
+// robotsPath 返回用于规则匹配的路径（含查询参数）
+func robotsPath(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return path
+}
+
+// robotsCache 按站点（协议+主机）缓存的robots.txt，可在多个实例间共享
+type robotsCache struct {
+	mu      sync.Mutex
+	entries map[string]robotsEntry
+}
+
+// robotsEntry 缓存项
+type robotsEntry struct {
+	robots  *Robots
+	expires time.Time
+}
+
+// newRobotsCache 创建robots.txt缓存
+func newRobotsCache() *robotsCache {
+	return &robotsCache{entries: make(map[string]robotsEntry)}
+}
+
+// get 获取未过期的缓存，不存在或已过期时返回nil
+func (c *robotsCache) get(origin string) *Robots {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[origin]
+	if !ok || time.Now().After(entry.expires) {
+		return nil
+	}
+	return entry.robots
+}
+
+// set 写入缓存
+func (c *robotsCache) set(origin string, robots *Robots, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[origin] = robotsEntry{robots: robots, expires: time.Now().Add(ttl)}
+}
+
+// Robots 解析后的robots.txt，并发安全（只读）
+type Robots struct {
+	groups      []robotsGroup
+	sitemaps    []string
+	disallowAll bool // 服务器错误时全部禁止
+}
+
+// robotsGroup 一组User-agent及其规则
+type robotsGroup struct {
+	agents     []string // 小写的UA标识（已去掉版本号）
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// robotsRule 一条Allow/Disallow规则
+type robotsRule struct {
+	allow   bool
+	pattern string // 支持*通配符和$结尾锚定
+}
+
+// ParseRobots 解析robots.txt内容
+// 支持User-agent分组（连续多个User-agent共用规则）、Allow/Disallow（含*和$通配）、Crawl-delay和Sitemap
+func ParseRobots(data []byte) *Robots {
+	robots := &Robots{}
+	var current *robotsGroup
+	inAgents := false // 上一条有效记录是否为User-agent
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	scanner.Buffer(make([]byte, 0, 64*1024), robotsMaxSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				robots.groups = append(robots.groups, robotsGroup{})
+				current = &robots.groups[len(robots.groups)-1]
+			}
+			inAgents = true
+			agent, _, _ := strings.Cut(strings.ToLower(value), "/")
+			if agent = strings.TrimSpace(agent); agent != "" {
+				current.agents = append(current.agents, agent)
+			}
+		case "allow", "disallow":
+			inAgents = false
+			if current == nil || value == "" {
+				continue // 空的Disallow表示全部允许，无需记录
+			}
+			current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: normalizeRobotsPattern(value)})
+		case "crawl-delay":
+			inAgents = false
+			if current == nil {
+				continue
+			}
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		case "sitemap":
+			if value != "" {
+				robots.sitemaps = append(robots.sitemaps, value)
+			}
+		default:
+			// 其他指令（如Host、Clean-param）不影响分组
+		}
+	}
+	return robots
+}
+
+// normalizeRobotsPattern 规则路径规范化：补全开头的/，路径部分按URL.EscapedPath的方式重新编码
+// 使"/新闻"与"/%E6%96%B0%E9%97%BB"两种写法都能匹配请求路径
+func normalizeRobotsPattern(pattern string) string {
+	if !strings.HasPrefix(pattern, "/") && !strings.HasPrefix(pattern, "*") {
+		pattern = "/" + pattern
+	}
+	path, query, hasQuery := strings.Cut(pattern, "?")
+	// 通配符*不参与编码（EscapedPath会把*转义为%2A）
+	segments := strings.Split(path, "*")
+	for i, segment := range segments {
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segments[i] = (&url.URL{Path: unescaped}).EscapedPath()
+		}
+	}
+	path = strings.Join(segments, "*")
+	if hasQuery {
+		path += "?" + query
+	}
+	return path
+}
+
+// Allowed 判断userAgent是否允许访问path（如"/a/b?c=1"，也可传完整URL）
+// 规则选择与Google一致：最长匹配的规则生效，长度相同时Allow优先；没有匹配的规则时允许
+func (r *Robots) Allowed(userAgent, path string) bool {
+	if r == nil {
+		return true
+	}
+	if u, err := url.Parse(path); err == nil && u.Scheme != "" {
+		path = robotsPath(u)
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+	if r.disallowAll {
+		return false
+	}
+	if path == "" {
+		path = "/"
+	}
+	allowed, best := true, -1
+	for _, group := range r.match(userAgent) {
+		for _, rule := range group.rules {
+			if !robotsMatch(rule.pattern, path) {
+				continue
+			}
+			if n := len(rule.pattern); n > best || (n == best && rule.allow) {
+				best, allowed = n, rule.allow
+			}
+		}
+	}
+	return allowed
+}
+
+// CrawlDelay 返回对userAgent生效的Crawl-delay，未设置时为0
+func (r *Robots) CrawlDelay(userAgent string) time.Duration {
+	if r == nil {
+		return 0
+	}
+	for _, group := range r.match(userAgent) {
+		if group.crawlDelay > 0 {
+			return group.crawlDelay
+		}
+	}
+	return 0
+}
+
+// Sitemaps 返回robots.txt中声明的Sitemap地址
+func (r *Robots) Sitemaps() []string {
+	if r == nil {
+		return nil
+	}
+	return append([]string(nil), r.sitemaps...)
+}
+
+// match 返回对userAgent生效的分组
+// 从UA中提取产品标识（如"Mozilla/5.0 (compatible; Baiduspider/2.0; ...)"中的baiduspider），
+// 取与之匹配的最长User-agent标识所在的全部分组，均不匹配时使用"*"分组
+func (r *Robots) match(userAgent string) []robotsGroup {
+	tokens := userAgentTokens(userAgent)
+	bestLen := 0
+	var best, wildcard []robotsGroup
+	for _, group := range r.groups {
+		groupLen := 0
+		isWildcard := false
+		for _, agent := range group.agents {
+			if agent == "*" {
+				isWildcard = true
+				continue
+			}
+			for _, token := range tokens {
+				if (token == agent || strings.HasPrefix(token, agent+"-")) && len(agent) > groupLen {
+					groupLen = len(agent)
+				}
+			}
+		}
+		switch {
+		case groupLen > bestLen:
+			bestLen, best = groupLen, []robotsGroup{group}
+		case groupLen > 0 && groupLen == bestLen:
+			best = append(best, group)
+		case groupLen == 0 && isWildcard:
+			wildcard = append(wildcard, group)
+		}
+	}
+	if bestLen > 0 {
+		return best
+	}
+	return wildcard
+}
+
+// userAgentTokens 提取UA中的产品标识（小写、去掉版本号）
+func userAgentTokens(userAgent string) []string {
+	userAgent = strings.ToLower(strings.TrimSpace(userAgent))
+	if userAgent == "" {
+		return nil
+	}
+	tokens := []string{userAgent}
+	for _, field := range strings.FieldsFunc(userAgent, func(r rune) bool {
+		return r == ' ' || r == ';' || r == '(' || r == ')' || r == ','
+	}) {
+		name, _, _ := strings.Cut(field, "/")
+		if name = strings.Trim(name, "+"); name != "" {
+			tokens = append(tokens, name)
+		}
+	}
+	return tokens
+}
+
+// robotsMatch 判断path是否匹配规则（*匹配任意字符序列，结尾$表示必须匹配到路径末尾）
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	if len(parts) == 1 {
+		return !anchored || pos == len(path)
+	}
+	// 中间片段按最左匹配即可
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(path[pos:], part)
+		if i < 0 {
+			return false
+		}
+		pos += i + len(part)
+	}
+	last := parts[len(parts)-1]
+	if anchored {
+		return len(path)-pos >= len(last) && strings.HasSuffix(path, last)
+	}
+	return strings.Contains(path[pos:], last)
+}
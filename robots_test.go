@@ -0,0 +1,167 @@
+package gather
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const robotsTestText = `# 测试用robots.txt
+User-agent: Baiduspider
+Disallow: /private/
+Allow: /private/open
+Crawl-delay: 2
+
+User-agent: Googlebot
+User-agent: bingbot
+Disallow: /*.php$
+Disallow: /search?q=*&page=
+
+User-agent: Googlebot-Image
+Disallow: /
+
+User-agent: *
+Disallow: /admin
+Disallow: /新闻/
+Crawl-delay: 0.5
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+// TestParseRobots 测试规则解析、UA分组匹配及通配符
+func TestParseRobots(t *testing.T) {
+	robots := ParseRobots([]byte(robotsTestText))
+	const (
+		baiduUA  = "Mozilla/5.0 (compatible; Baiduspider/2.0;++http://www.baidu.com/search/spider.html)"
+		googleUA = "Mozilla/5.0 (compatible; Googlebot/2.1;+http://www.google.com/bot.html)"
+		bingUA   = "Mozilla/5.0 (compatible; bingbot/2.0;+http://www.bing.com/bingbot.htm)"
+		chromeUA = "Mozilla/5.0 (Windows NT 6.1; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/56.0.2924.87 Safari/537.36"
+	)
+	testCases := []struct {
+		ua, path string
+		want     bool
+	}{
+		{baiduUA, "/private/a", false},
+		{baiduUA, "/private/open/1", true}, // 更长的Allow优先
+		{baiduUA, "/admin", true},          // 有专属分组时不再使用*分组
+		{googleUA, "/index.php", false},
+		{googleUA, "/index.php?a=1", true}, // $锚定结尾
+		{googleUA, "/search?q=go&page=2", false},
+		{googleUA, "/search?q=go", true},
+		{bingUA, "/a/b.php", false},
+		{"Googlebot-Image/1.0", "/a.jpg", false}, // 更具体的分组优先
+		{chromeUA, "/admin/users", false},
+		{chromeUA, "/%E6%96%B0%E9%97%BB/1.html", false}, // 中文路径按编码后匹配
+		{chromeUA, "/index.php", true},
+		{chromeUA, "https://example.com/admin?x=1", false},
+		{chromeUA, "/robots.txt", true},
+	}
+	for _, tc := range testCases {
+		if got := robots.Allowed(tc.ua, tc.path); got != tc.want {
+			t.Errorf("Allowed(%q, %q)=%v，期望%v", tc.ua, tc.path, got, tc.want)
+		}
+	}
+
+	if d := robots.CrawlDelay(baiduUA); d != 2*time.Second {
+		t.Errorf("Baiduspider的Crawl-delay错误：%v", d)
+	}
+	if d := robots.CrawlDelay(chromeUA); d != 500*time.Millisecond {
+		t.Errorf("*分组的Crawl-delay错误：%v", d)
+	}
+	if d := robots.CrawlDelay(googleUA); d != 0 {
+		t.Errorf("Googlebot未设置Crawl-delay，实际：%v", d)
+	}
+	if sitemaps := robots.Sitemaps(); len(sitemaps) != 1 || sitemaps[0] != "https://example.com/sitemap.xml" {
+		t.Errorf("Sitemap解析错误：%v", sitemaps)
+	}
+
+	// 空的Disallow表示全部允许
+	if !ParseRobots([]byte("User-agent: *\nDisallow:\n")).Allowed(chromeUA, "/a") {
+		t.Error("空Disallow应允许全部访问")
+	}
+}
+
+// TestRobotsPolicy 测试robots.txt下载、缓存及禁止访问
+func TestRobotsPolicy(t *testing.T) {
+	var fetches atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write([]byte(robotsTestText))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ga := NewGather("baidu", false)
+	// 未开启检查时不限制
+	if html, _, err := ga.Get(server.URL+"/private/a", ""); err != nil || html != "ok" {
+		t.Errorf("未开启robots检查时应正常请求，实际：%q, %v", html, err)
+	}
+
+	ga.SetRobotsPolicy(RobotsPolicy{Enforce: true})
+	_, _, err := ga.Get(server.URL+"/private/a", "")
+	var re *RobotsError
+	if !errors.Is(err, ErrRobotsDisallowed) || !errors.As(err, &re) || re.UserAgent == "" {
+		t.Errorf("应返回RobotsError，实际：%v", err)
+	}
+	if html, _, err := ga.Get(server.URL+"/private/open", ""); err != nil || html != "ok" {
+		t.Errorf("允许的路径应正常请求，实际：%q, %v", html, err)
+	}
+	if d, err := ga.CrawlDelay(server.URL); err != nil || d != 2*time.Second {
+		t.Errorf("CrawlDelay错误：%v, %v", d, err)
+	}
+	if ok, err := ga.RobotsAllowed(server.URL + "/admin"); err != nil || !ok {
+		t.Errorf("Baiduspider应允许访问/admin，实际：%v, %v", ok, err)
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("robots.txt应只下载1次（缓存），实际%d次", n)
+	}
+
+	// Pool内实例共享缓存，策略指定的UA优先
+	pool := NewGatherUtilPool(map[string]string{"User-Agent": "test"}, "", 30, false, 3)
+	pool.SetRobotsPolicy(RobotsPolicy{Enforce: true, UserAgent: "Googlebot"})
+	for i := 0; i < 3; i++ {
+		if _, _, err := pool.Get(server.URL+"/index.php", ""); !errors.Is(err, ErrRobotsDisallowed) {
+			t.Errorf("Pool应按Googlebot规则禁止，实际：%v", err)
+		}
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("Pool内应共享robots.txt缓存，实际共下载%d次", n)
+	}
+	if robots, err := pool.Robots(context.Background(), server.URL); err != nil || robots.Allowed("Googlebot", "/a.php") {
+		t.Errorf("Pool.Robots结果错误：%v", err)
+	}
+}
+
+// TestRobotsPolicy_Status 测试robots.txt不存在及服务器错误时的处理
+func TestRobotsPolicy_Status(t *testing.T) {
+	status := http.StatusNotFound
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	ga := NewGather("chrome", false)
+	ga.SetRobotsPolicy(RobotsPolicy{Enforce: true})
+	if _, _, err := ga.Get(server.URL+"/a", ""); err != nil {
+		t.Errorf("robots.txt不存在时应全部允许，实际：%v", err)
+	}
+
+	status = http.StatusServiceUnavailable
+	ga2 := NewGather("chrome", false)
+	ga2.SetRobotsPolicy(RobotsPolicy{Enforce: true})
+	if _, _, err := ga2.Get(server.URL+"/a", ""); !errors.Is(err, ErrRobotsDisallowed) {
+		t.Errorf("robots.txt返回5xx时应暂时全部禁止，实际：%v", err)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"context"
+	"net/http"
+	"slices"
+)
+
+// StatusPolicy 状态码策略：决定哪些状态码视为成功（返回内容且err为nil）
+// 默认（零值）仅2xx视为成功，其余返回*StatusError（Response版本的方法仍会同时返回resp）
+// 适用场景：
+// 1. 接口在400/422中返回JSON错误详情，需要像正常响应一样读取
+// 2. 部分站点用404返回真实页面内容
+// 3. 登录等流程需要拿到302响应本身（Location/Set-Cookie）而不是跳转后的页面
+type StatusPolicy struct {
+	Accept               []int // 额外视为成功的状态码（2xx始终视为成功）
+	AcceptAll            bool  // 所有状态码均视为成功
+	TreatRedirectAsFinal bool  // 不跟随3xx跳转，直接把3xx响应作为最终结果返回（视为成功）
+}
+
+// accepts 判断状态码是否视为成功
+func (sp StatusPolicy) accepts(code int) bool {
+	if sp.AcceptAll || code >= 200 && code < 300 {
+		return true
+	}
+	if sp.TreatRedirectAsFinal && code >= 300 && code < 400 {
+		return true
+	}
+	return slices.Contains(sp.Accept, code)
+}
+
+// statusPolicyKey 请求级状态码策略在context中的键
+type statusPolicyKey struct{}
+
+// WithStatusPolicy 返回携带请求级状态码策略的ctx，配合XxxContext系列方法使用
+// 请求级策略会完整替换实例级策略（而不是合并）
+// 示例：
+//
+//	ctx := gather.WithStatusPolicy(context.Background(), gather.StatusPolicy{Accept: []int{400, 422}})
+//	html, _, err := ga.PostJsonUtilContext(ctx, URL, "", "", `{"id":1}`) // 422时html为错误详情，err为nil
+func WithStatusPolicy(ctx context.Context, policy StatusPolicy) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, statusPolicyKey{}, policy)
+}
+
+// SetStatusPolicy 设置实例级状态码策略
+// 示例：
+//
+//	ga.SetStatusPolicy(gather.StatusPolicy{Accept: []int{404}}) // 404也返回页面内容
+func (g *GatherStruct) SetStatusPolicy(policy StatusPolicy) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	policy.Accept = slices.Clone(policy.Accept)
+	g.statusPolicy = policy
+}
+
+// SetStatusPolicy 为池内所有实例设置状态码策略
+func (p *Pool) SetStatusPolicy(policy StatusPolicy) {
+	for _, ga := range p.pool {
+		ga.SetStatusPolicy(policy)
+	}
+}
+
+// statusPolicyFor 返回本次请求生效的状态码策略：请求级优先，否则使用实例级
+func (g *GatherStruct) statusPolicyFor(req *http.Request) StatusPolicy {
+	if policy, ok := req.Context().Value(statusPolicyKey{}).(StatusPolicy); ok {
+		return policy
+	}
+	return g.statusPolicy
+}
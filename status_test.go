@@ -0,0 +1,71 @@
+package gather
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestStatusPolicy 测试实例级/请求级状态码策略
+func TestStatusPolicy(t *testing.T) {
+	ga := NewGather("chrome", false)
+
+	// 默认策略：422返回StatusError，tuple版本不返回内容
+	html, _, err := ga.Get(testBaseURL+"/error_json", "")
+	var se *StatusError
+	if !errors.As(err, &se) || html != "" {
+		t.Errorf("默认策略下422应返回StatusError，实际：%q, %v", html, err)
+	}
+
+	// 请求级策略：接受422，返回错误详情
+	ctx := WithStatusPolicy(context.Background(), StatusPolicy{Accept: []int{http.StatusUnprocessableEntity}})
+	html, _, err = ga.GetContext(ctx, testBaseURL+"/error_json", "")
+	if err != nil || !strings.Contains(html, "invalid param") {
+		t.Errorf("请求级策略应接受422并返回内容，实际：%q, %v", html, err)
+	}
+
+	// 实例级策略：接受所有状态码
+	ga.SetStatusPolicy(StatusPolicy{AcceptAll: true})
+	resp, err := ga.GetResponse(testBaseURL+"/404", "", "")
+	if err != nil || resp.StatusCode != http.StatusNotFound || resp.Text() != "404 Not Found" {
+		t.Errorf("AcceptAll应返回404内容，实际：%v, %v", resp, err)
+	}
+
+	// 请求级策略替换实例级策略
+	_, err = ga.GetResponseContext(WithStatusPolicy(context.Background(), StatusPolicy{}), testBaseURL+"/404", "", "")
+	if !errors.As(err, &se) || se.StatusCode != http.StatusNotFound {
+		t.Errorf("请求级默认策略应覆盖实例级策略，实际：%v", err)
+	}
+}
+
+// TestStatusPolicy_RedirectAsFinal 测试把3xx作为最终结果
+func TestStatusPolicy_RedirectAsFinal(t *testing.T) {
+	ga := NewGather("chrome", false)
+	ctx := WithStatusPolicy(context.Background(), StatusPolicy{TreatRedirectAsFinal: true})
+	resp, err := ga.GetResponseContext(ctx, testBaseURL+"/redirect", "", "")
+	if err != nil {
+		t.Fatalf("3xx作为最终结果时不应返回错误：%v", err)
+	}
+	if resp.StatusCode != http.StatusFound || resp.Location() != "/get?from=redirect" {
+		t.Errorf("应返回302响应本身，实际：%d %s", resp.StatusCode, resp.Location())
+	}
+	if len(resp.Cookies()) == 0 || resp.Cookies()[0].Name != "redirect_id" {
+		t.Error("应能读取302响应中的Set-Cookie")
+	}
+
+	// 不影响实例的其他请求：默认仍跟随跳转
+	resp, err = ga.GetResponse(testBaseURL+"/redirect", "", "")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("未设置策略的请求应跟随跳转，实际：%v, %v", resp, err)
+	}
+
+	// Pool实例级策略
+	pool := NewGatherUtilPool(map[string]string{"User-Agent": "test"}, "", 30, false, 2)
+	pool.SetStatusPolicy(StatusPolicy{TreatRedirectAsFinal: true})
+	resp, err = pool.GetResponse(testBaseURL+"/redirect", "", "")
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Errorf("Pool策略应返回302响应，实际：%v, %v", resp, err)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// RequestTiming 单次请求各阶段耗时（基于net/http/httptrace）
+// 发生跳转时DNS/Connect/TLSHandshake/TTFB/Download为最后一跳的耗时，Total为包含所有跳转的总耗时
+// 复用连接时DNS、Connect、TLSHandshake为0
+type RequestTiming struct {
+	URL          string        // 请求URL（有响应时为最终URL）
+	StartedAt    time.Time     // 开始时间
+	DNS          time.Duration // DNS解析耗时
+	Connect      time.Duration // TCP连接耗时
+	TLSHandshake time.Duration // TLS握手耗时
+	TTFB         time.Duration // 从开始获取连接到收到响应首字节的耗时（含DNS、连接、握手、发送请求及服务器处理）
+	Download     time.Duration // 从收到首字节到响应体读取完毕的耗时
+	Total        time.Duration // 总耗时
+	ConnReused   bool          // 是否复用了空闲连接
+	RemoteAddr   string        // 服务器地址（ip:port，经代理时为代理地址）
+	Err          error         // 请求失败时的错误
+}
+
+// timingCallbackKey 请求级耗时回调在context中的键
+type timingCallbackKey struct{}
+
+// WithTimingCallback 返回携带请求级耗时回调的ctx，配合XxxContext系列方法使用
+// 每次实际发出请求（含每次重试）完成后回调一次，请求级回调会替换实例级回调
+func WithTimingCallback(ctx context.Context, callback func(RequestTiming)) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, timingCallbackKey{}, callback)
+}
+
+// SetTimingCallback 设置实例级耗时回调（nil表示取消），可用于统计慢请求、对比快/慢速配置
+// 示例：
+//
+//	ga.SetTimingCallback(func(t gather.RequestTiming) {
+//	    log.Printf("%s dns=%v connect=%v tls=%v ttfb=%v download=%v reused=%v",
+//	        t.URL, t.DNS, t.Connect, t.TLSHandshake, t.TTFB, t.Download, t.ConnReused)
+//	})
+func (g *GatherStruct) SetTimingCallback(callback func(RequestTiming)) {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	g.timingCallback = callback
+}
+
+// SetTimingCallback 为池内所有实例设置耗时回调（回调可能被并发调用）
+func (p *Pool) SetTimingCallback(callback func(RequestTiming)) {
+	for _, ga := range p.pool {
+		ga.SetTimingCallback(callback)
+	}
+}
+
+// LastTiming 返回实例最近一次实际发出请求的耗时（命中缓存时不更新）
+func (g *GatherStruct) LastTiming() RequestTiming {
+	g.locker.Lock()
+	defer g.locker.Unlock()
+	return g.lastTiming
+}
+
+// timingCallbackFor 返回本次请求生效的耗时回调：请求级优先，否则使用实例级
+func (g *GatherStruct) timingCallbackFor(req *http.Request) func(RequestTiming) {
+	if callback, ok := req.Context().Value(timingCallbackKey{}).(func(RequestTiming)); ok {
+		return callback
+	}
+	return g.timingCallback
+}
+
+// connTrace 通过httptrace收集请求各阶段的时间点，并发安全
+// 同一请求发生跳转时，每一跳开始获取连接（GetConn）时重置上一跳的时间点
+type connTrace struct {
+	mu                        sync.Mutex
+	start                     time.Time // 整个请求的开始时间
+	getConn                   time.Time // 本跳开始获取连接的时间
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	gotConn, wroteRequest     time.Time
+	firstByte, bodyDone       time.Time
+	reused                    bool
+	remoteAddr                string
+}
+
+// newConnTrace 创建时间点收集器
+func newConnTrace() *connTrace {
+	return &connTrace{start: time.Now()}
+}
+
+// begin 重新设置开始时间（实际发出请求前调用）
+func (t *connTrace) begin() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.start = time.Now()
+}
+
+// connTraceKey 请求context中保存connTrace的键
+type connTraceKey struct{}
+
+// withContext 返回挂载了本收集器的请求
+func (t *connTrace) withContext(req *http.Request) *http.Request {
+	ctx := context.WithValue(req.Context(), connTraceKey{}, t)
+	return req.WithContext(httptrace.WithClientTrace(ctx, t.clientTrace()))
+}
+
+// connTraceFrom 取出请求context中的耗时收集器，没有时返回nil
+func connTraceFrom(ctx context.Context) *connTrace {
+	t, _ := ctx.Value(connTraceKey{}).(*connTrace)
+	return t
+}
+
+// clientTrace 返回记录时间点的ClientTrace
+func (t *connTrace) clientTrace() *httptrace.ClientTrace {
+	mark := func(field *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if field.IsZero() {
+			*field = time.Now()
+		}
+	}
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.getConn = time.Now()
+			t.dnsStart, t.dnsDone = time.Time{}, time.Time{}
+			t.connectStart, t.connectDone = time.Time{}, time.Time{}
+			t.tlsStart, t.tlsDone = time.Time{}, time.Time{}
+			t.gotConn, t.wroteRequest = time.Time{}, time.Time{}
+			t.firstByte, t.bodyDone = time.Time{}, time.Time{}
+			t.reused, t.remoteAddr = false, ""
+		},
+		DNSStart:          func(httptrace.DNSStartInfo) { mark(&t.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { mark(&t.dnsDone) },
+		ConnectStart:      func(string, string) { mark(&t.connectStart) },
+		ConnectDone:       func(string, string, error) { mark(&t.connectDone) },
+		TLSHandshakeStart: func() { mark(&t.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { mark(&t.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			mark(&t.gotConn)
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.reused = info.Reused
+			if info.Conn != nil {
+				t.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { mark(&t.wroteRequest) },
+		GotFirstResponseByte: func() { mark(&t.firstByte) },
+	}
+}
+
+// done 记录响应体读取完毕的时间
+func (t *connTrace) done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.bodyDone.IsZero() {
+		t.bodyDone = time.Now()
+	}
+}
+
+// timing 汇总为RequestTiming
+func (t *connTrace) timing(url string, err error) RequestTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	end := t.bodyDone
+	if end.IsZero() {
+		end = time.Now()
+	}
+	hopStart := t.getConn
+	if hopStart.IsZero() {
+		hopStart = t.start
+	}
+	return RequestTiming{
+		URL:          url,
+		StartedAt:    t.start,
+		DNS:          timeSpan(t.dnsStart, t.dnsDone),
+		Connect:      timeSpan(t.connectStart, t.connectDone),
+		TLSHandshake: timeSpan(t.tlsStart, t.tlsDone),
+		TTFB:         timeSpan(hopStart, t.firstByte),
+		Download:     timeSpan(t.firstByte, end),
+		Total:        end.Sub(t.start),
+		ConnReused:   t.reused,
+		RemoteAddr:   t.remoteAddr,
+		Err:          err,
+	}
+}
+
+// timeSpan 两个时间点之间的耗时，任一时间点缺失时为0
+func timeSpan(from, to time.Time) time.Duration {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return 0
+	}
+	return to.Sub(from)
+}
@@ -0,0 +1,78 @@
+package gather
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestRequestTiming 测试各阶段耗时、连接复用及回调
+func TestRequestTiming(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond) // 模拟服务器处理时间
+		w.Write([]byte(strings.Repeat("a", 1024)))
+	}))
+	defer server.Close()
+
+	ga := NewGather("chrome", false)
+	var calls []RequestTiming
+	ga.SetTimingCallback(func(timing RequestTiming) { calls = append(calls, timing) })
+
+	resp, err := ga.GetResponse(server.URL+"/a", "", "")
+	if err != nil {
+		t.Fatalf("请求失败：%v", err)
+	}
+	first := ga.LastTiming()
+	if first.TTFB < 30*time.Millisecond || first.Total < first.TTFB || first.RemoteAddr == "" || first.URL != server.URL+"/a" {
+		t.Errorf("耗时记录错误：%+v", first)
+	}
+	if resp.Timing.Total != first.Total {
+		t.Errorf("Response.Timing应与LastTiming一致：%+v", resp.Timing)
+	}
+
+	// 第二次请求复用连接
+	ga.Get(server.URL+"/b", "")
+	second := ga.LastTiming()
+	if !second.ConnReused || second.Connect != 0 || second.DNS != 0 {
+		t.Errorf("第二次请求应复用连接：%+v", second)
+	}
+	if len(calls) != 2 {
+		t.Errorf("实例级回调应调用2次，实际%d次", len(calls))
+	}
+
+	// 请求级回调替换实例级回调
+	var requestCalls atomic.Int32
+	ctx := WithTimingCallback(context.Background(), func(RequestTiming) { requestCalls.Add(1) })
+	ga.GetContext(ctx, server.URL, "")
+	if requestCalls.Load() != 1 || len(calls) != 2 {
+		t.Errorf("请求级回调应替换实例级回调：%d, %d", requestCalls.Load(), len(calls))
+	}
+
+	// 失败请求也会记录错误
+	ga.Get("http://127.0.0.1:1/", "")
+	if last := ga.LastTiming(); last.Err == nil || !errors.Is(calls[len(calls)-1].Err, last.Err) {
+		t.Errorf("失败请求应记录错误：%+v", last)
+	}
+}
+
+// TestPool_TimingCallback 测试Pool的耗时回调
+func TestPool_TimingCallback(t *testing.T) {
+	var count atomic.Int32
+	pool := NewGatherUtilPool(map[string]string{"User-Agent": "test"}, "", 30, false, 2)
+	pool.SetTimingCallback(func(timing RequestTiming) {
+		if timing.Total > 0 {
+			count.Add(1)
+		}
+	})
+	for i := 0; i < 3; i++ {
+		pool.Get(testBaseURL+"/get", "")
+	}
+	if count.Load() != 3 {
+		t.Errorf("Pool回调应调用3次，实际%d次", count.Load())
+	}
+}
@@ -9,38 +9,71 @@ package gather
 import (
 	"bytes"
 	"compress/gzip"
-	"fmt"
+	"context"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 )
 
+// writeFileAtomic 先写入同目录下的临时文件再重命名，写入中断不会损坏已有文件
+// 临时文件由os.CreateTemp创建，权限为0600
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
 // Ungzip 自动判断并解压GZIP数据
 // 逻辑：是标准GZIP则解压，否则直接返回原数据，无任何打印，仅解压失败返回原错误
 func Ungzip(data []byte) (string, error) {
+	uncompressedData, err := ungzipBytes(data, -1)
+	if err != nil {
+		return "", err
+	}
+	return string(uncompressedData), nil
+}
+
+// ungzipBytes Ungzip的字节版本，供Response组装时使用
+// limit>=0时解压结果超过limit字节返回ErrBodyTooLarge
+func ungzipBytes(data []byte, limit int64) ([]byte, error) {
 	// 空数据直接返回
 	if len(data) == 0 {
-		return "", nil
+		return data, nil
 	}
 	// 通过标准GZIP魔数0x1F8B判断，不依赖错误信息，可靠无歧义
 	if len(data) < 2 || data[0] != 0x1F || data[1] != 0x8B {
-		return string(data), nil
+		return data, nil
 	}
 	// 是GZIP，执行解压
 	reader, err := gzip.NewReader(bytes.NewReader(data))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer reader.Close()
 
-	uncompressedData, err := io.ReadAll(reader)
-	if err != nil {
-		return "", err
-	}
-	return string(uncompressedData), nil
+	return readAllLimit(reader, limit)
 }
 
 // newHttpRequest 构建HTTP请求，安全加载Header，不污染全局，防御类型异常
-func (g *GatherStruct) newHttpRequest(method, URL, refererURL, cookies string, body io.Reader) (*http.Request, error) {
+// ctx随请求一同传递，取消ctx即可中断进行中的请求（nil视为context.Background()）
+func (g *GatherStruct) newHttpRequest(ctx context.Context, method, URL, refererURL, cookies string, body io.Reader) (*http.Request, error) {
 	// 核心实例空值校验，直接panic暴露严重问题
 	if g == nil {
 		panic("FATAL: GatherStruct 未初始化，请先通过 NewGather 系列函数创建")
@@ -50,7 +83,10 @@ func (g *GatherStruct) newHttpRequest(method, URL, refererURL, cookies string, b
 	}
 
 	// 创建请求，直接返回标准库原始错误
-	req, err := http.NewRequest(method, URL, body)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, URL, body)
 	if err != nil {
 		return nil, err
 	}
@@ -67,6 +103,15 @@ func (g *GatherStruct) newHttpRequest(method, URL, refererURL, cookies string, b
 		return true
 	})
 
+	// 只声明能够解码的编码，避免服务器返回无法处理的压缩格式
+	if acceptEncoding := requestHeaders.Get("Accept-Encoding"); acceptEncoding != "" {
+		if filtered := filterAcceptEncoding(acceptEncoding); filtered != "" {
+			requestHeaders.Set("Accept-Encoding", filtered)
+		} else {
+			requestHeaders.Del("Accept-Encoding")
+		}
+	}
+
 	// 临时设置Referer，仅本次请求生效
 	if refererURL != "" {
 		requestHeaders.Set("Referer", refererURL)
@@ -80,38 +125,12 @@ func (g *GatherStruct) newHttpRequest(method, URL, refererURL, cookies string, b
 	return req, nil
 }
 
-// request 执行HTTP请求，自动解压GZIP，无多余打印，错误直接返回
+// request 执行HTTP请求，自动解码压缩内容，无多余打印，错误直接返回
+// 内部委托给do，仅把Response转换为(html, redirectURL, err)三元组
 func (g *GatherStruct) request(req *http.Request) (html, redirectURL string, err error) {
-	// 核心参数空值校验
-	if req == nil {
-		panic("FATAL: HTTP请求对象为nil，无法执行请求")
-	}
-	if g == nil || g.Client == nil {
-		panic("FATAL: GatherStruct/Client 未初始化，无法执行请求")
-	}
-
-	// 执行请求，直接返回原始错误
-	resp, err := g.Client.Do(req)
+	resp, err := g.do(req)
 	if err != nil {
 		return "", "", err
 	}
-	defer resp.Body.Close()
-
-	// 非2xx状态码，返回自定义状态错误（无原始错误可返回）
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", "", fmt.Errorf("http状态码:%d", resp.StatusCode)
-	}
-
-	// 读取响应体，直接返回原始错误
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", "", err
-	}
-
-	// 静默解压，失败则直接使用原始数据，无任何日志打印
-	html, _ = Ungzip(respBody)
-	// 获取最终跳转后的URL
-	redirectURL = resp.Request.URL.String()
-
-	return html, redirectURL, nil
+	return resp.Text(), resp.FinalURL, nil
 }
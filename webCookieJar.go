@@ -7,61 +7,707 @@
 package gather
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
-// cookie的保存对象
-type webCookieJar struct {
+// WebCookieJar cookie的保存对象（即GatherStruct.J），按RFC 6265处理域名匹配和路径匹配
+//  1. 未指定Domain属性的Cookie为host-only，只发送给设置它的主机
+//  2. 指定了Domain属性的Cookie发送给该域名及其所有子域名（如passport.example.com设置的.example.com可发送给www.example.com）
+//  3. 只发送路径匹配的Cookie，路径更长的排在前面，路径长度相同时先创建的排在前面
+//  4. 名称、域名、路径都相同的Cookie视为同一个，后设置的替换先设置的（保留原创建时间）
+//  5. 带Max-Age或Expires的为持久Cookie，到期后不再发送；Max-Age<=0或Expires为过去时间表示删除
+//  6. 两者都没有的为会话Cookie，在实例存续期间一直有效，可通过EndSession模拟关闭浏览器
+//  7. Secure Cookie只能由https设置、只发送给https；http响应不能覆盖同名的Secure Cookie
+//  8. __Secure-前缀必须带Secure，__Host-前缀还必须为host-only且Path=/，否则拒绝
+//  9. 请求带Referer且与目标跨站（协议或注册域名不同）时：SameSite=Strict不发送，
+//     SameSite=Lax只在GET/HEAD等安全方法时发送，未声明SameSite的按None处理（与旧版浏览器一致）
+//  10. Domain属性为公共后缀（如com.cn、gov.cn、github.io，见PublicSuffixList）的超级Cookie被拒绝，
+//     Domain恰好等于当前主机时按host-only处理
+//
+// 与RFC 6265一致，Cookie不区分端口
+// 除实现http.CookieJar外，还提供查看和管理Cookie的方法（All、Get、Delete、ClearDomain、Clear、Len），
+// 所有方法都是并发安全的，可在请求进行中调用，返回的Cookie均为副本
+type WebCookieJar struct {
 	lk            sync.Mutex
-	cookies       map[string][]*http.Cookie
+	entries       map[string]map[string]*jarEntry // key1=Cookie所属域名（小写、不含端口），key2=名称+路径
+	nextSeq       uint64                          // 创建序号，创建时间相同时用于稳定排序
+	now           func() time.Time                // 时钟，默认time.Now（见SetClock）
+	lastPurge     time.Time                       // 上次清理过期Cookie的时间
+	autoSavePath  string                          // 自动保存的文件路径，空表示不自动保存（见SetAutoSave）
+	suffixes      *PublicSuffixList               // 公共后缀列表，nil表示使用DefaultPublicSuffixList（见SetPublicSuffixList）
+	saveMu        sync.Mutex                      // 串行化文件写入
 	cookieLogOpen bool
+	logger        *slog.Logger // Cookie变更日志（见GatherStruct.SetLogger），值会脱敏
+}
+
+// cookiePurgeInterval 清理过期Cookie的最小间隔：读写jar时距上次清理超过该间隔则顺带清理一次
+const cookiePurgeInterval = time.Minute
+
+// jarEntry 保存在jar中的单个Cookie
+type jarEntry struct {
+	Name       string
+	Value      string
+	Quoted     bool   // 值在Set-Cookie中是否带双引号，发送时保持原样
+	Domain     string // 所属域名：host-only时为设置它的主机，否则为Domain属性（不含前导点）
+	Path       string
+	HostOnly   bool
+	Secure     bool          // 只通过https发送
+	HttpOnly   bool          // 禁止脚本访问（采集场景只保存该属性，用于持久化和导出）
+	SameSite   http.SameSite // 跨站请求时的发送规则
+	Persistent bool          // 持久Cookie（带Max-Age或Expires），否则为会话Cookie
+	Expires    time.Time     // 过期时间，会话Cookie为零值
+	Creation   time.Time
+	LastAccess time.Time
+	seq        uint64
+	imported   bool // 由ImportCookieString导入，服务器下发同名Cookie时被替换
 }
 
-func newWebCookieJar(isCookieLogOpen bool) *webCookieJar {
-	jar := new(webCookieJar)
+// id 同一域名下Cookie的唯一标识
+func (e *jarEntry) id() string {
+	return e.Name + ";" + e.Path
+}
+
+// expired 判断Cookie在now时是否已过期（会话Cookie不会过期）
+func (e *jarEntry) expired(now time.Time) bool {
+	return e.Persistent && !now.Before(e.Expires)
+}
+
+func newWebCookieJar(isCookieLogOpen bool) *WebCookieJar {
+	jar := new(WebCookieJar)
 	jar.cookieLogOpen = isCookieLogOpen
-	jar.cookies = make(map[string][]*http.Cookie)
+	jar.entries = make(map[string]map[string]*jarEntry)
+	jar.now = time.Now
 	return jar
 }
-func (j *webCookieJar) SetCookies(u *url.URL, newCookies []*http.Cookie) {
+
+// SetClock 替换jar使用的时钟（nil恢复为time.Now），用于测试过期逻辑或按指定时间回放
+func (j *WebCookieJar) SetClock(now func() time.Time) {
 	j.lk.Lock()
 	defer j.lk.Unlock()
-	//如果原来有了就覆盖,根据host和Path判断
-	oldCookies := j.cookies[u.Host]
-	if j.cookieLogOpen {
-		log.Println("COOKIE变更:", u.String())
-	}
-	for newIndex := 0; newIndex < len(newCookies); newIndex++ {
-		isFound := false
-		for oldIndex := 0; oldIndex < len(oldCookies); oldIndex++ {
-			if oldCookies[oldIndex].Name == newCookies[newIndex].Name &&
-				oldCookies[oldIndex].Path == newCookies[newIndex].Path {
-				//原来有的，就直接替换就可以
-				oldCookies[oldIndex] = newCookies[newIndex]
-				if j.cookieLogOpen {
-					log.Println("替换cookie:", newCookies[newIndex].String())
-				}
-				isFound = true
-				break
+	if now == nil {
+		now = time.Now
+	}
+	j.now = now
+}
+
+// PurgeExpired 立即清理所有已过期的Cookie，返回清理的数量
+// 读写jar时也会按cookiePurgeInterval的间隔自动清理，一般无需手动调用
+func (j *WebCookieJar) PurgeExpired() int {
+	j.lk.Lock()
+	removed := j.purgeExpired(j.now())
+	j.lk.Unlock()
+	if removed > 0 {
+		j.changed()
+	}
+	return removed
+}
+
+// EndSession 删除所有会话Cookie（模拟关闭浏览器），持久Cookie保留，返回删除的数量
+func (j *WebCookieJar) EndSession() int {
+	j.lk.Lock()
+	removed := j.removeIf(func(e *jarEntry) bool { return !e.Persistent })
+	j.lk.Unlock()
+	if removed > 0 {
+		j.changed()
+	}
+	return removed
+}
+
+// purgeExpired 清理已过期的Cookie，调用方需持有j.lk
+func (j *WebCookieJar) purgeExpired(now time.Time) int {
+	j.lastPurge = now
+	return j.removeIf(func(e *jarEntry) bool { return e.expired(now) })
+}
+
+// maybePurge 距上次清理超过cookiePurgeInterval时清理过期Cookie，返回清理的数量，调用方需持有j.lk
+func (j *WebCookieJar) maybePurge(now time.Time) int {
+	if now.Sub(j.lastPurge) < cookiePurgeInterval {
+		return 0
+	}
+	return j.purgeExpired(now)
+}
+
+// removeIf 删除满足条件的Cookie并清理空域名，返回删除的数量，调用方需持有j.lk
+func (j *WebCookieJar) removeIf(match func(e *jarEntry) bool) int {
+	removed := 0
+	for domain, domainEntries := range j.entries {
+		for id, e := range domainEntries {
+			if match(e) {
+				delete(domainEntries, id)
+				removed++
 			}
 		}
-		if !isFound {
-			oldCookies = append(oldCookies, newCookies[newIndex])
-			if j.cookieLogOpen {
-				log.Println("添加cookie:", newCookies[newIndex].String())
+		if len(domainEntries) == 0 {
+			delete(j.entries, domain)
+		}
+	}
+	return removed
+}
+
+// SetCookies 实现http.CookieJar，保存响应中下发的Cookie（不符合域名规则的Cookie被忽略）
+func (j *WebCookieJar) SetCookies(u *url.URL, newCookies []*http.Cookie) {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return
+	}
+	host, ok := canonicalCookieHost(u.Host)
+	if !ok {
+		return
+	}
+	j.lk.Lock()
+	j.logCookie("COOKIE变更", u, nil)
+	now := j.now()
+	changed := j.maybePurge(now) > 0
+	for _, c := range newCookies {
+		e, ok := j.newEntry(c, u.Scheme, host, u.Path, now)
+		if ok && !e.Secure && u.Scheme != "https" && j.shadowsSecure(e) {
+			ok = false
+		}
+		if !ok {
+			j.logCookie("忽略cookie", u, c)
+			continue
+		}
+		// 服务器下发的Cookie替换手工导入的同名Cookie（导入时无法得知其真实的域名属性）
+		if j.dropImported(e) {
+			changed = true
+		}
+		if e.expired(now) {
+			// Max-Age<=0或Expires为过去时间：删除已有的同名Cookie
+			if j.remove(e.Domain, e.id()) {
+				j.logCookie("删除cookie", u, c)
+				changed = true
 			}
+			continue
+		}
+		if old := j.entries[e.Domain][e.id()]; old != nil {
+			//原来有的，就直接替换，保留原创建时间
+			e.Creation = old.Creation
+			j.logCookie("替换cookie", u, c)
+		} else {
+			e.Creation = now
+			j.logCookie("添加cookie", u, c)
 		}
+		e.LastAccess = now
+		j.store(e)
+		changed = true
+	}
+	j.lk.Unlock()
+	if changed {
+		j.changed()
 	}
-	j.cookies[u.Host] = oldCookies
 }
-func (j *webCookieJar) Cookies(u *url.URL) []*http.Cookie {
-	cookies := j.cookies[u.Host]
-	/*log.Println("URL:", u.String())
-	for i, c := range cookies {
-		log.Println("cookie:", i, c.String())
-	}*/
+
+// ImportCookieString 把从浏览器复制的Cookie请求头（如"a=1; b=2"，可带"Cookie:"前缀）导入jar，返回导入的数量
+// domain决定Cookie的发送范围：带前导点（如.example.com）时发送给该域名及其所有子域名，否则只发送给该主机
+// 导入的Cookie为会话Cookie，路径为"/"；之后服务器下发同名Cookie（无论其Domain、Path属性是否与导入时相同）会替换导入的值
+// 与GetUtil/PostUtil的cookies参数只对单次请求生效不同，导入后后续所有请求都会携带，且随服务器的Set-Cookie更新
+// 示例：
+//
+//	ga.J.ImportCookieString(".example.com", "sid=abc123; token=xyz")
+//	html, _, err := ga.Get("https://www.example.com/user", "")
+func (j *WebCookieJar) ImportCookieString(domain, cookieHeader string) (int, error) {
+	hostOnly := !strings.HasPrefix(domain, ".")
+	host, ok := canonicalCookieHost(strings.TrimPrefix(domain, "."))
+	if !ok {
+		return 0, fmt.Errorf("无效的域名：%q", domain)
+	}
+	j.lk.Lock()
+	superCookie := !hostOnly && j.publicSuffixes().IsPublicSuffix(host)
+	j.lk.Unlock()
+	if superCookie {
+		return 0, fmt.Errorf("不能为公共后缀%q设置Cookie", domain)
+	}
+	cookieHeader = strings.TrimSpace(cookieHeader)
+	if len(cookieHeader) > len("cookie:") && strings.EqualFold(cookieHeader[:len("cookie:")], "cookie:") {
+		cookieHeader = cookieHeader[len("cookie:"):]
+	}
+
+	j.lk.Lock()
+	now := j.now()
+	count := 0
+	for _, pair := range strings.Split(cookieHeader, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		value, quoted := strings.TrimSpace(value), false
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value, quoted = value[1:len(value)-1], true
+		}
+		e := &jarEntry{
+			Name: name, Value: value, Quoted: quoted, Domain: host, Path: "/", HostOnly: hostOnly,
+			Creation: now, LastAccess: now, imported: true,
+		}
+		if old := j.entries[e.Domain][e.id()]; old != nil {
+			e.Creation = old.Creation
+		}
+		j.store(e)
+		count++
+	}
+	j.lk.Unlock()
+	if count > 0 {
+		j.changed()
+	}
+	return count, nil
+}
+
+// All 返回jar中所有未过期的Cookie副本（按域名、路径、名称排序），可用于检查登录是否成功
+// 返回的Cookie带完整属性：host-only的Domain为主机名，域Cookie的Domain带前导点（如.example.com）；会话Cookie的Expires为零值
+func (j *WebCookieJar) All() []*http.Cookie {
+	j.lk.Lock()
+	defer j.lk.Unlock()
+	saved := j.snapshot(j.now())
+	cookies := make([]*http.Cookie, 0, len(saved))
+	for _, c := range saved {
+		cookies = append(cookies, savedToCookie(c))
+	}
 	return cookies
 }
+
+// Get 返回指定域名下名为name的Cookie副本（如取CSRF token），不存在或已过期时返回false
+// domain为Cookie所属的域名，带不带前导点均可；同名Cookie有多个路径时返回路径最长的
+// 示例：
+//
+//	if c, ok := ga.J.Get("www.example.com", "csrftoken"); ok {
+//	    ga.PostUtil(URL, "", "", map[string]string{"csrf": c.Value})
+//	}
+func (j *WebCookieJar) Get(domain, name string) (*http.Cookie, bool) {
+	domain = normalizeCookieDomain(domain)
+	j.lk.Lock()
+	defer j.lk.Unlock()
+	now := j.now()
+	var found *jarEntry
+	for _, e := range j.entries[domain] {
+		if e.Name != name || e.expired(now) {
+			continue
+		}
+		if found == nil || len(e.Path) > len(found.Path) || (len(e.Path) == len(found.Path) && e.seq < found.seq) {
+			found = e
+		}
+	}
+	if found == nil {
+		return nil, false
+	}
+	return savedToCookie(savedCookie{
+		Name: found.Name, Value: found.Value, Quoted: found.Quoted, Domain: found.Domain, Path: found.Path,
+		HostOnly: found.HostOnly, Secure: found.Secure, HttpOnly: found.HttpOnly, SameSite: sameSiteString(found.SameSite),
+		Persistent: found.Persistent, Expires: found.Expires,
+	}), true
+}
+
+// Delete 删除指定域名、名称、路径的Cookie（domain带不带前导点均可，path为空时按"/"处理），返回是否存在
+func (j *WebCookieJar) Delete(domain, name, path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	j.lk.Lock()
+	removed := j.remove(normalizeCookieDomain(domain), name+";"+path)
+	j.lk.Unlock()
+	if removed {
+		j.changed()
+	}
+	return removed
+}
+
+// ClearDomain 删除属于domain及其所有子域名的Cookie（如清除某个网站的登录状态），返回删除的数量
+func (j *WebCookieJar) ClearDomain(domain string) int {
+	domain = normalizeCookieDomain(domain)
+	j.lk.Lock()
+	removed := j.removeIf(func(e *jarEntry) bool { return cookieDomainMatch(e.Domain, domain) })
+	j.lk.Unlock()
+	if removed > 0 {
+		j.changed()
+	}
+	return removed
+}
+
+// Clear 删除所有Cookie，返回删除的数量
+func (j *WebCookieJar) Clear() int {
+	j.lk.Lock()
+	removed := j.removeIf(func(*jarEntry) bool { return true })
+	j.lk.Unlock()
+	if removed > 0 {
+		j.changed()
+	}
+	return removed
+}
+
+// Len 返回jar中未过期的Cookie数量
+func (j *WebCookieJar) Len() int {
+	j.lk.Lock()
+	defer j.lk.Unlock()
+	now := j.now()
+	n := 0
+	for _, domainEntries := range j.entries {
+		for _, e := range domainEntries {
+			if !e.expired(now) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// normalizeCookieDomain 把用户传入的域名转为jar中的存储形式（小写、去掉前导点、端口和末尾的点）
+func normalizeCookieDomain(domain string) string {
+	host, _ := canonicalCookieHost(strings.TrimPrefix(strings.TrimSpace(domain), "."))
+	return host
+}
+
+// savedToCookie 转换为带完整属性的http.Cookie
+func savedToCookie(c savedCookie) *http.Cookie {
+	cookie := &http.Cookie{
+		Name: c.Name, Value: c.Value, Quoted: c.Quoted, Domain: c.Domain, Path: c.Path,
+		Secure: c.Secure, HttpOnly: c.HttpOnly, SameSite: parseSameSite(c.SameSite),
+	}
+	if !c.HostOnly {
+		cookie.Domain = "." + c.Domain
+	}
+	if c.Persistent {
+		cookie.Expires = c.Expires
+	}
+	return cookie
+}
+
+// dropImported 删除与服务器下发的Cookie同名且域名互相匹配的导入Cookie（不含键完全相同的），返回是否删除，调用方需持有j.lk
+// 导入时路径固定为"/"，并非真实路径，因此不比较路径
+func (j *WebCookieJar) dropImported(e *jarEntry) bool {
+	dropped := false
+	for domain, domainEntries := range j.entries {
+		if !cookieDomainMatch(domain, e.Domain) && !cookieDomainMatch(e.Domain, domain) {
+			continue
+		}
+		for id, old := range domainEntries {
+			if old.imported && old.Name == e.Name && !(domain == e.Domain && id == e.id()) {
+				j.remove(domain, id)
+				dropped = true
+			}
+		}
+	}
+	return dropped
+}
+
+// store 保存Cookie，替换名称、域名、路径相同的已有Cookie时沿用其创建序号，调用方需持有j.lk
+func (j *WebCookieJar) store(e *jarEntry) {
+	domainEntries := j.entries[e.Domain]
+	if domainEntries == nil {
+		domainEntries = make(map[string]*jarEntry)
+		j.entries[e.Domain] = domainEntries
+	}
+	if old := domainEntries[e.id()]; old != nil {
+		e.seq = old.seq
+	} else {
+		e.seq = j.nextSeq
+		j.nextSeq++
+	}
+	domainEntries[e.id()] = e
+}
+
+// remove 删除指定域名下的Cookie并清理空域名，返回是否存在，调用方需持有j.lk
+func (j *WebCookieJar) remove(domain, id string) bool {
+	domainEntries := j.entries[domain]
+	if _, exist := domainEntries[id]; !exist {
+		return false
+	}
+	delete(domainEntries, id)
+	if len(domainEntries) == 0 {
+		delete(j.entries, domain)
+	}
+	return true
+}
+
+// Cookies 实现http.CookieJar，返回应发送给u的Cookie（按路径长度降序、创建时间升序）
+// 不带Referer的请求视为同站请求，不受SameSite限制
+func (j *WebCookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.cookies(u, nil, "")
+}
+
+// cookies 返回应发送给u的Cookie，referer不为nil时按referer与u是否跨站应用SameSite规则
+func (j *WebCookieJar) cookies(u *url.URL, referer *url.URL, method string) []*http.Cookie {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+	host, ok := canonicalCookieHost(u.Host)
+	if !ok {
+		return nil
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	j.lk.Lock()
+	now := j.now()
+	purged := j.maybePurge(now)
+	crossSite := referer != nil && !sameSite(j.publicSuffixes(), referer, u)
+
+	var selected []*jarEntry
+	for _, domain := range cookieDomainCandidates(host) {
+		for _, e := range j.entries[domain] {
+			if e.HostOnly && e.Domain != host {
+				continue
+			}
+			if e.expired(now) || (e.Secure && u.Scheme != "https") {
+				continue
+			}
+			if crossSite && !sameSiteAllows(e.SameSite, method) {
+				continue
+			}
+			if !cookiePathMatch(path, e.Path) {
+				continue
+			}
+			selected = append(selected, e)
+		}
+	}
+	sort.Slice(selected, func(a, b int) bool {
+		if len(selected[a].Path) != len(selected[b].Path) {
+			return len(selected[a].Path) > len(selected[b].Path)
+		}
+		if !selected[a].Creation.Equal(selected[b].Creation) {
+			return selected[a].Creation.Before(selected[b].Creation)
+		}
+		return selected[a].seq < selected[b].seq
+	})
+
+	cookies := make([]*http.Cookie, 0, len(selected))
+	for _, e := range selected {
+		e.LastAccess = now
+		cookies = append(cookies, &http.Cookie{Name: e.Name, Value: e.Value, Quoted: e.Quoted})
+	}
+	j.lk.Unlock()
+	if purged > 0 {
+		j.changed()
+	}
+	return cookies
+}
+
+// newEntry 按RFC 6265第5.3节由Set-Cookie生成jar条目，Domain属性不合法时返回false
+// 过期时间：Max-Age优先于Expires，Max-Age<=0时过期时间为最早时间（表示删除）
+func (j *WebCookieJar) newEntry(c *http.Cookie, scheme, host, requestPath string, now time.Time) (*jarEntry, bool) {
+	if c.Name == "" && c.Value == "" {
+		return nil, false
+	}
+	// Secure Cookie及带安全前缀的Cookie只能由https设置
+	if c.Secure && scheme != "https" {
+		return nil, false
+	}
+	if hasCookiePrefix(c.Name, "__Secure-") && !c.Secure {
+		return nil, false
+	}
+	if hasCookiePrefix(c.Name, "__Host-") && (!c.Secure || c.Domain != "" || c.Path != "/") {
+		return nil, false
+	}
+	e := &jarEntry{
+		Name: c.Name, Value: c.Value, Quoted: c.Quoted, Path: c.Path,
+		Secure: c.Secure, HttpOnly: c.HttpOnly, SameSite: c.SameSite,
+	}
+	if e.Path == "" || e.Path[0] != '/' {
+		e.Path = defaultCookiePath(requestPath)
+	}
+	switch {
+	case c.MaxAge < 0: // Set-Cookie中Max-Age<=0
+		e.Persistent, e.Expires = true, time.Unix(0, 0)
+	case c.MaxAge > 0:
+		e.Persistent, e.Expires = true, now.Add(time.Duration(c.MaxAge)*time.Second)
+	case !c.Expires.IsZero():
+		e.Persistent, e.Expires = true, c.Expires
+	}
+
+	domain := strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+	// Domain为公共后缀：恰好等于当前主机时按host-only处理，否则为超级Cookie，拒绝
+	if domain != "" && j.publicSuffixes().IsPublicSuffix(domain) {
+		if domain != host {
+			return nil, false
+		}
+		domain = ""
+	}
+	if domain == "" || domain == host {
+		// 未指定Domain或Domain与主机相同：未指定时为host-only
+		e.Domain, e.HostOnly = host, domain == ""
+		return e, true
+	}
+	// IP地址不能设置域Cookie
+	if net.ParseIP(host) != nil {
+		return nil, false
+	}
+	if !cookieDomainMatch(host, domain) {
+		return nil, false
+	}
+	e.Domain = domain
+	return e, true
+}
+
+// SetPublicSuffixList 设置本jar使用的公共后缀列表（nil表示使用DefaultPublicSuffixList）
+// 只影响之后设置的Cookie，已保存的Cookie不受影响
+func (j *WebCookieJar) SetPublicSuffixList(list *PublicSuffixList) {
+	j.lk.Lock()
+	defer j.lk.Unlock()
+	j.suffixes = list
+}
+
+// publicSuffixes 返回生效的公共后缀列表，调用方需持有j.lk
+func (j *WebCookieJar) publicSuffixes() *PublicSuffixList {
+	if j.suffixes != nil {
+		return j.suffixes
+	}
+	return DefaultPublicSuffixList()
+}
+
+// shadowsSecure RFC 6265bis第5.6节：非安全来源设置的Cookie与已有的同名Secure Cookie域名互相匹配、
+// 且路径被其覆盖时，不允许覆盖或遮挡该Secure Cookie，调用方需持有j.lk
+func (j *WebCookieJar) shadowsSecure(e *jarEntry) bool {
+	for domain, domainEntries := range j.entries {
+		if !cookieDomainMatch(domain, e.Domain) && !cookieDomainMatch(e.Domain, domain) {
+			continue
+		}
+		for _, old := range domainEntries {
+			if old.Secure && old.Name == e.Name && cookiePathMatch(e.Path, old.Path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasCookiePrefix 判断Cookie名称是否带有指定前缀（不区分大小写）
+func hasCookiePrefix(name, prefix string) bool {
+	return len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix)
+}
+
+// sameSite 判断两个URL是否同站：协议相同且按list计算的注册域名相同
+func sameSite(list *PublicSuffixList, a, b *url.URL) bool {
+	if !strings.EqualFold(a.Scheme, b.Scheme) {
+		return false
+	}
+	hostA, _ := canonicalCookieHost(a.Host)
+	hostB, _ := canonicalCookieHost(b.Host)
+	return hostA == hostB || registrableDomainIn(list, hostA) == registrableDomainIn(list, hostB)
+}
+
+// sameSiteAllows 跨站请求时判断SameSite属性是否允许发送
+func sameSiteAllows(mode http.SameSite, method string) bool {
+	switch mode {
+	case http.SameSiteStrictMode:
+		return false
+	case http.SameSiteLaxMode:
+		switch method {
+		case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// cookieSiteJar 带请求上下文（Referer、方法）的jar视图，由clientFor为每次请求创建
+// http.CookieJar接口只传URL，借助该视图在跳转的每一跳都能按该跳的Referer和方法判断SameSite
+// 同一视图只在一次Client.Do内使用，标准库按顺序调用CheckRedirect和jar，无需加锁
+type cookieSiteJar struct {
+	jar     *WebCookieJar
+	referer *url.URL // 本跳的Referer，没有时为nil（视为同站请求）
+	method  string   // 本跳的请求方法
+}
+
+// update 按即将发出的请求刷新Referer和方法
+func (s *cookieSiteJar) update(req *http.Request) {
+	s.referer, s.method = nil, req.Method
+	if referer, err := url.Parse(req.Header.Get("Referer")); err == nil && referer.Host != "" {
+		s.referer = referer
+	}
+}
+
+func (s *cookieSiteJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	s.jar.SetCookies(u, cookies)
+}
+
+func (s *cookieSiteJar) Cookies(u *url.URL) []*http.Cookie {
+	return s.jar.cookies(u, s.referer, s.method)
+}
+
+// canonicalCookieHost 返回用于Cookie匹配的主机名（小写、去掉端口和末尾的点）
+func canonicalCookieHost(hostport string) (string, bool) {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+	return host, host != ""
+}
+
+// cookieDomainCandidates 返回可能存有host可用Cookie的域名：host本身及其各级父域名
+// 如www.a.example.com返回[www.a.example.com a.example.com example.com com]
+func cookieDomainCandidates(host string) []string {
+	if net.ParseIP(host) != nil {
+		return []string{host}
+	}
+	candidates := []string{host}
+	for i := 0; i < len(host); i++ {
+		if host[i] == '.' && i+1 < len(host) {
+			candidates = append(candidates, host[i+1:])
+		}
+	}
+	return candidates
+}
+
+// cookieDomainMatch RFC 6265第5.1.3节：host与domain相同，或host以"."+domain结尾且host不是IP
+func cookieDomainMatch(host, domain string) bool {
+	if host == domain {
+		return true
+	}
+	return strings.HasSuffix(host, "."+domain) && net.ParseIP(host) == nil
+}
+
+// cookiePathMatch RFC 6265第5.1.4节：请求路径与Cookie路径相同，或以Cookie路径为前缀且在"/"处分隔
+func cookiePathMatch(requestPath, cookiePath string) bool {
+	if requestPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
+
+// defaultCookiePath RFC 6265第5.1.4节：取请求路径最后一个"/"之前的部分，没有时为"/"
+func defaultCookiePath(requestPath string) string {
+	if requestPath == "" || requestPath[0] != '/' {
+		return "/"
+	}
+	i := strings.LastIndex(requestPath, "/")
+	if i == 0 {
+		return "/"
+	}
+	return requestPath[:i]
+}
+
+// setLogger 设置Cookie变更日志
+func (j *WebCookieJar) setLogger(logger *slog.Logger) {
+	j.lk.Lock()
+	defer j.lk.Unlock()
+	j.logger = logger
+}
+
+// logCookie 记录Cookie变更（Cookie值脱敏），调用方需持有j.lk
+// 设置了logger时按Debug级别记录；未设置但开启了isCookieLogOpen时，按Info级别写入slog默认日志
+func (j *WebCookieJar) logCookie(msg string, u *url.URL, c *http.Cookie) {
+	logger, level := j.logger, slog.LevelDebug
+	if logger == nil {
+		if !j.cookieLogOpen {
+			return
+		}
+		logger, level = slog.Default(), slog.LevelInfo
+	}
+	attrs := []slog.Attr{slog.String("url", redactURL(u))}
+	if c != nil {
+		attrs = append(attrs, slog.String("cookie", redactCookie(c)))
+	}
+	logger.LogAttrs(context.Background(), level, msg, attrs...)
+}
@@ -0,0 +1,457 @@
+package gather
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mustParseURL 测试辅助：解析URL
+func mustParseURL(t *testing.T, rawURL string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("解析URL失败：%v", err)
+	}
+	return u
+}
+
+// transportFunc 测试辅助：用函数模拟Transport，无需真实网络
+type transportFunc func(req *http.Request) (*http.Response, error)
+
+func (f transportFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// jarCookieString 测试辅助：返回jar发送给rawURL的Cookie（name=value，按发送顺序）
+func jarCookieString(t *testing.T, jar *WebCookieJar, rawURL string) string {
+	t.Helper()
+	var parts []string
+	for _, c := range jar.Cookies(mustParseURL(t, rawURL)) {
+		parts = append(parts, c.Name+"="+c.Value)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// TestWebCookieJar_Domain 测试host-only与域Cookie的匹配
+func TestWebCookieJar_Domain(t *testing.T) {
+	jar := newWebCookieJar(false)
+	jar.SetCookies(mustParseURL(t, "https://passport.example.com/login"), []*http.Cookie{
+		{Name: "sso", Value: "1", Domain: ".example.com", Path: "/"},
+		{Name: "host", Value: "2", Path: "/"},
+		{Name: "evil", Value: "3", Domain: "other.com", Path: "/"},    // 不属于当前主机
+		{Name: "tld", Value: "4", Domain: "com", Path: "/"},           // 顶级域
+		{Name: "sub", Value: "5", Domain: "a.example.com", Path: "/"}, // 兄弟域名
+	})
+
+	cases := map[string]string{
+		"https://passport.example.com/":      "sso=1; host=2",
+		"https://www.example.com/":           "sso=1",
+		"https://example.com/":               "sso=1",
+		"https://deep.www.example.com:8443/": "sso=1",
+		"https://other.com/":                 "",
+		"https://notexample.com/":            "",
+		"https://a.example.com/":             "sso=1",
+		"ftp://www.example.com/":             "",
+	}
+	for rawURL, want := range cases {
+		if got := jarCookieString(t, jar, rawURL); got != want {
+			t.Errorf("%s 应发送%q，实际%q", rawURL, want, got)
+		}
+	}
+
+	// IP地址只能设置host-only Cookie，且不区分端口
+	jar.SetCookies(mustParseURL(t, "http://127.0.0.1:8080/"), []*http.Cookie{
+		{Name: "ip", Value: "1"},
+		{Name: "ipdomain", Value: "2", Domain: "0.0.1"},
+	})
+	if got := jarCookieString(t, jar, "http://127.0.0.1:9090/"); got != "ip=1" {
+		t.Errorf("IP地址Cookie错误：%q", got)
+	}
+}
+
+// TestWebCookieJar_Path 测试路径匹配、默认路径、排序及替换
+func TestWebCookieJar_Path(t *testing.T) {
+	jar := newWebCookieJar(false)
+	u := mustParseURL(t, "http://www.example.com/app/user/login")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "root", Value: "1", Path: "/"},
+		{Name: "app", Value: "2", Path: "/app"},
+		{Name: "default", Value: "3"}, // 默认路径为/app/user
+		{Name: "other", Value: "4", Path: "/application"},
+	})
+	jar.SetCookies(u, []*http.Cookie{{Name: "root2", Value: "5", Path: "/"}})
+
+	cases := map[string]string{
+		"http://www.example.com/":              "root=1; root2=5",
+		"http://www.example.com/app":           "app=2; root=1; root2=5",
+		"http://www.example.com/app/user/info": "default=3; app=2; root=1; root2=5",
+		"http://www.example.com/appx":          "root=1; root2=5",
+		"http://www.example.com/application/a": "other=4; root=1; root2=5",
+	}
+	for rawURL, want := range cases {
+		if got := jarCookieString(t, jar, rawURL); got != want {
+			t.Errorf("%s 应发送%q，实际%q", rawURL, want, got)
+		}
+	}
+
+	// 同名、同域、同路径替换值，保留原来的顺序
+	jar.SetCookies(u, []*http.Cookie{{Name: "root", Value: "6", Path: "/"}})
+	if got := jarCookieString(t, jar, "http://www.example.com/"); got != "root=6; root2=5" {
+		t.Errorf("替换后Cookie错误：%q", got)
+	}
+	// 不同路径的同名Cookie共存
+	jar.SetCookies(u, []*http.Cookie{{Name: "root", Value: "7", Path: "/app"}})
+	if got := jarCookieString(t, jar, "http://www.example.com/app"); got != "app=2; root=7; root=6; root2=5" {
+		t.Errorf("同名不同路径Cookie错误：%q", got)
+	}
+}
+
+// TestWebCookieJar_SSO 测试登录服务下发的域Cookie在跳转后发送给其他子域名
+func TestWebCookieJar_SSO(t *testing.T) {
+	ga := NewGather("chrome", false)
+	ga.Client.Transport = transportFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		body := ""
+		switch req.URL.Host {
+		case "passport.example.com":
+			header.Add("Set-Cookie", "ticket=abc; Domain=.example.com; Path=/")
+			header.Add("Set-Cookie", "local=1; Path=/")
+			header.Set("Location", "http://www.example.com/home")
+			return &http.Response{StatusCode: http.StatusFound, Header: header, Body: http.NoBody, Request: req}, nil
+		case "www.example.com":
+			body = req.Header.Get("Cookie")
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})
+	html, _, err := ga.Get("http://passport.example.com/login", "")
+	if err != nil {
+		t.Fatalf("请求失败：%v", err)
+	}
+	if html != "ticket=abc" {
+		t.Errorf("跳转后应携带域Cookie且不携带host-only Cookie，实际%q", html)
+	}
+}
+
+// TestWebCookieJar_Expiry 测试Max-Age、Expires、删除、会话Cookie及过期清理
+func TestWebCookieJar_Expiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	jar := newWebCookieJar(false)
+	jar.SetClock(func() time.Time { return now })
+	u := mustParseURL(t, "http://www.example.com/")
+
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "1"},
+		{Name: "maxage", Value: "2", MaxAge: 60},
+		{Name: "expires", Value: "3", Expires: now.Add(2 * time.Hour)},
+		{Name: "both", Value: "4", MaxAge: 10, Expires: now.Add(24 * time.Hour)}, // Max-Age优先
+		{Name: "past", Value: "5", Expires: now.Add(-time.Hour)},                 // 已过期不保存
+	})
+	if got := jarCookieString(t, jar, u.String()); got != "session=1; maxage=2; expires=3; both=4" {
+		t.Fatalf("初始Cookie错误：%q", got)
+	}
+
+	now = now.Add(30 * time.Second)
+	if got := jarCookieString(t, jar, u.String()); got != "session=1; maxage=2; expires=3" {
+		t.Errorf("Max-Age应优先于Expires：%q", got)
+	}
+	now = now.Add(time.Minute)
+	if got := jarCookieString(t, jar, u.String()); got != "session=1; expires=3" {
+		t.Errorf("Max-Age到期后不应发送：%q", got)
+	}
+
+	// 服务器以Max-Age=0（MaxAge<0）或过去的Expires删除Cookie
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "expires", Value: "", MaxAge: -1},
+		{Name: "session", Value: "", Expires: time.Unix(0, 0)},
+	})
+	if got := jarCookieString(t, jar, u.String()); got != "" {
+		t.Errorf("删除后不应再发送：%q", got)
+	}
+
+	// 清理过期Cookie，会话Cookie不受影响
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "short", Value: "1", MaxAge: 1},
+		{Name: "long", Value: "2", MaxAge: 3600},
+		{Name: "session", Value: "3"},
+	})
+	now = now.Add(2 * time.Second)
+	if removed := jar.PurgeExpired(); removed != 1 {
+		t.Errorf("应清理1个过期Cookie，实际%d个", removed)
+	}
+	now = now.Add(2 * time.Hour) // 超过清理间隔后读写jar时自动清理
+	jar.Cookies(u)
+	if len(jar.entries) != 1 || len(jar.entries["www.example.com"]) != 1 {
+		t.Errorf("自动清理后应只剩会话Cookie：%v", jar.entries)
+	}
+	if removed := jar.EndSession(); removed != 1 || len(jar.entries) != 0 {
+		t.Errorf("EndSession应删除会话Cookie：%d, %v", removed, jar.entries)
+	}
+}
+
+// TestWebCookieJar_Logout 测试服务器下发过期Cookie注销登录
+func TestWebCookieJar_Logout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "abc", Path: "/", MaxAge: 3600})
+		case "/logout":
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "", Path: "/", MaxAge: -1})
+		}
+		w.Write([]byte(r.Header.Get("Cookie")))
+	}))
+	defer server.Close()
+
+	ga := NewGather("chrome", false)
+	ga.Get(server.URL+"/login", "")
+	if html, _, _ := ga.Get(server.URL+"/check", ""); html != "sid=abc" {
+		t.Errorf("登录后应携带Cookie：%q", html)
+	}
+	ga.Get(server.URL+"/logout", "")
+	if html, _, _ := ga.Get(server.URL+"/check", ""); html != "" {
+		t.Errorf("注销后不应携带Cookie：%q", html)
+	}
+}
+
+// TestWebCookieJar_Secure 测试Secure属性及__Secure-/__Host-前缀
+func TestWebCookieJar_Secure(t *testing.T) {
+	jar := newWebCookieJar(false)
+	httpsURL := mustParseURL(t, "https://www.example.com/")
+	httpURL := mustParseURL(t, "http://www.example.com/")
+
+	jar.SetCookies(httpURL, []*http.Cookie{{Name: "insecure", Value: "1", Secure: true}}) // http不能设置Secure
+	jar.SetCookies(httpsURL, []*http.Cookie{
+		{Name: "sid", Value: "1", Secure: true, Path: "/"},
+		{Name: "plain", Value: "2", Path: "/"},
+		{Name: "__Secure-ok", Value: "3", Secure: true, Path: "/"},
+		{Name: "__Secure-bad", Value: "4", Path: "/"},
+		{Name: "__Host-ok", Value: "5", Secure: true, Path: "/"},
+		{Name: "__Host-domain", Value: "6", Secure: true, Path: "/", Domain: "example.com"},
+		{Name: "__host-path", Value: "7", Secure: true, Path: "/app"},
+	})
+	if got := jarCookieString(t, jar, httpsURL.String()); got != "sid=1; plain=2; __Secure-ok=3; __Host-ok=5" {
+		t.Errorf("https应发送全部合法Cookie：%q", got)
+	}
+	if got := jarCookieString(t, jar, httpURL.String()); got != "plain=2" {
+		t.Errorf("http不应发送Secure Cookie：%q", got)
+	}
+
+	// http响应不能覆盖或删除同名的Secure Cookie
+	jar.SetCookies(httpURL, []*http.Cookie{{Name: "sid", Value: "evil", Path: "/"}, {Name: "__Secure-ok", MaxAge: -1}})
+	if got := jarCookieString(t, jar, httpsURL.String()); got != "sid=1; plain=2; __Secure-ok=3; __Host-ok=5" {
+		t.Errorf("http不应覆盖Secure Cookie：%q", got)
+	}
+}
+
+// TestWebCookieJar_SameSite 测试跨站请求时的SameSite规则
+func TestWebCookieJar_SameSite(t *testing.T) {
+	jar := newWebCookieJar(false)
+	u := mustParseURL(t, "https://www.example.com/")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "strict", Value: "1", SameSite: http.SameSiteStrictMode},
+		{Name: "lax", Value: "2", SameSite: http.SameSiteLaxMode},
+		{Name: "none", Value: "3", SameSite: http.SameSiteNoneMode, Secure: true},
+		{Name: "default", Value: "4"},
+	})
+	names := func(referer, method string) string {
+		var parts []string
+		for _, c := range jar.cookies(u, mustParseURL(t, referer), method) {
+			parts = append(parts, c.Name)
+		}
+		return strings.Join(parts, ",")
+	}
+	cases := []struct{ referer, method, want string }{
+		{"https://passport.example.com/", "POST", "strict,lax,none,default"}, // 同站
+		{"https://other.com/", "GET", "lax,none,default"},
+		{"https://other.com/", "POST", "none,default"},
+		{"http://www.example.com/", "GET", "lax,none,default"}, // 协议不同视为跨站
+	}
+	for _, c := range cases {
+		if got := names(c.referer, c.method); got != c.want {
+			t.Errorf("Referer=%s %s 应发送%s，实际%s", c.referer, c.method, c.want, got)
+		}
+	}
+
+	// 同站判断使用jar自己的公共后缀列表：shop.example.com成为公共后缀后，a/b两个子域名互为跨站
+	custom, _ := ParsePublicSuffixList(strings.NewReader("com\nshop.example.com\n"))
+	jar.SetPublicSuffixList(custom)
+	shop := mustParseURL(t, "https://a.shop.example.com/")
+	jar.SetCookies(shop, []*http.Cookie{{Name: "shop", Value: "5", SameSite: http.SameSiteStrictMode}})
+	if got := jar.cookies(shop, mustParseURL(t, "https://b.shop.example.com/"), "GET"); len(got) != 0 {
+		t.Errorf("按jar的公共后缀列表应视为跨站，不发送Strict Cookie：%v", got)
+	}
+	if got := jar.cookies(shop, mustParseURL(t, "https://x.a.shop.example.com/"), "GET"); len(got) != 1 {
+		t.Errorf("同一注册域名应视为同站：%v", got)
+	}
+
+	// 通过newHttpRequest设置的Referer生效
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "1", Path: "/", SameSite: http.SameSiteStrictMode})
+		}
+		w.Write([]byte(r.Header.Get("Cookie")))
+	}))
+	defer server.Close()
+	ga := NewGather("chrome", false)
+	ga.Get(server.URL+"/login", "")
+	if html, _, _ := ga.Get(server.URL+"/check", "http://other.com/page"); html != "" {
+		t.Errorf("跨站Referer不应发送Strict Cookie：%q", html)
+	}
+	if html, _, _ := ga.Get(server.URL+"/check", server.URL+"/index"); html != "sid=1" {
+		t.Errorf("同站Referer应发送Strict Cookie：%q", html)
+	}
+}
+
+// TestWebCookieJar_SameSiteRedirect 测试跳转的每一跳按该跳实际的Referer和方法判断SameSite
+func TestWebCookieJar_SameSiteRedirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "lax", Value: "1", Path: "/", SameSite: http.SameSiteLaxMode})
+			http.SetCookie(w, &http.Cookie{Name: "strict", Value: "2", Path: "/", SameSite: http.SameSiteStrictMode})
+		case "/submit", "/go":
+			http.Redirect(w, r, "/check", http.StatusFound)
+			return
+		}
+		w.Write([]byte(r.Method + " " + r.Header.Get("Cookie")))
+	}))
+	defer server.Close()
+	ga := NewGather("chrome", false)
+	ga.Get(server.URL+"/login", "")
+
+	// 跨站POST被302改为GET后，第二跳是跨站GET，应发送Lax Cookie
+	if html, _, _ := ga.Post(server.URL+"/submit", "http://other.com/form", map[string]string{"a": "1"}); html != "GET lax=1" {
+		t.Errorf("POST跳转为GET后应按GET判断SameSite，实际：%q", html)
+	}
+	// UpdateReferer把Referer更新为同站的上一跳后，应发送Strict Cookie
+	if html, _, _ := ga.Get(server.URL+"/go", "http://other.com/page"); html != "GET lax=1" {
+		t.Errorf("保留跨站Referer时不应发送Strict Cookie，实际：%q", html)
+	}
+	ga.SetRedirectPolicy(RedirectPolicy{UpdateReferer: true})
+	if html, _, _ := ga.Get(server.URL+"/go", "http://other.com/page"); html != "GET lax=1; strict=2" {
+		t.Errorf("Referer更新为同站后应发送Strict Cookie，实际：%q", html)
+	}
+}
+
+// TestWebCookieJar_ImportCookieString 测试导入浏览器复制的Cookie字符串并与服务器下发的Cookie合并
+func TestWebCookieJar_ImportCookieString(t *testing.T) {
+	jar := newWebCookieJar(false)
+	n, err := jar.ImportCookieString(".example.com", `Cookie: sid=abc; token="x y"; ;flag; theme=dark`)
+	if err != nil || n != 4 {
+		t.Fatalf("导入失败：%d, %v", n, err)
+	}
+	if got := jarCookieString(t, jar, "http://www.example.com/a/b"); got != "sid=abc; token=x y; flag=; theme=dark" {
+		t.Errorf("导入后发送的Cookie错误：%q", got)
+	}
+	if _, err := jar.ImportCookieString("", "a=1"); err == nil {
+		t.Errorf("域名为空时应返回错误")
+	}
+
+	// host-only导入只发送给该主机
+	jar.ImportCookieString("api.example.com:8443", "key=1")
+	if got := jarCookieString(t, jar, "http://www.example.com/"); strings.Contains(got, "key=") {
+		t.Errorf("host-only导入不应发送给其他主机：%q", got)
+	}
+
+	// 服务器下发同名Cookie（Domain、Path不同）替换导入值，删除同样生效
+	u := mustParseURL(t, "http://www.example.com/login")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "sid", Value: "new"}, // host-only，默认路径/
+		{Name: "token", Value: "t2", Domain: "example.com", Path: "/app"},
+		{Name: "theme", MaxAge: -1},
+	})
+	if got := jarCookieString(t, jar, "http://www.example.com/app"); got != "token=t2; flag=; sid=new" {
+		t.Errorf("合并后的Cookie错误：%q", got)
+	}
+
+	// 端到端：导入后Get携带，服务器更新后携带新值
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/refresh" {
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "refreshed", Path: "/"})
+		}
+		w.Write([]byte(r.Header.Get("Cookie")))
+	}))
+	defer server.Close()
+	ga := NewGather("chrome", false)
+	ga.J.ImportCookieString(strings.TrimPrefix(server.URL, "http://"), "sid=pasted")
+	if html, _, _ := ga.Get(server.URL+"/refresh", ""); html != "sid=pasted" {
+		t.Errorf("应携带导入的Cookie：%q", html)
+	}
+	if html, _, _ := ga.Get(server.URL+"/check", ""); html != "sid=refreshed" {
+		t.Errorf("服务器更新后应携带新值：%q", html)
+	}
+}
+
+// TestWebCookieJar_API 测试查看和管理Cookie的公开方法
+func TestWebCookieJar_API(t *testing.T) {
+	jar := newWebCookieJar(false)
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	jar.SetCookies(mustParseURL(t, "https://www.example.com/login"), []*http.Cookie{
+		{Name: "csrftoken", Value: "root", Path: "/"},
+		{Name: "csrftoken", Value: "app", Path: "/app"},
+		{Name: "sso", Value: "1", Domain: "example.com", Path: "/", Secure: true, HttpOnly: true, Expires: expires},
+	})
+	jar.SetCookies(mustParseURL(t, "https://other.com/"), []*http.Cookie{{Name: "a", Value: "1", Path: "/"}})
+
+	all := jar.All()
+	if len(all) != 4 || jar.Len() != 4 {
+		t.Fatalf("All应返回4个Cookie：%v", all)
+	}
+	if all[0].Name != "sso" || all[0].Domain != ".example.com" || !all[0].Secure || !all[0].HttpOnly || !all[0].Expires.Equal(expires) {
+		t.Errorf("域Cookie属性错误：%+v", all[0])
+	}
+	if c, ok := jar.Get("WWW.example.com", "csrftoken"); !ok || c.Value != "app" || c.Domain != "www.example.com" || c.Path != "/app" {
+		t.Errorf("Get应返回路径最长的Cookie：%+v", c)
+	}
+	if c, ok := jar.Get(".example.com", "sso"); !ok || c.Value != "1" {
+		t.Errorf("Get域Cookie错误：%+v", c)
+	}
+	if _, ok := jar.Get("example.com", "csrftoken"); ok {
+		t.Errorf("host-only Cookie不属于父域名")
+	}
+	all[0].Value = "changed" // 返回副本，修改不影响jar
+	if c, _ := jar.Get("example.com", "sso"); c.Value != "1" {
+		t.Errorf("修改返回值不应影响jar")
+	}
+
+	if !jar.Delete("www.example.com", "csrftoken", "/app") || jar.Delete("www.example.com", "csrftoken", "/app") {
+		t.Errorf("Delete应只删除一次")
+	}
+	if c, _ := jar.Get("www.example.com", "csrftoken"); c == nil || c.Value != "root" {
+		t.Errorf("删除后应返回剩余的同名Cookie：%+v", c)
+	}
+	if n := jar.ClearDomain("example.com"); n != 2 {
+		t.Errorf("ClearDomain应删除域名及子域名的2个Cookie，实际%d个", n)
+	}
+	if n := jar.Clear(); n != 1 || jar.Len() != 0 {
+		t.Errorf("Clear应删除剩余的1个Cookie，实际%d个", n)
+	}
+}
+
+// TestWebCookieJar_Concurrent 测试请求进行中并发管理Cookie（配合-race运行）
+func TestWebCookieJar_Concurrent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "n", Value: r.URL.Query().Get("i"), Path: "/"})
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	ga := NewGather("chrome", false)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			ga.J.All()
+			ga.J.Get(host, "n")
+			ga.J.ClearDomain(host)
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		ga.Get(server.URL+"/?i="+strings.Repeat("x", i), "")
+	}
+	<-done
+}
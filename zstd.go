@@ -0,0 +1,918 @@
+// Copyright 2020 ratelimit Author(https://github.com/yudeguang17/gather). All Rights Reserved.
+//
+// This Source Code Form is subject to the terms of the MIT License.
+// If a copy of the MIT was not distributed with this file,
+// You can obtain one at https://github.com/yudeguang17/gather.
+// 模拟浏览器进行数据采集包,可较方便的定义http头，同时全自动化处理cookies
+package gather
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// zstd解码实现（RFC 8878），纯Go实现，无任何外部依赖
+// 支持：多帧、可跳过帧、Raw/RLE/Compressed块、Huffman字面量（单流/4流）、FSE序列、重复偏移、内容校验（xxHash64）
+// 不支持：外部字典（HTTP内容编码场景下不会使用）
+
+// errZstdCorrupt zstd数据格式错误
+var errZstdCorrupt = errors.New("zstd: 数据格式错误")
+
+const (
+	zstdMagic          = 0xFD2FB528
+	zstdMaxBlockSize   = 128 << 10
+	zstdMaxWindowSize  = 1 << 30 // 超出此窗口的帧视为不合法，避免恶意数据导致内存耗尽
+	zstdMaxOutputBytes = 1 << 31
+)
+
+// 字面量长度/匹配长度编码表：{基数, 额外位数}
+var zstdLiteralLengthCodes = [36][2]uint32{
+	{0, 0}, {1, 0}, {2, 0}, {3, 0}, {4, 0}, {5, 0}, {6, 0}, {7, 0}, {8, 0}, {9, 0}, {10, 0}, {11, 0},
+	{12, 0}, {13, 0}, {14, 0}, {15, 0}, {16, 1}, {18, 1}, {20, 1}, {22, 1}, {24, 2}, {28, 2}, {32, 3}, {40, 3},
+	{48, 4}, {64, 6}, {128, 7}, {256, 8}, {512, 9}, {1024, 10}, {2048, 11}, {4096, 12}, {8192, 13}, {16384, 14}, {32768, 15}, {65536, 16},
+}
+var zstdMatchLengthCodes = [53][2]uint32{
+	{3, 0}, {4, 0}, {5, 0}, {6, 0}, {7, 0}, {8, 0}, {9, 0}, {10, 0}, {11, 0}, {12, 0}, {13, 0}, {14, 0}, {15, 0}, {16, 0}, {17, 0}, {18, 0},
+	{19, 0}, {20, 0}, {21, 0}, {22, 0}, {23, 0}, {24, 0}, {25, 0}, {26, 0}, {27, 0}, {28, 0}, {29, 0}, {30, 0}, {31, 0}, {32, 0}, {33, 0}, {34, 0},
+	{35, 1}, {37, 1}, {39, 1}, {41, 1}, {43, 2}, {47, 2}, {51, 3}, {59, 3}, {67, 4}, {83, 4}, {99, 5}, {131, 7}, {259, 8}, {515, 9}, {1027, 10}, {2051, 11},
+	{4099, 12}, {8195, 13}, {16387, 14}, {32771, 15}, {65539, 16},
+}
+
+// 预定义分布（RFC 8878 3.1.1.3.2.2）
+var zstdDefaultLLNorm = []int16{4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1, 2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1, -1, -1, -1, -1}
+var zstdDefaultMLNorm = []int16{1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1, -1, -1}
+var zstdDefaultOFNorm = []int16{1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1}
+
+// ZstdDecode 解码完整的zstd数据（可包含多个帧）
+func ZstdDecode(data []byte) ([]byte, error) {
+	return zstdDecode(data, -1)
+}
+
+// zstdDecode 解码zstd数据，limit>=0时解码结果超过limit字节立即返回ErrBodyTooLarge
+// 每个块解码后的长度不超过128KB，逐块检查即可保证不会先解出全部数据
+func zstdDecode(data []byte, limit int64) (out []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok && (errors.Is(e, errZstdCorrupt) || errors.Is(e, ErrBodyTooLarge)) {
+				out, err = nil, e
+				return
+			}
+			panic(r)
+		}
+	}()
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: 数据为空", errZstdCorrupt)
+	}
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("%w: 帧头不完整", errZstdCorrupt)
+		}
+		magic := binary.LittleEndian.Uint32(data)
+		// 可跳过帧：魔数0x184D2A50~0x184D2A5F
+		if magic&0xFFFFFFF0 == 0x184D2A50 {
+			if len(data) < 8 {
+				return nil, fmt.Errorf("%w: 可跳过帧不完整", errZstdCorrupt)
+			}
+			size := int(binary.LittleEndian.Uint32(data[4:]))
+			if size > len(data)-8 {
+				return nil, fmt.Errorf("%w: 可跳过帧不完整", errZstdCorrupt)
+			}
+			data = data[8+size:]
+			continue
+		}
+		if magic != zstdMagic {
+			return nil, fmt.Errorf("%w: 魔数不匹配", errZstdCorrupt)
+		}
+		d := &zstdDecoder{data: data, pos: 4, out: out, frameStart: len(out), limit: limit}
+		d.decodeFrame()
+		out = d.out
+		data = data[d.pos:]
+	}
+	return out, nil
+}
+
+// zstdDecoder 单个帧的解码状态
+type zstdDecoder struct {
+	data       []byte
+	pos        int
+	out        []byte
+	frameStart int // 本帧输出在out中的起始位置（多帧时前面的帧不可被引用）
+	blockStart int // 当前块输出在out中的起始位置
+	windowSize int
+	limit      int64 // 解码结果最大字节数，<0表示不限制
+
+	// 跨块保留的状态
+	huff       *zstdHuffman
+	llTable    *fseTable
+	mlTable    *fseTable
+	ofTable    *fseTable
+	repOffsets [3]int
+}
+
+// corrupt 以格式错误panic
+func (d *zstdDecoder) corrupt(msg string) {
+	panic(fmt.Errorf("%w: %s", errZstdCorrupt, msg))
+}
+
+// need 确保剩余数据至少n字节
+func (d *zstdDecoder) need(n int) {
+	if n < 0 || len(d.data)-d.pos < n {
+		d.corrupt("数据意外结束")
+	}
+}
+
+// decodeFrame 解码一个zstd帧
+func (d *zstdDecoder) decodeFrame() {
+	d.need(1)
+	fhd := d.data[d.pos]
+	d.pos++
+	fcsFlag := fhd >> 6
+	singleSegment := fhd>>5&1 == 1
+	if fhd>>3&1 == 1 {
+		d.corrupt("保留位非0")
+	}
+	hasChecksum := fhd>>2&1 == 1
+	dictIDFlag := fhd & 3
+
+	if !singleSegment {
+		d.need(1)
+		wd := d.data[d.pos]
+		d.pos++
+		exponent := uint(wd >> 3)
+		base := 1 << (10 + exponent)
+		d.windowSize = base + base/8*int(wd&7)
+	}
+	dictIDSize := [4]int{0, 1, 2, 4}[dictIDFlag]
+	d.need(dictIDSize)
+	dictID := uint32(0)
+	for i := 0; i < dictIDSize; i++ {
+		dictID |= uint32(d.data[d.pos+i]) << (8 * i)
+	}
+	d.pos += dictIDSize
+	if dictID != 0 {
+		d.corrupt("不支持使用字典的帧")
+	}
+
+	fcsSize := [4]int{0, 2, 4, 8}[fcsFlag]
+	if fcsFlag == 0 && singleSegment {
+		fcsSize = 1
+	}
+	d.need(fcsSize)
+	contentSize := uint64(0)
+	hasContentSize := fcsSize > 0
+	for i := 0; i < fcsSize; i++ {
+		contentSize |= uint64(d.data[d.pos+i]) << (8 * i)
+	}
+	if fcsSize == 2 {
+		contentSize += 256
+	}
+	d.pos += fcsSize
+	if singleSegment {
+		d.windowSize = int(min(contentSize, zstdMaxWindowSize+1))
+	}
+	if d.windowSize > zstdMaxWindowSize {
+		d.corrupt("窗口过大")
+	}
+	if hasContentSize && contentSize < zstdMaxOutputBytes {
+		prealloc := min(contentSize, 64<<20)
+		if d.limit >= 0 {
+			// 帧头声明的长度不可信，预分配不超过大小上限
+			prealloc = min(prealloc, uint64(d.limit)+1)
+		}
+		d.out = growBytes(d.out, int(prealloc))
+	}
+
+	d.repOffsets = [3]int{1, 4, 8}
+	for {
+		d.need(3)
+		header := uint32(d.data[d.pos]) | uint32(d.data[d.pos+1])<<8 | uint32(d.data[d.pos+2])<<16
+		d.pos += 3
+		last := header&1 == 1
+		blockType := header >> 1 & 3
+		blockSize := int(header >> 3)
+		switch blockType {
+		case 0: // Raw
+			if blockSize > zstdMaxBlockSize {
+				d.corrupt("块过大")
+			}
+			d.need(blockSize)
+			d.out = append(d.out, d.data[d.pos:d.pos+blockSize]...)
+			d.pos += blockSize
+		case 1: // RLE
+			d.need(1)
+			if blockSize > zstdMaxBlockSize {
+				d.corrupt("块过大")
+			}
+			b := d.data[d.pos]
+			d.pos++
+			for i := 0; i < blockSize; i++ {
+				d.out = append(d.out, b)
+			}
+		case 2: // Compressed
+			if blockSize > zstdMaxBlockSize {
+				d.corrupt("块过大")
+			}
+			d.need(blockSize)
+			d.decodeCompressedBlock(d.data[d.pos : d.pos+blockSize])
+			d.pos += blockSize
+		default:
+			d.corrupt("保留的块类型")
+		}
+		if len(d.out)-d.frameStart > zstdMaxOutputBytes {
+			d.corrupt("解压数据过大")
+		}
+		if d.limit >= 0 && int64(len(d.out)) > d.limit {
+			panic(fmt.Errorf("%w: 解码后超过 %d 字节", ErrBodyTooLarge, d.limit))
+		}
+		if last {
+			break
+		}
+	}
+
+	if hasContentSize && uint64(len(d.out)-d.frameStart) != contentSize {
+		d.corrupt("解压长度与帧头不一致")
+	}
+	if hasChecksum {
+		d.need(4)
+		want := binary.LittleEndian.Uint32(d.data[d.pos:])
+		d.pos += 4
+		if uint32(xxhash64(d.out[d.frameStart:])) != want {
+			d.corrupt("内容校验失败")
+		}
+	}
+}
+
+// growBytes 预留容量
+func growBytes(b []byte, n int) []byte {
+	if cap(b)-len(b) >= n {
+		return b
+	}
+	nb := make([]byte, len(b), len(b)+n)
+	copy(nb, b)
+	return nb
+}
+
+// decodeCompressedBlock 解码压缩块：字面量段+序列段
+func (d *zstdDecoder) decodeCompressedBlock(block []byte) {
+	d.blockStart = len(d.out)
+	literals, n := d.decodeLiterals(block)
+	block = block[n:]
+	d.decodeSequences(block, literals)
+}
+
+// decodeLiterals 解码字面量段，返回字面量及该段占用的字节数
+func (d *zstdDecoder) decodeLiterals(block []byte) ([]byte, int) {
+	if len(block) < 1 {
+		d.corrupt("字面量段为空")
+	}
+	litType := block[0] & 3
+	sizeFormat := block[0] >> 2 & 3
+
+	// Raw/RLE字面量
+	if litType == 0 || litType == 1 {
+		var regen, hdr int
+		switch sizeFormat {
+		case 0, 2:
+			regen, hdr = int(block[0]>>3), 1
+		case 1:
+			if len(block) < 2 {
+				d.corrupt("字面量头不完整")
+			}
+			regen, hdr = int(block[0]>>4)|int(block[1])<<4, 2
+		case 3:
+			if len(block) < 3 {
+				d.corrupt("字面量头不完整")
+			}
+			regen, hdr = int(block[0]>>4)|int(block[1])<<4|int(block[2])<<12, 3
+		}
+		if regen > zstdMaxBlockSize {
+			d.corrupt("字面量过大")
+		}
+		if litType == 0 {
+			if len(block) < hdr+regen {
+				d.corrupt("字面量不完整")
+			}
+			return block[hdr : hdr+regen], hdr + regen
+		}
+		if len(block) < hdr+1 {
+			d.corrupt("字面量不完整")
+		}
+		lits := make([]byte, regen)
+		for i := range lits {
+			lits[i] = block[hdr]
+		}
+		return lits, hdr + 1
+	}
+
+	// Compressed/Treeless字面量
+	var regen, comp, hdr int
+	streams := 4
+	switch sizeFormat {
+	case 0, 1:
+		if len(block) < 3 {
+			d.corrupt("字面量头不完整")
+		}
+		if sizeFormat == 0 {
+			streams = 1
+		}
+		v := uint32(block[0]) | uint32(block[1])<<8 | uint32(block[2])<<16
+		regen, comp, hdr = int(v>>4&0x3FF), int(v>>14&0x3FF), 3
+	case 2:
+		if len(block) < 4 {
+			d.corrupt("字面量头不完整")
+		}
+		v := binary.LittleEndian.Uint32(block)
+		regen, comp, hdr = int(v>>4&0x3FFF), int(v>>18&0x3FFF), 4
+	case 3:
+		if len(block) < 5 {
+			d.corrupt("字面量头不完整")
+		}
+		v := uint64(binary.LittleEndian.Uint32(block)) | uint64(block[4])<<32
+		regen, comp, hdr = int(v>>4&0x3FFFF), int(v>>22&0x3FFFF), 5
+	}
+	if regen > zstdMaxBlockSize || len(block) < hdr+comp {
+		d.corrupt("字面量长度不合法")
+	}
+	data := block[hdr : hdr+comp]
+	if litType == 2 {
+		h, n := d.readHuffmanTable(data)
+		d.huff = h
+		data = data[n:]
+	} else if d.huff == nil {
+		d.corrupt("缺少可复用的Huffman表")
+	}
+
+	lits := make([]byte, regen)
+	if streams == 1 {
+		d.huff.decodeStream(data, lits)
+	} else {
+		if len(data) < 6 {
+			d.corrupt("字面量跳转表不完整")
+		}
+		s1 := int(binary.LittleEndian.Uint16(data))
+		s2 := int(binary.LittleEndian.Uint16(data[2:]))
+		s3 := int(binary.LittleEndian.Uint16(data[4:]))
+		data = data[6:]
+		if s1+s2+s3 > len(data) {
+			d.corrupt("字面量跳转表不合法")
+		}
+		seg := (regen + 3) / 4
+		if 3*seg > regen {
+			d.corrupt("字面量长度不合法")
+		}
+		d.huff.decodeStream(data[:s1], lits[:seg])
+		d.huff.decodeStream(data[s1:s1+s2], lits[seg:2*seg])
+		d.huff.decodeStream(data[s1+s2:s1+s2+s3], lits[2*seg:3*seg])
+		d.huff.decodeStream(data[s1+s2+s3:], lits[3*seg:])
+	}
+	return lits, hdr + comp
+}
+
+// ---------------------- 逆向位读取器（zstd的FSE/Huffman流从末尾向前读取） ----------------------
+type zstdBackReader struct {
+	data  []byte
+	pos   int // 下一个要读入的字节下标（向前递减）
+	val   uint64
+	nbits uint // 位缓冲中的有效位数（高位优先）
+}
+
+// newZstdBackReader 初始化逆向位读取器，跳过末字节中的填充位和结束标记位
+func newZstdBackReader(data []byte) *zstdBackReader {
+	if len(data) == 0 || data[len(data)-1] == 0 {
+		panic(fmt.Errorf("%w: 比特流结束标记缺失", errZstdCorrupt))
+	}
+	br := &zstdBackReader{data: data, pos: len(data)}
+	last := data[len(data)-1]
+	br.pos--
+	br.val = uint64(last)
+	br.nbits = 8
+	pad := uint(bits.LeadingZeros8(last)) + 1
+	br.val &= 1<<(8-pad) - 1
+	br.nbits -= pad
+	return br
+}
+
+// fill 尽量填充位缓冲（新字节放在低位）
+func (br *zstdBackReader) fill() {
+	for br.nbits <= 56 && br.pos > 0 {
+		br.pos--
+		br.val = br.val<<8 | uint64(br.data[br.pos])
+		br.nbits += 8
+	}
+}
+
+// bits 读取n位，流耗尽后按规范以0补齐
+func (br *zstdBackReader) bits(n uint) uint32 {
+	if n == 0 {
+		return 0
+	}
+	if br.nbits < n {
+		br.fill()
+	}
+	if br.nbits < n {
+		// 越界读取：高位为已有数据，低位补0
+		v := uint32(br.val << (n - br.nbits) & (1<<n - 1))
+		br.val = 0
+		br.nbits = 0
+		br.pos = -1 - int(n) // 标记为越界
+		return v
+	}
+	br.nbits -= n
+	return uint32(br.val >> br.nbits & (1<<n - 1))
+}
+
+// finished 流是否已恰好读完
+func (br *zstdBackReader) finished() bool {
+	return br.pos == 0 && br.nbits == 0
+}
+
+// overflow 是否发生了越界读取
+func (br *zstdBackReader) overflow() bool {
+	return br.pos < 0
+}
+
+// ---------------------- FSE ----------------------
+// fseTable FSE解码表
+type fseTable struct {
+	accuracyLog uint
+	symbol      []uint8
+	nbBits      []uint8
+	baseline    []uint16
+}
+
+// readFSETable 从正向数据中读取FSE归一化分布并建表，返回表和占用字节数
+func readFSETable(data []byte, maxSymbol int, maxLog uint) (*fseTable, int) {
+	br := &lsbBitReader{data: data, corrupt: errZstdCorrupt}
+	accuracyLog := uint(br.bits(4)) + 5
+	if accuracyLog > maxLog {
+		panic(fmt.Errorf("%w: FSE精度过大", errZstdCorrupt))
+	}
+	remaining := 1<<accuracyLog + 1
+	threshold := 1 << accuracyLog
+	nbits := accuracyLog + 1
+	norm := make([]int16, 0, maxSymbol+1)
+	for remaining > 1 && len(norm) <= maxSymbol {
+		// 可变长度读取：小于lowMask的值少读一位
+		max := uint32(2*threshold - 1 - remaining)
+		v := br.peek(nbits)
+		var value uint32
+		if v&uint32(threshold-1) < max {
+			value = v & uint32(threshold-1)
+			br.skip(nbits - 1)
+		} else {
+			value = v & uint32(2*threshold-1)
+			if value >= uint32(threshold) {
+				value -= max
+			}
+			br.skip(nbits)
+		}
+		prob := int(value) - 1
+		if prob < 0 {
+			remaining -= 1
+		} else {
+			remaining -= prob
+		}
+		norm = append(norm, int16(prob))
+		if prob == 0 {
+			// 0概率后跟重复标记：每2位表示再重复0~3个0
+			for {
+				rep := br.bits(2)
+				for i := uint32(0); i < rep; i++ {
+					norm = append(norm, 0)
+				}
+				if rep != 3 {
+					break
+				}
+			}
+		}
+		for remaining < threshold && threshold > 1 {
+			threshold >>= 1
+			nbits--
+		}
+	}
+	if remaining != 1 || len(norm) > maxSymbol+1 {
+		panic(fmt.Errorf("%w: FSE分布不合法", errZstdCorrupt))
+	}
+	used := (br.pos*8 - int(br.nbits) + 7) / 8
+	return buildFSETable(norm, accuracyLog), used
+}
+
+// buildFSETable 由归一化分布构建FSE解码表
+func buildFSETable(norm []int16, accuracyLog uint) *fseTable {
+	size := 1 << accuracyLog
+	t := &fseTable{
+		accuracyLog: accuracyLog,
+		symbol:      make([]uint8, size),
+		nbBits:      make([]uint8, size),
+		baseline:    make([]uint16, size),
+	}
+	high := size - 1
+	next := make([]int, len(norm))
+	// 概率为-1（低于1）的符号放在表尾
+	for s, p := range norm {
+		if p == -1 {
+			t.symbol[high] = uint8(s)
+			high--
+			next[s] = 1
+		} else {
+			next[s] = int(p)
+		}
+	}
+	step := size>>1 + size>>3 + 3
+	mask := size - 1
+	pos := 0
+	for s, p := range norm {
+		for i := 0; i < int(p); i++ {
+			t.symbol[pos] = uint8(s)
+			pos = (pos + step) & mask
+			for pos > high {
+				pos = (pos + step) & mask
+			}
+		}
+	}
+	if pos != 0 {
+		panic(fmt.Errorf("%w: FSE分布不合法", errZstdCorrupt))
+	}
+	for i := 0; i < size; i++ {
+		s := t.symbol[i]
+		x := next[s]
+		next[s]++
+		nb := accuracyLog - uint(bits.Len(uint(x))-1)
+		t.nbBits[i] = uint8(nb)
+		t.baseline[i] = uint16(x<<nb - size)
+	}
+	return t
+}
+
+// fseState FSE解码状态
+type fseState struct {
+	table *fseTable
+	state int
+}
+
+func (s *fseState) init(br *zstdBackReader, t *fseTable) {
+	s.table = t
+	s.state = int(br.bits(t.accuracyLog))
+}
+
+func (s *fseState) symbol() int {
+	return int(s.table.symbol[s.state])
+}
+
+func (s *fseState) update(br *zstdBackReader) {
+	s.state = int(s.table.baseline[s.state]) + int(br.bits(uint(s.table.nbBits[s.state])))
+}
+
+// rleFSETable 构造只含一个符号的表（RLE模式）
+func rleFSETable(sym uint8) *fseTable {
+	return &fseTable{symbol: []uint8{sym}, nbBits: []uint8{0}, baseline: []uint16{0}}
+}
+
+// ---------------------- Huffman（字面量） ----------------------
+type zstdHuffman struct {
+	maxBits uint
+	symbol  []uint8
+	nbBits  []uint8
+}
+
+// readHuffmanTable 读取Huffman树描述，返回解码表及占用字节数
+func (d *zstdDecoder) readHuffmanTable(data []byte) (*zstdHuffman, int) {
+	if len(data) < 1 {
+		d.corrupt("Huffman头不完整")
+	}
+	header := int(data[0])
+	var weights []uint8
+	used := 0
+	if header < 128 {
+		// 权重经FSE压缩
+		if len(data) < 1+header {
+			d.corrupt("Huffman权重不完整")
+		}
+		src := data[1 : 1+header]
+		table, n := readFSETable(src, 255, 6)
+		br := newZstdBackReader(src[n:])
+		var s1, s2 fseState
+		s1.init(br, table)
+		s2.init(br, table)
+		// 交替解码两个状态，直到位流耗尽
+		for len(weights) < 255 {
+			weights = append(weights, uint8(s1.symbol()))
+			s1.update(br)
+			if br.overflow() {
+				weights = append(weights, uint8(s2.symbol()))
+				break
+			}
+			weights = append(weights, uint8(s2.symbol()))
+			s2.update(br)
+			if br.overflow() {
+				weights = append(weights, uint8(s1.symbol()))
+				break
+			}
+		}
+		used = 1 + header
+	} else {
+		// 权重直接存放，每个4位
+		n := header - 127
+		size := (n + 1) / 2
+		if len(data) < 1+size {
+			d.corrupt("Huffman权重不完整")
+		}
+		for i := 0; i < n; i++ {
+			b := data[1+i/2]
+			if i%2 == 0 {
+				weights = append(weights, b>>4)
+			} else {
+				weights = append(weights, b&15)
+			}
+		}
+		used = 1 + size
+	}
+
+	// 最后一个符号的权重由总和推导
+	total := 0
+	for _, w := range weights {
+		if w > 12 {
+			d.corrupt("Huffman权重过大")
+		}
+		if w > 0 {
+			total += 1 << (w - 1)
+		}
+	}
+	if total == 0 || len(weights) > 255 {
+		d.corrupt("Huffman权重不合法")
+	}
+	maxBits := uint(bits.Len(uint(total)))
+	rest := 1<<maxBits - total
+	if rest&(rest-1) != 0 {
+		d.corrupt("Huffman权重不合法")
+	}
+	weights = append(weights, uint8(bits.Len(uint(rest))))
+	if maxBits > 11 {
+		d.corrupt("Huffman码长过大")
+	}
+
+	// 按权重从小到大、同权重按符号顺序分配码字
+	h := &zstdHuffman{maxBits: maxBits, symbol: make([]uint8, 1<<maxBits), nbBits: make([]uint8, 1<<maxBits)}
+	var rankStart [13]int
+	var rankCount [13]int
+	for _, w := range weights {
+		rankCount[w]++
+	}
+	next := 0
+	for w := 1; w <= 12; w++ {
+		rankStart[w] = next
+		next += rankCount[w] << (w - 1)
+	}
+	for sym, w := range weights {
+		if w == 0 {
+			continue
+		}
+		length := 1 << (w - 1)
+		nb := uint8(maxBits + 1 - uint(w))
+		for i := 0; i < length; i++ {
+			h.symbol[rankStart[w]+i] = uint8(sym)
+			h.nbBits[rankStart[w]+i] = nb
+		}
+		rankStart[w] += length
+	}
+	return h, used
+}
+
+// decodeStream 解码一个Huffman位流，填满out
+func (h *zstdHuffman) decodeStream(data []byte, out []byte) {
+	br := newZstdBackReader(data)
+	for i := range out {
+		if br.nbits < h.maxBits {
+			br.fill()
+		}
+		var idx uint32
+		if br.nbits >= h.maxBits {
+			idx = uint32(br.val >> (br.nbits - h.maxBits) & (1<<h.maxBits - 1))
+		} else {
+			idx = uint32(br.val << (h.maxBits - br.nbits) & (1<<h.maxBits - 1))
+		}
+		nb := uint(h.nbBits[idx])
+		if nb > br.nbits {
+			panic(fmt.Errorf("%w: Huffman流意外结束", errZstdCorrupt))
+		}
+		br.nbits -= nb
+		br.val &= 1<<br.nbits - 1
+		out[i] = h.symbol[idx]
+	}
+	if !br.finished() {
+		panic(fmt.Errorf("%w: Huffman流长度不一致", errZstdCorrupt))
+	}
+}
+
+// ---------------------- 序列段 ----------------------
+// decodeSequences 解码序列段并执行序列
+func (d *zstdDecoder) decodeSequences(data []byte, literals []byte) {
+	if len(data) < 1 {
+		d.corrupt("序列段为空")
+	}
+	nbSeq := int(data[0])
+	pos := 1
+	if nbSeq >= 128 {
+		if nbSeq == 255 {
+			if len(data) < 3 {
+				d.corrupt("序列头不完整")
+			}
+			nbSeq = int(binary.LittleEndian.Uint16(data[1:])) + 0x7F00
+			pos = 3
+		} else {
+			if len(data) < 2 {
+				d.corrupt("序列头不完整")
+			}
+			nbSeq = (nbSeq-128)<<8 + int(data[1])
+			pos = 2
+		}
+	}
+	if nbSeq == 0 {
+		d.out = append(d.out, literals...)
+		return
+	}
+
+	if len(data) < pos+1 {
+		d.corrupt("序列头不完整")
+	}
+	modes := data[pos]
+	pos++
+	if modes&3 != 0 {
+		d.corrupt("保留位非0")
+	}
+	pos += d.readSeqTable(&d.llTable, data[pos:], modes>>6, zstdDefaultLLNorm, 6, 35, 9)
+	pos += d.readSeqTable(&d.ofTable, data[pos:], modes>>4&3, zstdDefaultOFNorm, 5, 31, 8)
+	pos += d.readSeqTable(&d.mlTable, data[pos:], modes>>2&3, zstdDefaultMLNorm, 6, 52, 9)
+
+	br := newZstdBackReader(data[pos:])
+	var ll, of, ml fseState
+	ll.init(br, d.llTable)
+	of.init(br, d.ofTable)
+	ml.init(br, d.mlTable)
+
+	litPos := 0
+	for i := 0; i < nbSeq; i++ {
+		ofCode := of.symbol()
+		llCode := ll.symbol()
+		mlCode := ml.symbol()
+		if ofCode > 31 || llCode > 35 || mlCode > 52 {
+			d.corrupt("序列码越界")
+		}
+		offsetValue := 1<<uint(ofCode) + int(br.bits(uint(ofCode)))
+		matchLen := int(zstdMatchLengthCodes[mlCode][0] + br.bits(uint(zstdMatchLengthCodes[mlCode][1])))
+		litLen := int(zstdLiteralLengthCodes[llCode][0] + br.bits(uint(zstdLiteralLengthCodes[llCode][1])))
+
+		// 重复偏移处理
+		var offset int
+		if offsetValue > 3 {
+			offset = offsetValue - 3
+			d.repOffsets = [3]int{offset, d.repOffsets[0], d.repOffsets[1]}
+		} else {
+			idx := offsetValue - 1
+			if litLen == 0 {
+				idx++
+			}
+			switch idx {
+			case 0:
+				offset = d.repOffsets[0]
+			case 3:
+				offset = d.repOffsets[0] - 1
+				d.repOffsets = [3]int{offset, d.repOffsets[0], d.repOffsets[1]}
+			default:
+				offset = d.repOffsets[idx]
+				if idx == 1 {
+					d.repOffsets = [3]int{offset, d.repOffsets[0], d.repOffsets[2]}
+				} else {
+					d.repOffsets = [3]int{offset, d.repOffsets[0], d.repOffsets[1]}
+				}
+			}
+		}
+
+		// 执行序列：先复制字面量，再复制匹配
+		if litPos+litLen > len(literals) {
+			d.corrupt("字面量越界")
+		}
+		d.out = append(d.out, literals[litPos:litPos+litLen]...)
+		litPos += litLen
+		start := len(d.out) - offset
+		if offset <= 0 || start < d.frameStart {
+			d.corrupt("匹配偏移越界")
+		}
+		if matchLen > zstdMaxBlockSize {
+			d.corrupt("匹配长度过大")
+		}
+		if len(d.out)+matchLen-d.blockStart > zstdMaxBlockSize {
+			d.corrupt("块解压后过大")
+		}
+		for j := 0; j < matchLen; j++ {
+			d.out = append(d.out, d.out[start+j])
+		}
+
+		if i < nbSeq-1 {
+			ll.update(br)
+			ml.update(br)
+			of.update(br)
+		}
+		if br.overflow() {
+			d.corrupt("序列位流越界")
+		}
+	}
+	if !br.finished() {
+		d.corrupt("序列位流长度不一致")
+	}
+	d.out = append(d.out, literals[litPos:]...)
+}
+
+// readSeqTable 按模式读取序列码表：0预定义，1 RLE，2 FSE压缩，3复用上一块
+func (d *zstdDecoder) readSeqTable(table **fseTable, data []byte, mode byte, defNorm []int16, defLog uint, maxSymbol int, maxLog uint) int {
+	switch mode {
+	case 0:
+		*table = buildFSETable(defNorm, defLog)
+		return 0
+	case 1:
+		if len(data) < 1 || int(data[0]) > maxSymbol {
+			d.corrupt("RLE序列码不合法")
+		}
+		*table = rleFSETable(data[0])
+		return 1
+	case 2:
+		t, n := readFSETable(data, maxSymbol, maxLog)
+		*table = t
+		return n
+	default:
+		if *table == nil {
+			d.corrupt("缺少可复用的序列码表")
+		}
+		return 0
+	}
+}
+
+// ---------------------- xxHash64 ----------------------
+// 定义为变量以便在运行时做回绕运算（常量运算溢出会编译失败）
+var (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+// xxhash64 计算种子为0的xxHash64（zstd内容校验使用其低32位）
+func xxhash64(b []byte) uint64 {
+	n := len(b)
+	var h uint64
+	if n >= 32 {
+		v1 := xxPrime1 + xxPrime2
+		v2 := xxPrime2
+		v3 := uint64(0)
+		v4 := -xxPrime1
+		for len(b) >= 32 {
+			v1 = xxRound(v1, binary.LittleEndian.Uint64(b[0:]))
+			v2 = xxRound(v2, binary.LittleEndian.Uint64(b[8:]))
+			v3 = xxRound(v3, binary.LittleEndian.Uint64(b[16:]))
+			v4 = xxRound(v4, binary.LittleEndian.Uint64(b[24:]))
+			b = b[32:]
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) + bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxMerge(h, v1)
+		h = xxMerge(h, v2)
+		h = xxMerge(h, v3)
+		h = xxMerge(h, v4)
+	} else {
+		h = xxPrime5
+	}
+	h += uint64(n)
+	for ; len(b) >= 8; b = b[8:] {
+		h ^= xxRound(0, binary.LittleEndian.Uint64(b))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if len(b) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(b)) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		b = b[4:]
+	}
+	for _, c := range b {
+		h ^= uint64(c) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxPrime1
+}
+
+func xxMerge(acc, val uint64) uint64 {
+	val = xxRound(0, val)
+	acc ^= val
+	return acc*xxPrime1 + xxPrime4
+}